@@ -12,20 +12,77 @@ import (
 	"github.com/joho/godotenv"
 	"vnf-config/internal/infra/db"
 	"vnf-config/internal/router"
+	"vnf-config/internal/service"
+	"vnf-shared/auth"
+	"vnf-shared/gitops"
+	"vnf-shared/secrets"
 )
 
 func main() {
 	// 加载环境变量
 	_ = godotenv.Load()
 
-	// 初始化数据库
-	if err := db.Init(); err != nil {
+	// 初始化存储（STORAGE_BACKEND=dual|embedded）
+	repos, err := db.Init()
+	if err != nil {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
-	defer db.Close()
+	defer repos.Close(context.Background())
+
+	// 机密参数加密密钥（SECRET_KEYS_FILE 或 SECRET_KEY）
+	if err := secrets.Init(); err != nil {
+		log.Fatalf("加密密钥加载失败: %v", err)
+	}
+
+	// GitOps同步（GITOPS_REPO_PATH 为空时不启用）
+	if err := gitops.Init(); err != nil {
+		log.Fatalf("GitOps仓库初始化失败: %v", err)
+	}
+
+	// 认证（AUTH_PROVIDERS=token,jwt,oidc），未设置时拒绝启动，本地开发可设置 AUTH_DISABLED=true 显式关闭
+	authenticator, err := auth.New(auth.LoadConfig())
+	if err != nil {
+		log.Fatalf("认证初始化失败: %v", err)
+	}
+
+	// 定时对账（RECONCILE_INTERVAL 为空时不启用）
+	reconcileInterval := envDuration("RECONCILE_INTERVAL", 0)
+	reconcileOpts := service.ReconcileOptions{
+		Source: os.Getenv("RECONCILE_SOURCE"),
+		Repair: os.Getenv("RECONCILE_REPAIR") == "true",
+	}
+	if src := reconcileOpts.Source; src != "" && src != service.ReconcileSourceMySQL && src != service.ReconcileSourceMongo {
+		log.Fatalf("不支持的 RECONCILE_SOURCE: %s", src)
+	}
+
+	// 密钥与配置均已加载，再启动会读写机密取值的后台任务
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// 启动MongoDB发件箱派发
+	go service.NewOutboxService(repos).Run(workerCtx, envDuration("OUTBOX_INTERVAL", 5*time.Second))
+
+	// 为早期写入的MongoDB定义文档补齐 definition_id，已补齐时不做任何修改
+	go func() {
+		updated, removed, err := service.NewReconcileService(repos).BackfillDefinitionIDs(workerCtx)
+		if err != nil {
+			log.Printf("定义文档补齐 definition_id 失败: %v", err)
+		} else if updated > 0 || removed > 0 {
+			log.Printf("定义文档补齐 definition_id: 更新 %d, 删除重复 %d", updated, removed)
+		}
+	}()
+
+	if reconcileInterval > 0 {
+		go service.NewReconcileService(repos).RunScheduled(workerCtx, reconcileInterval, reconcileOpts)
+	}
+
+	// GITOPS_PULL_INTERVAL 设置时定时导回外部提交
+	if interval := envDuration("GITOPS_PULL_INTERVAL", 0); interval > 0 && gitops.Default() != nil {
+		go service.NewGitOpsService(repos).RunPull(workerCtx, interval)
+	}
 
 	// 创建路由
-	r := router.New()
+	r := router.New(repos, authenticator)
 
 	// 获取端口配置
 	port := os.Getenv("APP_PORT")
@@ -64,4 +121,10 @@ func main() {
 	log.Println("服务器已关闭")
 }
 
-
+// envDuration 读取时间间隔配置，如 "5s"、"1m"
+func envDuration(key string, d time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return d
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+)
+
+type AuditController struct {
+	service *service.AuditService
+}
+
+func NewAuditController(repos *repository.Repositories) *AuditController {
+	return &AuditController{service: service.NewAuditService(repos)}
+}
+
+// ListAudit 查询全部VNF的变更历史，可按 vnfId 及其他条件过滤
+func (ctl *AuditController) ListAudit(c *gin.Context) {
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, total, err := ctl.service.List(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "page": filter.Page, "pageSize": filter.PageSize})
+}
+
+// ExportAudit 导出全部VNF的变更历史，format=csv（默认）或 json
+func (ctl *AuditController) ExportAudit(c *gin.Context) {
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	writeExport(c, "audit", func(format string, w io.Writer) error {
+		return ctl.service.Export(c, filter, format, w)
+	})
+}
+
+// historyFilter 解析变更历史的查询参数：vnfId、entityType、parameter、action、actor、requestId、
+// since/until（RFC3339或日期）、page、pageSize
+func historyFilter(c *gin.Context) (repository.HistoryFilter, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	vnfID, _ := strconv.Atoi(c.Query("vnfId"))
+	filter := repository.HistoryFilter{
+		VNFID:         uint(vnfID),
+		EntityType:    c.Query("entityType"),
+		ParameterName: c.Query("parameter"),
+		Action:        c.Query("action"),
+		Actor:         c.Query("actor"),
+		RequestID:     c.Query("requestId"),
+		Page:          page,
+		PageSize:      pageSize,
+	}
+	var err error
+	if filter.Since, err = parseTimeQuery(c, "since"); err != nil {
+		return filter, err
+	}
+	filter.Until, err = parseTimeQuery(c, "until")
+	return filter, err
+}
+
+func parseTimeQuery(c *gin.Context, name string) (time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s 格式无效，应为RFC3339时间或 YYYY-MM-DD 日期", name)
+}
+
+// writeExport 以附件形式输出导出结果；写出开始后出错只能中断响应
+func writeExport(c *gin.Context, name string, export func(format string, w io.Writer) error) {
+	format := c.DefaultQuery("format", service.ExportFormatCSV)
+	contentType := map[string]string{
+		service.ExportFormatCSV:  "text/csv; charset=utf-8",
+		service.ExportFormatJSON: "application/json; charset=utf-8",
+	}[format]
+	if contentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrExportFormat.Error()})
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("20060102-150405"), format))
+	c.Status(http.StatusOK)
+	if err := export(format, c.Writer); err != nil && !errors.Is(err, c.Request.Context().Err()) {
+		log.Printf("导出%s失败: %v", name, err)
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,192 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+)
+
+type ChangeSetController struct {
+	service *service.ChangeSetService
+}
+
+func NewChangeSetController(repos *repository.Repositories) *ChangeSetController {
+	return &ChangeSetController{service: service.NewChangeSetService(repos)}
+}
+
+// ListChangeSets 列出VNF的变更单，可按 status 过滤
+func (ctl *ChangeSetController) ListChangeSets(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	items, total, err := ctl.service.List(c, uint(vnfID), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "page": page, "pageSize": pageSize})
+}
+
+// GetChangeSet 查看变更单及逐项对比
+func (ctl *ChangeSetController) GetChangeSet(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	csID, _ := strconv.Atoi(c.Param("csId"))
+	resp, err := ctl.service.Get(c, uint(vnfID), uint(csID))
+	if err != nil {
+		c.JSON(changeSetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateChangeSet 提交变更单
+func (ctl *ChangeSetController) CreateChangeSet(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.ChangeSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := ctl.service.Create(c, uint(vnfID), req)
+	if err != nil {
+		writeChangeSetError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// UpdateChangeSet 替换变更单的内容，需重新审批
+func (ctl *ChangeSetController) UpdateChangeSet(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	csID, _ := strconv.Atoi(c.Param("csId"))
+	var req dto.ChangeSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := ctl.service.Update(c, uint(vnfID), uint(csID), req)
+	if err != nil {
+		writeChangeSetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveChangeSet 批准变更单
+func (ctl *ChangeSetController) ApproveChangeSet(c *gin.Context) {
+	ctl.review(c, ctl.service.Approve)
+}
+
+// RejectChangeSet 驳回或撤回变更单
+func (ctl *ChangeSetController) RejectChangeSet(c *gin.Context) {
+	ctl.review(c, ctl.service.Reject)
+}
+
+func (ctl *ChangeSetController) review(c *gin.Context, fn func(ctx context.Context, vnfID, id uint, comment string) (*service.ChangeSetView, error)) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	csID, _ := strconv.Atoi(c.Param("csId"))
+	var req dto.ChangeSetReviewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	resp, err := fn(c, uint(vnfID), uint(csID), req.Comment)
+	if err != nil {
+		c.JSON(changeSetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApplyChangeSet 应用已批准的变更单
+func (ctl *ChangeSetController) ApplyChangeSet(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	csID, _ := strconv.Atoi(c.Param("csId"))
+	resp, err := ctl.service.Apply(c, uint(vnfID), uint(csID))
+	if err != nil {
+		var conflict *service.ChangeSetConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": conflict.Parameters})
+			return
+		}
+		writeChangeSetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListApprovalPolicies 列出VNF的分组审批策略
+func (ctl *ChangeSetController) ListApprovalPolicies(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	items, err := ctl.service.ListPolicies(c, uint(vnfID))
+	if err != nil {
+		c.JSON(changeSetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// SetApprovalPolicy 新增或覆盖分组的审批策略
+func (ctl *ChangeSetController) SetApprovalPolicy(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.ApprovalPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	policy, err := ctl.service.SetPolicy(c, uint(vnfID), req)
+	if err != nil {
+		c.JSON(changeSetErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteApprovalPolicy 删除审批策略，分组恢复为未分组策略或默认要求
+func (ctl *ChangeSetController) DeleteApprovalPolicy(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	policyID, _ := strconv.Atoi(c.Param("policyId"))
+	if err := ctl.service.DeletePolicy(c, uint(vnfID), uint(policyID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeChangeSetError 取值校验失败返回422及各参数的错误，其余按 changeSetErrorStatus
+func writeChangeSetError(c *gin.Context, err error) {
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": invalid.Fields})
+		return
+	}
+	c.JSON(changeSetErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+func changeSetErrorStatus(err error) int {
+	var conflict *service.ChangeSetConflictError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPermissionDenied), errors.Is(err, service.ErrNotAuthor),
+		errors.Is(err, service.ErrSelfReview), errors.Is(err, service.ErrReviewForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrChangeSetState), errors.Is(err, service.ErrAlreadyReviewed), errors.As(err, &conflict):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
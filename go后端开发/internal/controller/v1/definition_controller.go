@@ -1,21 +1,24 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"vnf-config/internal/dto"
+	"vnf-config/internal/repository"
 	"vnf-config/internal/service"
 )
 
 type DefinitionController struct {
 	service *service.DefinitionService
+	secrets *service.SecretService
 }
 
-func NewDefinitionController() *DefinitionController {
-	return &DefinitionController{service: service.NewDefinitionService()}
+func NewDefinitionController(repos *repository.Repositories) *DefinitionController {
+	return &DefinitionController{service: service.NewDefinitionService(repos), secrets: service.NewSecretService(repos)}
 }
 
 func (ctl *DefinitionController) ListDefinitions(c *gin.Context) {
@@ -41,7 +44,7 @@ func (ctl *DefinitionController) CreateDefinition(c *gin.Context) {
 	}
 	resp, err := ctl.service.Create(c, uint(vnfID), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, resp)
@@ -55,22 +58,176 @@ func (ctl *DefinitionController) UpdateDefinition(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
+	if c.Query("dryRun") == "true" {
+		// 试运行：校验未通过时同样返回200，由 valid 与 errors 说明
+		result, err := ctl.service.DryRunUpdate(c, uint(vnfID), uint(defID), req)
+		if err != nil {
+			c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
 	resp, err := ctl.service.Update(c, uint(vnfID), uint(defID), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var invalid *service.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": invalid.Fields})
+			return
+		}
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// BulkUpdateDefinitions 批量修改当前值，整组校验通过后在同一事务中提交；校验失败返回422及各参数的错误
+func (ctl *DefinitionController) BulkUpdateDefinitions(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.DefinitionBulkUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if c.Query("dryRun") == "true" {
+		// 试运行：校验未通过时同样返回200，由 valid 与 errors 说明
+		result, err := ctl.service.DryRunBulkUpdate(c, uint(vnfID), req)
+		if err != nil {
+			c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	items, err := ctl.service.BulkUpdate(c, uint(vnfID), req)
+	if err != nil {
+		var invalid *service.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": invalid.Fields})
+			return
+		}
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "updated": len(items)})
+}
+
 func (ctl *DefinitionController) DeleteDefinition(c *gin.Context) {
 	vnfID, _ := strconv.Atoi(c.Param("id"))
 	defID, _ := strconv.Atoi(c.Param("defId"))
 	if err := ctl.service.Delete(c, uint(vnfID), uint(defID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
 
+// ResetDefinition 将参数当前值重置为默认值；请求体可选 {"reason":"..."}
+func (ctl *DefinitionController) ResetDefinition(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	defID, _ := strconv.Atoi(c.Param("defId"))
+	var req dto.DefinitionResetRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	resp, err := ctl.service.Reset(c, uint(vnfID), uint(defID), req.Reason)
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResetDefinitions 将一个分组或整个VNF中已修改的参数重置为默认值
+func (ctl *DefinitionController) ResetDefinitions(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.DefinitionResetRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	resp, err := ctl.service.ResetAll(c, uint(vnfID), req)
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetValueHistory 列出参数当前值的历次变更
+func (ctl *DefinitionController) GetValueHistory(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	defID, _ := strconv.Atoi(c.Param("defId"))
+	items, err := ctl.service.ValueHistory(c, uint(vnfID), uint(defID))
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+// RestoreDefinition 将参数当前值恢复为取值历史中某条记录的新值
+func (ctl *DefinitionController) RestoreDefinition(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	defID, _ := strconv.Atoi(c.Param("defId"))
+	var req dto.DefinitionRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := ctl.service.Restore(c, uint(vnfID), uint(defID), req)
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+
+// CheckConsistency 校验定义在MySQL与MongoDB中是否一致
+func (ctl *DefinitionController) CheckConsistency(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	report, err := ctl.service.CheckConsistency(c, uint(vnfID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RevealDefinition 查看参数的明文取值（含机密参数），须填写原因并记录在变更历史中
+func (ctl *DefinitionController) RevealDefinition(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	defID, _ := strconv.Atoi(c.Param("defId"))
+	var req dto.RevealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrReasonRequired.Error()})
+		return
+	}
+	resp, err := ctl.secrets.Reveal(c, uint(vnfID), uint(defID), req.Reason)
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// definitionErrorStatus 参数权限不足（含移动需要审批的分组）返回403，不存在或无权查看返回404，其余按请求错误处理
+func definitionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPermissionDenied), errors.Is(err, service.ErrGroupMoveForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrApprovalRequired):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+)
+
+type HealthController struct {
+	service *service.HealthService
+}
+
+func NewHealthController(repos *repository.Repositories) *HealthController {
+	return &HealthController{service: service.NewHealthService(repos)}
+}
+
+// Healthz 存活检查：进程能处理请求即返回成功，不探测存储
+func (ctl *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": service.HealthOK})
+}
+
+// Readyz 就绪检查：必需存储不可用时返回503，可选存储不可用时返回200并标记为降级
+func (ctl *HealthController) Readyz(c *gin.Context) {
+	report := ctl.service.Check(c)
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
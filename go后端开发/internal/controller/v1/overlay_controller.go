@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+)
+
+type OverlayController struct {
+	service *service.OverlayService
+}
+
+func NewOverlayController(repos *repository.Repositories) *OverlayController {
+	return &OverlayController{service: service.NewOverlayService(repos)}
+}
+
+// ListOverlays 列出VNF的环境覆盖层
+func (ctl *OverlayController) ListOverlays(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	items, err := ctl.service.List(c, uint(vnfID))
+	if err != nil {
+		c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+// GetOverlay 查看覆盖层本层的取值
+func (ctl *OverlayController) GetOverlay(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	resp, err := ctl.service.Get(c, uint(vnfID), c.Param("name"))
+	if err != nil {
+		c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateOverlay 创建覆盖层
+func (ctl *OverlayController) CreateOverlay(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.OverlayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := ctl.service.Create(c, uint(vnfID), req)
+	if err != nil {
+		writeOverlayError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// UpdateOverlay 整体替换覆盖层的上级、说明与取值
+func (ctl *OverlayController) UpdateOverlay(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.OverlayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := ctl.service.Update(c, uint(vnfID), c.Param("name"), req)
+	if err != nil {
+		writeOverlayError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteOverlay 删除覆盖层
+func (ctl *OverlayController) DeleteOverlay(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	if err := ctl.service.Delete(c, uint(vnfID), c.Param("name")); err != nil {
+		c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetEffectiveValues 计算覆盖层（或 base）中全部参数的生效取值及来源
+func (ctl *OverlayController) GetEffectiveValues(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	items, err := ctl.service.Effective(c, uint(vnfID), c.Param("name"))
+	if err != nil {
+		c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"overlay": c.Param("name"), "items": items, "total": len(items)})
+}
+
+// DiffOverlays 比较两个覆盖层的生效取值，from/to 默认为 base
+func (ctl *OverlayController) DiffOverlays(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	from, to := c.DefaultQuery("from", service.BaseOverlay), c.DefaultQuery("to", service.BaseOverlay)
+	items, err := ctl.service.Diff(c, uint(vnfID), from, to)
+	if err != nil {
+		c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "items": items, "total": len(items)})
+}
+
+// writeOverlayError 校验失败返回422及各参数的错误，其余按 overlayErrorStatus
+func writeOverlayError(c *gin.Context, err error) {
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": invalid.Fields})
+		return
+	}
+	c.JSON(overlayErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+func overlayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrOverlayExists), errors.Is(err, service.ErrOverlayInUse), errors.Is(err, service.ErrOverlayLocked):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrOverlayName), errors.Is(err, service.ErrOverlayCycle):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
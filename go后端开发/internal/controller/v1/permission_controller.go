@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+)
+
+type PermissionController struct {
+	service *service.PermissionService
+}
+
+func NewPermissionController(repos *repository.Repositories) *PermissionController {
+	return &PermissionController{service: service.NewPermissionService(repos)}
+}
+
+// ListPermissions 列出VNF的参数与分组权限规则
+func (ctl *PermissionController) ListPermissions(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	items, err := ctl.service.List(c, uint(vnfID))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// SetPermission 新增或覆盖一条权限规则
+func (ctl *PermissionController) SetPermission(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	var req dto.PermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	rule, err := ctl.service.Set(c, uint(vnfID), req)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeletePermission 删除权限规则，对应参数恢复为分组规则或默认权限
+func (ctl *PermissionController) DeletePermission(c *gin.Context) {
+	vnfID, _ := strconv.Atoi(c.Param("id"))
+	ruleID, _ := strconv.Atoi(c.Param("ruleId"))
+	if err := ctl.service.Delete(c, uint(vnfID), uint(ruleID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"vnf-config/internal/repository"
+	"vnf-config/internal/service"
+	"vnf-shared/gitops"
+)
+
+type StorageController struct {
+	storage   *service.DualStorageService
+	outbox    *service.OutboxService
+	reconcile *service.ReconcileService
+	secrets   *service.SecretService
+	gitops    *service.GitOpsService
+}
+
+func NewStorageController(repos *repository.Repositories) *StorageController {
+	return &StorageController{
+		storage:   service.NewDualStorageService(repos),
+		outbox:    service.NewOutboxService(repos),
+		reconcile: service.NewReconcileService(repos),
+		secrets:   service.NewSecretService(repos),
+		gitops:    service.NewGitOpsService(repos),
+	}
+}
+
+// GetStorageStatus 查看各存储的连接状态与连接池统计
+func (ctl *StorageController) GetStorageStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, ctl.storage.GetStorageStatus())
+}
+
+// GetOutboxStatus 查看待同步到MongoDB的操作
+func (ctl *StorageController) GetOutboxStatus(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	status, err := ctl.outbox.Status(c, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RetryOutboxItem 重新派发已放弃的操作
+func (ctl *StorageController) RetryOutboxItem(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := ctl.outbox.Retry(c, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DiscardOutboxItem 丢弃已放弃的操作，使同一VNF的后续操作继续派发
+func (ctl *StorageController) DiscardOutboxItem(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := ctl.outbox.Discard(c, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Reconcile 对比MySQL与MongoDB并生成差异报告，repair=true 时按 source 修复
+func (ctl *StorageController) Reconcile(c *gin.Context) {
+	var opts service.ReconcileOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	report, err := ctl.reconcile.Run(c, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetLastReconcileReport 返回最近一次对账报告（含定时任务）
+func (ctl *StorageController) GetLastReconcileReport(c *gin.Context) {
+	report := ctl.reconcile.LastReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "尚未执行对账"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSecretStatus 查看加密密钥及各密钥加密的取值个数
+func (ctl *StorageController) GetSecretStatus(c *gin.Context) {
+	status, err := ctl.secrets.Status(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RotateSecrets 将旧主密钥加密的取值改用当前主密钥
+func (ctl *StorageController) RotateSecrets(c *gin.Context) {
+	report, err := ctl.secrets.Rotate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetGitOpsStatus 查看GitOps仓库的当前提交与最近一次拉取位置
+func (ctl *StorageController) GetGitOpsStatus(c *gin.Context) {
+	status, err := ctl.gitops.Status(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// PullGitOps 将GitOps仓库中的外部提交导回为参数变更
+func (ctl *StorageController) PullGitOps(c *gin.Context) {
+	result, err := ctl.gitops.Pull(c)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, gitops.ErrDisabled) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -1,12 +1,15 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"vnf-config/internal/repository"
 	"vnf-config/internal/service"
 )
 
@@ -14,8 +17,8 @@ type UploadController struct {
 	uploadService *service.UploadService
 }
 
-func NewUploadController() *UploadController {
-	return &UploadController{uploadService: service.NewUploadService()}
+func NewUploadController(repos *repository.Repositories) *UploadController {
+	return &UploadController{uploadService: service.NewUploadService(repos)}
 }
 
 func (u *UploadController) UploadZip(c *gin.Context) {
@@ -58,11 +61,12 @@ func (u *UploadController) UploadZip(c *gin.Context) {
 			"storage": gin.H{
 				"mysql": gin.H{
 					"success": result.StorageResult.MySQLSuccess,
-					"error":   result.StorageResult.MySQLError,
+					"error":   errorString(result.StorageResult.MySQLError),
 				},
 				"mongodb": gin.H{
 					"success": result.StorageResult.MongoSuccess,
-					"error":   result.StorageResult.MongoError,
+					"pending": result.StorageResult.MongoPending,
+					"error":   errorString(result.StorageResult.MongoError),
 				},
 			},
 		},
@@ -78,36 +82,57 @@ func (u *UploadController) UploadZip(c *gin.Context) {
 
 // GetFormFields 获取表单项
 func (u *UploadController) GetFormFields(c *gin.Context) {
-	vnfID := c.Param("id")
-	if vnfID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "VNF ID是必需的"})
+	instance, source, ok := u.loadConfig(c)
+	if !ok {
 		return
 	}
-
-	// 这里可以调用服务获取表单项
-	// 暂时返回成功响应
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "获取表单项成功",
-		"data":    gin.H{},
+		"success":  true,
+		"message":  "获取表单项成功",
+		"data":     instance.FormFields,
+		"source":   source,
+		"degraded": source != service.ReadSourceMongo,
 	})
 }
 
 // GetYAMLConfig 获取YAML配置
 func (u *UploadController) GetYAMLConfig(c *gin.Context) {
-	vnfID := c.Param("id")
-	if vnfID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "VNF ID是必需的"})
+	instance, source, ok := u.loadConfig(c)
+	if !ok {
 		return
 	}
-
-	// 这里可以调用服务获取YAML配置
-	// 暂时返回成功响应
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "获取YAML配置成功",
-		"data":    gin.H{},
+		"success":  true,
+		"message":  "获取YAML配置成功",
+		"data":     instance.YAMLConfig,
+		"source":   source,
+		"degraded": source != service.ReadSourceMongo,
 	})
 }
 
+// loadConfig 读取VNF配置文档，失败时已写入错误响应
+func (u *UploadController) loadConfig(c *gin.Context) (*service.VNFInstanceMongo, string, bool) {
+	vnfID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || vnfID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "VNF ID是必需的"})
+		return nil, "", false
+	}
+	instance, source, err := u.uploadService.GetVNFConfig(c, uint(vnfID))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return instance, source, true
+}
 
+// errorString 将错误转换为可序列化的字符串
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
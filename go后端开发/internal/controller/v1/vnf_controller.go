@@ -1,20 +1,34 @@
 package v1
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"vnf-config/internal/dto"
+	"vnf-config/internal/repository"
 	"vnf-config/internal/service"
+	"vnf-shared/formats"
 )
 
 type VNFController struct {
-	service *service.VNFService
+	service  *service.VNFService
+	compare  *service.CompareService
+	manifest *service.ManifestService
 }
 
-func NewVNFController() *VNFController {
-	return &VNFController{service: service.NewVNFService()}
+func NewVNFController(repos *repository.Repositories) *VNFController {
+	return &VNFController{
+		service:  service.NewVNFService(repos),
+		compare:  service.NewCompareService(repos),
+		manifest: service.NewManifestService(repos),
+	}
 }
 
 func (ctl *VNFController) ListVNFInstances(c *gin.Context) {
@@ -49,4 +63,188 @@ func (ctl *VNFController) DeleteVNFInstance(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// CloneVNFInstance 以新名称克隆VNF实例及其参数定义与配置文档，可同时覆盖部分参数的当前值
+func (ctl *VNFController) CloneVNFInstance(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	var req dto.VNFCloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	resp, err := ctl.service.Clone(c, uint(id), req)
+	if err != nil {
+		var invalid *service.ValidationError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "fields": invalid.Fields})
+			return
+		}
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ExportVNFInstance 导出VNF参数集归档（ZIP），format=json（默认）或 yaml 指定参数集文档格式
+func (ctl *VNFController) ExportVNFInstance(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	format := c.DefaultQuery("format", service.ExportFormatJSON)
+	if format != service.ExportFormatJSON && format != service.ExportFormatYAML {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrArchiveFormat.Error()})
+		return
+	}
+	archive, err := ctl.service.Export(c, uint(id))
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="vnf-%d-%s.zip"`, id, time.Now().Format("20060102-150405")))
+	c.Status(http.StatusOK)
+	if err := service.WriteArchive(archive, format, c.Writer); err != nil && !errors.Is(err, c.Request.Context().Err()) {
+		log.Printf("导出VNF #%d 失败: %v", id, err)
+		_ = c.Error(err)
+	}
+}
 
+// ImportVNFInstance 由导出的归档新建VNF实例；name 可指定新名称，onConflict=fail（默认，重名时返回409）或 rename
+func (ctl *VNFController) ImportVNFInstance(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	opts := service.ImportOptions{
+		Name:       c.PostForm("name"),
+		OnConflict: c.DefaultPostForm("onConflict", service.ConflictFail),
+		Reason:     c.PostForm("reason"),
+	}
+	result, err := ctl.service.Import(c, file, fileHeader.Size, opts)
+	if err != nil {
+		var conflict *service.ImportConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": conflict.Existing})
+			return
+		}
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// RenderVNFInstance 将参数当前值（或 overlay 指定覆盖层的生效取值）渲染为Kubernetes清单或参数文档，
+// format=configmap（默认）、secret、kustomize、json、toml、env、properties 或 yaml，namespace、name、labels=k1=v1,k2=v2 设置元数据
+func (ctl *VNFController) RenderVNFInstance(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	labels, err := service.ParseLabels(c.QueryArray("labels"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts := service.ManifestOptions{
+		Format:    c.DefaultQuery("format", service.RenderFormatConfigMap),
+		Overlay:   c.Query("overlay"),
+		Namespace: c.Query("namespace"),
+		Name:      c.Query("name"),
+		Labels:    labels,
+		Reason:    c.Query("reason"),
+	}
+	manifest, err := ctl.manifest.Render(c, uint(id), opts)
+	if err != nil {
+		status := definitionErrorStatus(err)
+		switch {
+		case errors.Is(err, service.ErrRenderDenied):
+			status = http.StatusForbidden
+		case errors.Is(err, formats.ErrUnrepresentable):
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", manifest.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, manifest.FileName))
+	c.Status(http.StatusOK)
+	if err := service.WriteManifest(manifest, c.Writer); err != nil && !errors.Is(err, c.Request.Context().Err()) {
+		log.Printf("渲染VNF #%d 的清单失败: %v", id, err)
+		_ = c.Error(err)
+	}
+}
+
+// GetHistory 查看VNF的变更历史，可按参数名、操作人、动作、请求ID与时间过滤
+func (ctl *VNFController) GetHistory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.VNFID = uint(id)
+	items, total, err := ctl.service.History(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "page": filter.Page, "pageSize": filter.PageSize})
+}
+
+// ExportHistory 导出VNF的变更历史，format=csv（默认）或 json
+func (ctl *VNFController) ExportHistory(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.VNFID = uint(id)
+	writeExport(c, "vnf-"+c.Param("id")+"-history", func(format string, w io.Writer) error {
+		return ctl.service.ExportHistory(c, filter, format, w)
+	})
+}
+
+// CompareVNFInstances 按参数名比较两个VNF实例，可按分组与是否修改过滤
+func (ctl *VNFController) CompareVNFInstances(c *gin.Context) {
+	result, ok := ctl.comparison(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportComparison 导出比较结果，format=csv（默认）或 json
+func (ctl *VNFController) ExportComparison(c *gin.Context) {
+	result, ok := ctl.comparison(c)
+	if !ok {
+		return
+	}
+	name := fmt.Sprintf("vnf-compare-%d-%d", result.Left.ID, result.Right.ID)
+	writeExport(c, name, func(format string, w io.Writer) error {
+		return service.WriteCompareResult(result, format, w)
+	})
+}
+
+// comparison 解析比较条件并执行比较，出错时已写出响应
+func (ctl *VNFController) comparison(c *gin.Context) (*service.CompareResult, bool) {
+	left, lerr := strconv.ParseUint(c.Query("left"), 10, 64)
+	right, rerr := strconv.ParseUint(c.Query("right"), 10, 64)
+	if lerr != nil || rerr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "left 与 right 须为VNF实例ID"})
+		return nil, false
+	}
+	filter := service.CompareFilter{
+		Left:         uint(left),
+		Right:        uint(right),
+		Group:        c.Query("group"),
+		ModifiedOnly: c.DefaultQuery("modifiedOnly", "false") == "true",
+	}
+	result, err := ctl.compare.Compare(c, filter)
+	if err != nil {
+		c.JSON(definitionErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return result, true
+}
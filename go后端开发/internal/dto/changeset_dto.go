@@ -0,0 +1,27 @@
+package dto
+
+// ChangeSetItemRequest 变更单中的一项修改，参数可按定义ID或参数名指定
+type ChangeSetItemRequest struct {
+	DefinitionID  uint    `json:"definitionId"`
+	ParameterName string  `json:"parameterName"`
+	Value         *string `json:"value" binding:"required"`
+}
+
+// ChangeSetRequest 创建变更单，或整体替换待审批变更单的内容（已有的审批意见作废）
+type ChangeSetRequest struct {
+	Title       string                 `json:"title" binding:"required"`
+	Description string                 `json:"description"`
+	Items       []ChangeSetItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// ChangeSetReviewRequest 批准或驳回变更单
+type ChangeSetReviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+// ApprovalPolicyRequest 设置分组的审批策略，group 为空表示未单独配置的分组
+type ApprovalPolicyRequest struct {
+	Group             string `json:"group"`
+	RequiredApprovals *int   `json:"requiredApprovals" binding:"required"`
+	ApproverRole      string `json:"approverRole"`
+}
@@ -5,22 +5,48 @@ type DefinitionCreateRequest struct {
 	DefaultValue    string  `json:"defaultValue" binding:"required"`
 	DescriptionText string  `json:"descriptionTxt" binding:"required"`
 	Type            string  `json:"type" binding:"required"`
+	Group           string  `json:"group"`
 	CanBeUpdated    bool    `json:"canBeUpdated"`
 	HiddenCondition string  `json:"hidenCondition"`
 	Optional        *bool   `json:"optional"`
 	Constraints     string  `json:"constraints"`
 	CurrentValue    *string `json:"currentValue"`
+	Reason          string  `json:"reason"` // 变更原因，记录在变更历史中（也可通过 X-Change-Reason 请求头传递）
 }
 
 type DefinitionUpdateRequest struct {
 	DefaultValue    *string `json:"defaultValue"`
 	DescriptionText *string `json:"descriptionTxt"`
 	Type            *string `json:"type"`
+	Group           *string `json:"group"`
 	CanBeUpdated    *bool   `json:"canBeUpdated"`
 	HiddenCondition *string `json:"hidenCondition"`
 	Optional        *bool   `json:"optional"`
 	Constraints     *string `json:"constraints"`
 	CurrentValue    *string `json:"currentValue"`
+	Reason          string  `json:"reason"`
 }
 
+// RevealRequest 查看机密参数明文，原因记录在变更历史中
+type RevealRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// DefinitionBulkUpdateRequest 批量修改当前值，values 为参数名到新值的映射（值可为字符串、数字或布尔值）
+type DefinitionBulkUpdateRequest struct {
+	Values map[string]interface{} `json:"values" binding:"required,min=1"`
+	Reason string                 `json:"reason"`
+}
 
+// DefinitionResetRequest 将当前值重置为默认值。批量重置时 group 为 null 表示整个VNF，
+// 空字符串表示未分组的参数；单个参数重置只使用 reason
+type DefinitionResetRequest struct {
+	Group  *string `json:"group"`
+	Reason string  `json:"reason"`
+}
+
+// DefinitionRestoreRequest 将当前值恢复为参数取值历史中某条记录的新值
+type DefinitionRestoreRequest struct {
+	RecordID uint   `json:"recordId" binding:"required"`
+	Reason   string `json:"reason"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// OverlayRequest 创建覆盖层，或整体替换覆盖层的上级、说明与取值（名称不可修改）。
+// parent 为上级覆盖层名称，为空表示直接继承基础取值；values 为参数名到取值的映射（值可为字符串、数字或布尔值）
+type OverlayRequest struct {
+	Name        string                 `json:"name"`
+	Parent      string                 `json:"parent"`
+	Description string                 `json:"description"`
+	Values      map[string]interface{} `json:"values"`
+	Reason      string                 `json:"reason"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+// PermissionRequest 设置参数或分组的权限规则，同一作用范围与目标的规则会被覆盖
+type PermissionRequest struct {
+	Scope    string `json:"scope" binding:"required"`
+	Target   string `json:"target" binding:"required"`
+	ViewRole string `json:"viewRole"`
+	EditRole string `json:"editRole"`
+	Masked   *bool  `json:"masked"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// VNFCloneRequest 克隆VNF实例，overrides 为参数名到新当前值的映射（值可为字符串、数字或布尔值）
+type VNFCloneRequest struct {
+	Name      string                 `json:"name" binding:"required"`
+	Overrides map[string]interface{} `json:"overrides"`
+	Reason    string                 `json:"reason"`
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求头：调用方可自带请求ID（网关透传）；变更原因需URL编码以支持中文
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderReason    = "X-Change-Reason"
+)
+
+type requestIDKey struct{}
+type reasonKey struct{}
+
+// Middleware 为每个请求分配请求ID（沿用合法的 X-Request-ID），写入响应头，
+// 并把请求ID与 X-Change-Reason 放入请求上下文，供变更历史记录
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(HeaderRequestID, id)
+		ctx := context.WithValue(c.Request.Context(), requestIDKey{}, id)
+		if reason := c.GetHeader(HeaderReason); reason != "" {
+			if decoded, err := url.PathUnescape(reason); err == nil {
+				reason = decoded
+			}
+			ctx = WithReason(ctx, reason)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequestID 返回当前请求的ID，不在请求中时为空串
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithReason 为本次写入产生的变更历史附加原因说明，空串不覆盖已有原因
+func WithReason(ctx context.Context, reason string) context.Context {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// Reason 返回变更原因
+func Reason(ctx context.Context) string {
+	reason, _ := ctx.Value(reasonKey{}).(string)
+	return reason
+}
+
+// validRequestID 只接受较短的可打印ASCII，避免日志与CSV注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' || r == ',' || r == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
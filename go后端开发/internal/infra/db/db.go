@@ -2,11 +2,13 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"path/filepath"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"gorm.io/driver/mysql"
@@ -14,74 +16,146 @@ import (
 	"gorm.io/gorm/logger"
 
 	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
 )
 
-var (
-	MySQLDB *gorm.DB
-	MongoDB *mongo.Client
+// 存储后端
+const (
+	BackendDual     = "dual"     // MySQL + MongoDB
+	BackendEmbedded = "embedded" // SQLite + 本地文件文档存储，单机/测试使用
 )
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Backend       string
 	MySQLDSN      string
 	MongoURI      string
 	MongoDatabase string
+	DataDir       string
 	MaxOpen       int
 	MaxIdle       int
+	// MongoTimeout 选择MongoDB服务器的超时，MongoDB不可用时读写在此时间后失败
+	MongoTimeout time.Duration
+	// MongoRequired 为 false 时MongoDB不可用不阻止启动，服务以降级模式运行
+	MongoRequired bool
 }
 
-// Init 初始化双数据库连接
-func Init() error {
-	config := &DatabaseConfig{
-		MySQLDSN:      os.Getenv("MYSQL_DSN"),
-		MongoURI:      os.Getenv("MONGO_URI"),
-		MongoDatabase: os.Getenv("MONGO_DATABASE"),
+// LoadConfig 从环境变量读取数据库配置
+func LoadConfig() *DatabaseConfig {
+	return &DatabaseConfig{
+		Backend:       defaultString(os.Getenv("STORAGE_BACKEND"), BackendDual),
+		MySQLDSN:      defaultString(os.Getenv("MYSQL_DSN"), "root:password@tcp(127.0.0.1:3306)/vnf_config?charset=utf8mb4&parseTime=True&loc=Local"),
+		MongoURI:      defaultString(os.Getenv("MONGO_URI"), "mongodb://localhost:27017"),
+		MongoDatabase: defaultString(os.Getenv("MONGO_DATABASE"), "vnf_config"),
+		DataDir:       defaultString(os.Getenv("EMBEDDED_DATA_DIR"), "./data/embedded"),
 		MaxOpen:       20,
 		MaxIdle:       10,
+		MongoTimeout:  envDuration("MONGO_TIMEOUT", 3*time.Second),
+		MongoRequired: os.Getenv("MONGO_REQUIRED") == "true",
 	}
+}
+
+// Init 按配置初始化存储后端
+func Init() (*repository.Repositories, error) {
+	return Open(LoadConfig())
+}
 
-	// 设置默认值
-	if config.MySQLDSN == "" {
-		config.MySQLDSN = "root:password@tcp(127.00.1:3306)/vnf_config?charset=utf8mb4&parseTime=True&loc=Local"
+// Open 按配置打开关系型存储与文档存储
+func Open(config *DatabaseConfig) (*repository.Repositories, error) {
+	switch config.Backend {
+	case BackendDual:
+		return openDual(config)
+	case BackendEmbedded:
+		return openEmbedded(config)
+	default:
+		return nil, fmt.Errorf("未知的存储后端: %s", config.Backend)
 	}
-	if config.MongoURI == "" {
-		config.MongoURI = "mongodb://localhost:27017"
+}
+
+func openDual(config *DatabaseConfig) (*repository.Repositories, error) {
+	database, err := initMySQL(config)
+	if err != nil {
+		return nil, err
 	}
-	if config.MongoDatabase == "" {
-		config.MongoDatabase = "vnf_config"
+	client, err := initMongoDB(config)
+	if err != nil {
+		return nil, err
 	}
+	log.Println("双数据库初始化完成")
+	return &repository.Repositories{
+		Store:             repository.NewGormStore(database, "mysql"),
+		Documents:         repository.NewMongoDocuments(client, config.MongoDatabase),
+		DocumentsRequired: config.MongoRequired,
+	}, nil
+}
 
-	// 初始化MySQL
-	if err := initMySQL(config); err != nil {
-		return err
+func openEmbedded(config *DatabaseConfig) (*repository.Repositories, error) {
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return nil, err
 	}
-
-	// 初始化MongoDB
-	if err := initMongoDB(config); err != nil {
-		return err
+	dsn := filepath.Join(config.DataDir, "vnf_config.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	database, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(gormLogMode())})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 单写者，串行化连接避免 "database is locked"
+	sqlDB.SetMaxOpenConns(1)
+	if err := migrate(database); err != nil {
+		return nil, err
 	}
 
-	log.Println("双数据库初始化完成")
-	return nil
+	documents, err := repository.NewFileDocuments(filepath.Join(config.DataDir, "documents"))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("嵌入式存储初始化完成: %s", config.DataDir)
+	return &repository.Repositories{
+		Store:             repository.NewGormStore(database, "sqlite"),
+		Documents:         documents,
+		DocumentsRequired: true,
+	}, nil
 }
 
-// initMySQL 初始化MySQL连接
-func initMySQL(config *DatabaseConfig) error {
-	logMode := logger.Silent
+func gormLogMode() logger.LogLevel {
 	if os.Getenv("APP_ENV") == "development" {
-		logMode = logger.Info
+		return logger.Info
 	}
+	return logger.Silent
+}
 
+// migrate 自动迁移表结构
+func migrate(database *gorm.DB) error {
+	return database.AutoMigrate(
+		&model.VNFInstance{},
+		&model.VNFDefinition{},
+		&model.MongoOutbox{},
+		&model.ChangeRecord{},
+		&model.ParameterPermission{},
+		&model.ChangeSet{},
+		&model.ChangeSetItem{},
+		&model.ChangeSetReview{},
+		&model.ApprovalPolicy{},
+		&model.Overlay{},
+		&model.OverlayValue{},
+	)
+}
+
+// initMySQL 初始化MySQL连接
+func initMySQL(config *DatabaseConfig) (*gorm.DB, error) {
 	database, err := gorm.Open(mysql.Open(config.MySQLDSN), &gorm.Config{
-		Logger: logger.Default.LogMode(logMode),
+		Logger: logger.Default.LogMode(gormLogMode()),
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sqlDB, err := database.DB()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	sqlDB.SetMaxOpenConns(config.MaxOpen)
@@ -89,56 +163,48 @@ func initMySQL(config *DatabaseConfig) error {
 	sqlDB.SetConnMaxLifetime(60 * time.Minute)
 
 	// 自动迁移MySQL表结构
-	if err := database.AutoMigrate(&model.VNFInstance{}, &model.VNFDefinition{}); err != nil {
-		return err
+	if err := migrate(database); err != nil {
+		return nil, err
 	}
 
-	MySQLDB = database
 	log.Println("MySQL数据库初始化完成")
-	return nil
+	return database, nil
 }
 
-// initMongoDB 初始化MongoDB连接
-func initMongoDB(config *DatabaseConfig) error {
+// initMongoDB 初始化MongoDB连接。
+// 未设置 MONGO_REQUIRED=true 时连接失败只记录日志：驱动会在后台重连，
+// 期间读接口回退到MySQL，写入暂存在发件箱中。
+func initMongoDB(config *DatabaseConfig) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.MongoURI))
+	opts := options.Client().ApplyURI(config.MongoURI).
+		SetServerSelectionTimeout(config.MongoTimeout).
+		SetConnectTimeout(config.MongoTimeout)
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 测试连接
 	if err := client.Ping(ctx, nil); err != nil {
-		return err
+		if config.MongoRequired {
+			return nil, err
+		}
+		log.Printf("MongoDB不可用，以降级模式启动: %v", err)
+		return client, nil
 	}
 
-	MongoDB = client
 	log.Println("MongoDB数据库初始化完成")
-	return nil
+	return client, nil
 }
 
-// GetMongoCollection 获取MongoDB集合
-func GetMongoCollection(collectionName string) *mongo.Collection {
-	database := os.Getenv("MONGO_DATABASE")
-	if database == "" {
-		database = "vnf_config"
-	}
-	return MongoDB.Database(database).Collection(collectionName)
-}
-
-// Close 关闭数据库连接
-func Close() {
-	if MySQLDB != nil {
-		if sqlDB, err := MySQLDB.DB(); err == nil {
-			sqlDB.Close()
-		}
-	}
-	if MongoDB != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		MongoDB.Disconnect(ctx)
+// envDuration 读取时间间隔配置，如 "3s"
+func envDuration(key string, d time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil && v > 0 {
+		return v
 	}
+	return d
 }
 
 func defaultString(v string, d string) string {
@@ -147,5 +213,3 @@ func defaultString(v string, d string) string {
 	}
 	return v
 }
-
-
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"vnf-config/internal/repository"
+)
+
+const namespace = "vnf_config"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "按路由统计的HTTP请求数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "按路由统计的HTTP请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	uploadSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "上传的ZIP文件大小",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 10), // 1KiB ~ 256MiB
+	})
+
+	parseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "yaml_parse_duration_seconds",
+		Help:      "YAML解析耗时",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"result"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "存储操作耗时，backend 为 mysql/sqlite/mongodb/file",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+
+	storageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "存储操作失败次数（不含记录不存在）",
+	}, []string{"backend", "operation"})
+
+	outboxDispatch = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_dispatch_total",
+		Help:      "发件箱派发结果：applied 成功，retry 失败待重试，failed 超过重试次数被放弃",
+	}, []string{"result"})
+)
+
+// Middleware 记录每个请求的次数与耗时，路由取注册时的模板（如 /api/v1/vnfs/:id），避免标签基数膨胀
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler 暴露 Prometheus 抓取端点
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// ObserveUpload 记录上传文件大小
+func ObserveUpload(size int64) {
+	uploadSize.Observe(float64(size))
+}
+
+// ObserveParse 记录一次YAML解析的耗时与结果
+func ObserveParse(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	parseDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveStorage 记录一次存储操作的耗时，失败时计入错误数
+func ObserveStorage(backend, operation string, start time.Time, err error) {
+	storageDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		storageErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// OutboxDispatched 记录一次发件箱派发结果
+func OutboxDispatched(result string) {
+	outboxDispatch.WithLabelValues(result).Inc()
+}
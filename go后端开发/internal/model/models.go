@@ -7,7 +7,7 @@ type VNFInstance struct {
 	Name      string         `gorm:"size:255;not null" json:"name"`
 	CreatedAt time.Time      `json:"createdAt"`
 	UpdatedAt time.Time      `json:"updatedAt"`
-	Defs      []VNFDefinition `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Defs      []VNFDefinition `gorm:"foreignKey:VNFID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 type VNFDefinition struct {
@@ -17,6 +17,7 @@ type VNFDefinition struct {
 	DefaultValue    string    `gorm:"size:1024;not null" json:"defaultValue"`
 	DescriptionText string    `gorm:"size:1024;not null" json:"descriptionTxt"`
 	Type            string    `gorm:"size:64;not null" json:"type"`
+	Group           string    `gorm:"size:64;index" json:"group"`
 	CanBeUpdated    bool      `gorm:"default:false" json:"canBeUpdated"`
 	HiddenCondition string    `gorm:"size:64" json:"hidenCondition"`
 	Optional        *bool     `json:"optional"`
@@ -28,3 +29,176 @@ type VNFDefinition struct {
 }
 
 
+
+// 发件箱操作状态
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusApplied = "applied"
+	OutboxStatusFailed  = "failed"
+	// OutboxStatusDiscarded 已放弃的操作被运维人员丢弃，不再阻塞同一VNF的后续操作
+	OutboxStatusDiscarded = "discarded"
+)
+
+// 发件箱操作类型
+const (
+	OutboxOpUpsert = "upsert"
+	OutboxOpDelete = "delete"
+)
+
+// MongoOutbox 与MySQL写入同事务记录的待执行MongoDB操作
+type MongoOutbox struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	IdempotencyKey string     `gorm:"size:191;uniqueIndex;not null" json:"idempotencyKey"`
+	Collection     string     `gorm:"size:64;not null" json:"collection"`
+	OrderingKey    string     `gorm:"size:128;index" json:"orderingKey"`
+	Operation      string     `gorm:"size:16;not null" json:"operation"`
+	Filter         string     `gorm:"type:text;not null" json:"filter"`
+	Document       string     `gorm:"type:longtext" json:"-"`
+	Status         string     `gorm:"size:16;index;not null" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	LastError      string     `gorm:"size:1024" json:"lastError"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"nextAttemptAt"`
+	AppliedAt      *time.Time `json:"appliedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// 变更动作
+const (
+	ChangeActionCreate = "create"
+	ChangeActionUpdate = "update"
+	ChangeActionDelete = "delete"
+	ChangeActionReveal = "reveal" // 查看机密参数明文，只记录操作人与原因
+)
+
+// ChangeRecord 实例与定义的变更历史（只追加），Before/After 为变更前后的JSON快照，
+// RequestID 关联同一请求产生的多条记录（如上传、删除实例）
+type ChangeRecord struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	VNFID         uint      `gorm:"index;not null" json:"vnfId"`
+	EntityType    string    `gorm:"size:32;not null" json:"entityType"`
+	EntityID      uint      `gorm:"index" json:"entityId"`
+	ParameterName string    `gorm:"size:255;index" json:"parameterName,omitempty"`
+	Action        string    `gorm:"size:32;not null" json:"action"`
+	Actor         string    `gorm:"size:255;index" json:"actor"`
+	RequestID     string    `gorm:"size:64;index" json:"requestId,omitempty"`
+	Reason        string    `gorm:"size:1024" json:"reason,omitempty"`
+	Before        string    `gorm:"type:text" json:"before,omitempty"`
+	After         string    `gorm:"type:text" json:"after,omitempty"`
+	CreatedAt     time.Time `gorm:"index" json:"createdAt"`
+}
+
+// 参数权限规则的作用范围
+const (
+	PermissionScopeParameter = "parameter"
+	PermissionScopeGroup     = "group"
+)
+
+// 参数权限规则的来源
+const (
+	PermissionSourceDescriptor = "descriptor" // 上传的描述文件中声明
+	PermissionSourceAPI        = "api"
+)
+
+// ParameterPermission 参数或参数分组的访问规则。ViewRole/EditRole 为所需的最低角色，
+// 为空或 Masked 为 nil 时沿用分组规则及默认值；参数规则优先于分组规则
+type ParameterPermission struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	VNFID     uint      `gorm:"uniqueIndex:idx_permission_target;not null" json:"vnfId"`
+	Scope     string    `gorm:"size:16;uniqueIndex:idx_permission_target;not null" json:"scope"`
+	Target    string    `gorm:"size:191;uniqueIndex:idx_permission_target;not null" json:"target"`
+	ViewRole  string    `gorm:"size:32" json:"viewRole,omitempty"`
+	EditRole  string    `gorm:"size:32" json:"editRole,omitempty"`
+	Masked    *bool     `json:"masked,omitempty"`
+	Source    string    `gorm:"size:16;not null" json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// 变更单状态
+const (
+	ChangeSetStatusOpen     = "open"     // 待审批，作者可修改条目
+	ChangeSetStatusApproved = "approved" // 已达到所需批准数，待应用
+	ChangeSetStatusApplied  = "applied"
+	ChangeSetStatusRejected = "rejected"
+)
+
+// 审批意见
+const (
+	ReviewDecisionApprove = "approve"
+	ReviewDecisionReject  = "reject"
+)
+
+// ChangeSet 变更单：一组待审批的参数当前值修改，批准后在同一事务中整体应用。
+// RequiredApprovals/ApproverRole 按各条目所在分组的审批策略计算，取最严格者
+type ChangeSet struct {
+	ID                uint              `gorm:"primaryKey" json:"id"`
+	VNFID             uint              `gorm:"index;not null" json:"vnfId"`
+	Title             string            `gorm:"size:255;not null" json:"title"`
+	Description       string            `gorm:"type:text" json:"description,omitempty"`
+	Status            string            `gorm:"size:16;index;not null" json:"status"`
+	Author            string            `gorm:"size:255;index;not null" json:"author"`
+	RequiredApprovals int               `json:"requiredApprovals"`
+	ApproverRole      string            `gorm:"size:32" json:"approverRole"`
+	ClosedBy          string            `gorm:"size:255" json:"closedBy,omitempty"` // 应用或驳回人
+	ClosedAt          *time.Time        `json:"closedAt,omitempty"`
+	Items             []ChangeSetItem   `gorm:"foreignKey:ChangeSetID" json:"-"` // 取值可能为密文，按调用方权限输出
+	Reviews           []ChangeSetReview `gorm:"foreignKey:ChangeSetID" json:"reviews"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	UpdatedAt         time.Time         `json:"updatedAt"`
+}
+
+// ChangeSetItem 变更单中对一个参数当前值的修改。BaseValue 为提交时的当前值，
+// 与 NewValue 一样按存储形式保存（机密参数为密文），应用时当前值已变化视为冲突
+type ChangeSetItem struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	ChangeSetID   uint   `gorm:"uniqueIndex:idx_changeset_item;not null" json:"changeSetId"`
+	DefinitionID  uint   `gorm:"uniqueIndex:idx_changeset_item;not null" json:"definitionId"`
+	ParameterName string `gorm:"size:255;not null" json:"parameterName"`
+	Group         string `gorm:"size:64" json:"group"`
+	BaseValue     string `gorm:"type:text" json:"baseValue"`
+	NewValue      string `gorm:"type:text" json:"newValue"`
+}
+
+// ChangeSetReview 审批意见，同一审批人对同一变更单只能提交一次
+type ChangeSetReview struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ChangeSetID uint      `gorm:"uniqueIndex:idx_changeset_review;not null" json:"changeSetId"`
+	Reviewer    string    `gorm:"size:191;uniqueIndex:idx_changeset_review;not null" json:"reviewer"`
+	Decision    string    `gorm:"size:16;not null" json:"decision"`
+	Comment     string    `gorm:"size:1024" json:"comment,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ApprovalPolicy 参数分组的变更审批策略，Group 为空的策略作用于未单独配置的分组。
+// 配置了策略（RequiredApprovals > 0）的分组只能通过变更单修改当前值
+type ApprovalPolicy struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	VNFID             uint      `gorm:"uniqueIndex:idx_approval_policy;not null" json:"vnfId"`
+	Group             string    `gorm:"size:64;uniqueIndex:idx_approval_policy" json:"group"`
+	RequiredApprovals int       `json:"requiredApprovals"`
+	ApproverRole      string    `gorm:"size:32" json:"approverRole"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// Overlay 环境覆盖层（如 dev、staging、prod）：在VNF基础取值之上覆盖部分参数的当前值。
+// ParentID 指向上级覆盖层，生效取值按 基础 → 上级 → 本层 的顺序逐层覆盖（如 基础 → 区域 → 站点）
+type Overlay struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	VNFID       uint           `gorm:"uniqueIndex:idx_overlay_name;not null" json:"vnfId"`
+	Name        string         `gorm:"size:64;uniqueIndex:idx_overlay_name;not null" json:"name"`
+	ParentID    *uint          `gorm:"index" json:"parentId,omitempty"`
+	Description string         `gorm:"size:1024" json:"description,omitempty"`
+	Values      []OverlayValue `gorm:"foreignKey:OverlayID" json:"-"` // 取值可能为密文，按调用方权限输出
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// OverlayValue 覆盖层中一个参数的取值，按存储形式保存（机密参数为密文）
+type OverlayValue struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	OverlayID     uint   `gorm:"uniqueIndex:idx_overlay_value;not null" json:"overlayId"`
+	ParameterName string `gorm:"size:191;uniqueIndex:idx_overlay_value;not null" json:"parameterName"`
+	Value         string `gorm:"type:text" json:"value"`
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fileDocuments 基于本地文件的文档存储，每个集合一个扩展JSON文件，
+// 供单机运行与测试使用。全部文档常驻内存，写入时整体落盘。
+type fileDocuments struct {
+	dir         string
+	mu          sync.RWMutex
+	collections map[string][]bson.D
+}
+
+// NewFileDocuments 打开（必要时创建）目录 dir 下的文档存储
+func NewFileDocuments(dir string) (DocumentRepository, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileDocuments{dir: dir, collections: make(map[string][]bson.D)}, nil
+}
+
+func (r *fileDocuments) path(collection string) string {
+	return filepath.Join(r.dir, collection+".json")
+}
+
+// load 读取集合，调用方需持有锁
+func (r *fileDocuments) load(collection string) ([]bson.D, error) {
+	if docs, ok := r.collections[collection]; ok {
+		return docs, nil
+	}
+	data, err := os.ReadFile(r.path(collection))
+	if os.IsNotExist(err) {
+		r.collections[collection] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		Documents []bson.D `bson:"documents"`
+	}
+	if err := bson.UnmarshalExtJSON(data, true, &wrapper); err != nil {
+		return nil, fmt.Errorf("读取文档集合 %s 失败: %v", collection, err)
+	}
+	r.collections[collection] = wrapper.Documents
+	return wrapper.Documents, nil
+}
+
+// persist 先写临时文件再重命名，避免写入中断损坏集合
+func (r *fileDocuments) persist(collection string, docs []bson.D) error {
+	data, err := bson.MarshalExtJSONIndent(bson.D{{Key: "documents", Value: docs}}, true, false, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path(collection) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, r.path(collection)); err != nil {
+		return err
+	}
+	r.collections[collection] = docs
+	return nil
+}
+
+func (r *fileDocuments) Replace(ctx context.Context, collection string, filter bson.M, doc interface{}) error {
+	replacement, err := toDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	docs, err := r.load(collection)
+	if err != nil {
+		return err
+	}
+	docs = append([]bson.D(nil), docs...)
+	for i, existing := range docs {
+		if matches(existing, filter) {
+			docs[i] = withID(replacement, lookup(existing, "_id"))
+			return r.persist(collection, docs)
+		}
+	}
+	return r.persist(collection, append(docs, withID(replacement, primitive.NewObjectID())))
+}
+
+func (r *fileDocuments) Delete(ctx context.Context, collection string, filter bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	docs, err := r.load(collection)
+	if err != nil {
+		return err
+	}
+	kept := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		if !matches(doc, filter) {
+			kept = append(kept, doc)
+		}
+	}
+	if len(kept) == len(docs) {
+		return nil
+	}
+	return r.persist(collection, kept)
+}
+
+func (r *fileDocuments) Find(ctx context.Context, collection string, filter bson.M, out interface{}) error {
+	r.mu.Lock()
+	docs, err := r.load(collection)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	matched := bson.A{}
+	for _, doc := range docs {
+		if matches(doc, filter) {
+			matched = append(matched, doc)
+		}
+	}
+	// 借助BSON编解码将匹配结果解码到调用方的切片类型
+	raw, err := bson.Marshal(bson.D{{Key: "items", Value: matched}})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(raw).Lookup("items").Unmarshal(out)
+}
+
+func (r *fileDocuments) FindOne(ctx context.Context, collection string, filter bson.M, out interface{}) error {
+	r.mu.Lock()
+	docs, err := r.load(collection)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if matches(doc, filter) {
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			return bson.Unmarshal(raw, out)
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *fileDocuments) Backend() string { return "file" }
+
+func (r *fileDocuments) Ping(ctx context.Context) error {
+	_, err := os.Stat(r.dir)
+	return err
+}
+
+func (r *fileDocuments) Close(ctx context.Context) error { return nil }
+
+// toDocument 将任意可编码为BSON的值转换为有序文档
+func toDocument(v interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// withID 设置文档的 _id 并放在首位
+func withID(doc bson.D, id interface{}) bson.D {
+	out := bson.D{{Key: "_id", Value: id}}
+	for _, e := range doc {
+		if e.Key != "_id" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func lookup(doc bson.D, key string) interface{} {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+func hasKey(doc bson.D, key string) bool {
+	for _, e := range doc {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 判断文档是否满足过滤条件，支持相等、$regex 与 $exists
+func matches(doc bson.D, filter bson.M) bool {
+	for key, want := range filter {
+		got := lookup(doc, key)
+		if cond, ok := want.(bson.M); ok {
+			if exists, ok := cond["$exists"].(bool); ok {
+				if hasKey(doc, key) != exists {
+					return false
+				}
+				continue
+			}
+			if pattern, ok := cond["$regex"]; ok {
+				expr := fmt.Sprint(pattern)
+				if opts, _ := cond["$options"].(string); strings.Contains(opts, "i") {
+					expr = "(?i)" + expr
+				}
+				re, err := regexp.Compile(expr)
+				if err != nil || !re.MatchString(fmt.Sprint(got)) {
+					return false
+				}
+				continue
+			}
+		}
+		if normalize(got) != normalize(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize 统一数值与ObjectID的比较形式，与MongoDB跨数值类型相等的语义一致
+func normalize(v interface{}) string {
+	switch x := v.(type) {
+	case primitive.ObjectID:
+		return "oid:" + x.Hex()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("num:%v", x)
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
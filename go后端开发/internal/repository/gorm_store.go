@@ -0,0 +1,645 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"vnf-config/internal/model"
+)
+
+// gormStore 基于GORM的关系型存储，MySQL与嵌入式SQLite共用
+type gormStore struct {
+	db      *gorm.DB
+	backend string
+}
+
+// NewGormStore 包装已初始化的GORM连接，backend 为 "mysql" 或 "sqlite"
+func NewGormStore(db *gorm.DB, backend string) Store {
+	return &gormStore{db: db, backend: backend}
+}
+
+func (s *gormStore) Instances() InstanceRepository     { return &gormInstances{db: s.db} }
+func (s *gormStore) Definitions() DefinitionRepository { return &gormDefinitions{db: s.db} }
+func (s *gormStore) Permissions() PermissionRepository { return &gormPermissions{db: s.db} }
+func (s *gormStore) Outbox() OutboxRepository          { return &gormOutbox{db: s.db} }
+func (s *gormStore) ChangeSets() ChangeSetRepository   { return &gormChangeSets{db: s.db} }
+func (s *gormStore) ApprovalPolicies() ApprovalPolicyRepository {
+	return &gormApprovalPolicies{db: s.db}
+}
+func (s *gormStore) Overlays() OverlayRepository { return &gormOverlays{db: s.db} }
+func (s *gormStore) History() HistoryRepository  { return &gormHistory{db: s.db} }
+func (s *gormStore) Ciphertexts() CiphertextRepository {
+	return &gormCiphertexts{db: s.db}
+}
+func (s *gormStore) Backend() string { return s.backend }
+
+func (s *gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx, backend: s.backend})
+	})
+}
+
+func (s *gormStore) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *gormStore) Stats() interface{} {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil
+	}
+	return sqlDB.Stats()
+}
+
+func (s *gormStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// notFound 将GORM的未找到错误统一为 ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func paginate(page, pageSize, maxSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > maxSize {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+type gormInstances struct{ db *gorm.DB }
+
+func (r *gormInstances) List(ctx context.Context, page, pageSize int, keyword string) ([]model.VNFInstance, int64, error) {
+	page, pageSize = paginate(page, pageSize, 100)
+	q := r.db.WithContext(ctx).Model(&model.VNFInstance{})
+	if keyword != "" {
+		q = q.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.VNFInstance
+	if err := q.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *gormInstances) ListAll(ctx context.Context, id uint) ([]model.VNFInstance, error) {
+	q := r.db.WithContext(ctx).Order("id asc")
+	if id != 0 {
+		q = q.Where("id = ?", id)
+	}
+	var items []model.VNFInstance
+	err := q.Find(&items).Error
+	return items, err
+}
+
+func (r *gormInstances) Get(ctx context.Context, id uint) (*model.VNFInstance, error) {
+	var item model.VNFInstance
+	if err := r.db.WithContext(ctx).First(&item, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &item, nil
+}
+
+func (r *gormInstances) Create(ctx context.Context, inst *model.VNFInstance) error {
+	return r.db.WithContext(ctx).Create(inst).Error
+}
+
+func (r *gormInstances) Save(ctx context.Context, inst *model.VNFInstance) error {
+	return r.db.WithContext(ctx).Save(inst).Error
+}
+
+func (r *gormInstances) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&model.VNFInstance{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormDefinitions struct{ db *gorm.DB }
+
+func (r *gormDefinitions) List(ctx context.Context, filter DefinitionFilter) ([]model.VNFDefinition, int64, error) {
+	page, pageSize := paginate(filter.Page, filter.PageSize, 200)
+	q := r.db.WithContext(ctx).Model(&model.VNFDefinition{}).Where("vnf_id = ?", filter.VNFID)
+	if filter.ModifiedOnly {
+		q = q.Where("modified = ?", true)
+	}
+	if len(filter.ExcludeNames) > 0 {
+		q = q.Where("parameter_name NOT IN ?", filter.ExcludeNames)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.VNFDefinition
+	if err := q.Order("id asc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *gormDefinitions) ListByVNF(ctx context.Context, vnfID uint) ([]model.VNFDefinition, error) {
+	q := r.db.WithContext(ctx).Order("id asc")
+	if vnfID != 0 {
+		q = q.Where("vnf_id = ?", vnfID)
+	}
+	var items []model.VNFDefinition
+	err := q.Find(&items).Error
+	return items, err
+}
+
+func (r *gormDefinitions) Get(ctx context.Context, vnfID, defID uint) (*model.VNFDefinition, error) {
+	var item model.VNFDefinition
+	if err := r.db.WithContext(ctx).Where("id = ? AND vnf_id = ?", defID, vnfID).First(&item).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &item, nil
+}
+
+func (r *gormDefinitions) Create(ctx context.Context, def *model.VNFDefinition) error {
+	return r.db.WithContext(ctx).Create(def).Error
+}
+
+func (r *gormDefinitions) CreateBatch(ctx context.Context, defs []model.VNFDefinition) error {
+	if len(defs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&defs).Error
+}
+
+func (r *gormDefinitions) Save(ctx context.Context, def *model.VNFDefinition) error {
+	return r.db.WithContext(ctx).Save(def).Error
+}
+
+func (r *gormDefinitions) Delete(ctx context.Context, vnfID, defID uint) error {
+	res := r.db.WithContext(ctx).Where("vnf_id = ? AND id = ?", vnfID, defID).Delete(&model.VNFDefinition{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormDefinitions) DeleteByVNF(ctx context.Context, vnfID uint) error {
+	return r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Delete(&model.VNFDefinition{}).Error
+}
+
+type gormPermissions struct{ db *gorm.DB }
+
+func (r *gormPermissions) ListByVNF(ctx context.Context, vnfID uint) ([]model.ParameterPermission, error) {
+	var items []model.ParameterPermission
+	err := r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Order("scope asc, target asc").Find(&items).Error
+	return items, err
+}
+
+func (r *gormPermissions) Get(ctx context.Context, vnfID, id uint) (*model.ParameterPermission, error) {
+	var item model.ParameterPermission
+	if err := r.db.WithContext(ctx).Where("id = ? AND vnf_id = ?", id, vnfID).First(&item).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &item, nil
+}
+
+func (r *gormPermissions) Upsert(ctx context.Context, rule *model.ParameterPermission) error {
+	var existing model.ParameterPermission
+	err := r.db.WithContext(ctx).
+		Where("vnf_id = ? AND scope = ? AND target = ?", rule.VNFID, rule.Scope, rule.Target).
+		First(&existing).Error
+	switch {
+	case err == nil:
+		rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+func (r *gormPermissions) Delete(ctx context.Context, vnfID, id uint) error {
+	res := r.db.WithContext(ctx).Where("vnf_id = ? AND id = ?", vnfID, id).Delete(&model.ParameterPermission{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPermissions) DeleteByVNF(ctx context.Context, vnfID uint) error {
+	return r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Delete(&model.ParameterPermission{}).Error
+}
+
+type gormChangeSets struct{ db *gorm.DB }
+
+// orderByID 预加载的条目与审批意见按创建顺序返回
+func orderByID(db *gorm.DB) *gorm.DB { return db.Order("id asc") }
+
+func (r *gormChangeSets) List(ctx context.Context, filter ChangeSetFilter) ([]model.ChangeSet, int64, error) {
+	page, pageSize := paginate(filter.Page, filter.PageSize, 100)
+	q := r.db.WithContext(ctx).Model(&model.ChangeSet{})
+	if filter.VNFID != 0 {
+		q = q.Where("vnf_id = ?", filter.VNFID)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.ChangeSet
+	err := q.Preload("Items", orderByID).Preload("Reviews", orderByID).
+		Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&items).Error
+	return items, total, err
+}
+
+func (r *gormChangeSets) Get(ctx context.Context, vnfID, id uint) (*model.ChangeSet, error) {
+	var cs model.ChangeSet
+	err := r.db.WithContext(ctx).Preload("Items", orderByID).Preload("Reviews", orderByID).
+		Where("id = ? AND vnf_id = ?", id, vnfID).First(&cs).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &cs, nil
+}
+
+func (r *gormChangeSets) Create(ctx context.Context, cs *model.ChangeSet) error {
+	return r.db.WithContext(ctx).Create(cs).Error
+}
+
+func (r *gormChangeSets) Update(ctx context.Context, cs *model.ChangeSet, status string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(cs).Omit(clause.Associations).
+		Where("status = ?", status).Select("*").Updates(cs)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *gormChangeSets) ReplaceItems(ctx context.Context, id uint, items []model.ChangeSetItem) error {
+	if err := r.db.WithContext(ctx).Where("change_set_id = ?", id).Delete(&model.ChangeSetItem{}).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	for i := range items {
+		items[i].ID, items[i].ChangeSetID = 0, id
+	}
+	return r.db.WithContext(ctx).Create(&items).Error
+}
+
+func (r *gormChangeSets) AddReview(ctx context.Context, review *model.ChangeSetReview) error {
+	return r.db.WithContext(ctx).Create(review).Error
+}
+
+func (r *gormChangeSets) ClearReviews(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Where("change_set_id = ?", id).Delete(&model.ChangeSetReview{}).Error
+}
+
+func (r *gormChangeSets) DeleteByVNF(ctx context.Context, vnfID uint) error {
+	ids := r.db.Model(&model.ChangeSet{}).Select("id").Where("vnf_id = ?", vnfID)
+	if err := r.db.WithContext(ctx).Where("change_set_id IN (?)", ids).Delete(&model.ChangeSetItem{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Where("change_set_id IN (?)", ids).Delete(&model.ChangeSetReview{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Delete(&model.ChangeSet{}).Error
+}
+
+type gormApprovalPolicies struct{ db *gorm.DB }
+
+func (r *gormApprovalPolicies) ListByVNF(ctx context.Context, vnfID uint) ([]model.ApprovalPolicy, error) {
+	var items []model.ApprovalPolicy
+	err := r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Order("`group` asc").Find(&items).Error
+	return items, err
+}
+
+func (r *gormApprovalPolicies) Upsert(ctx context.Context, policy *model.ApprovalPolicy) error {
+	var existing model.ApprovalPolicy
+	err := r.db.WithContext(ctx).
+		Where("vnf_id = ? AND `group` = ?", policy.VNFID, policy.Group).
+		First(&existing).Error
+	switch {
+	case err == nil:
+		policy.ID, policy.CreatedAt = existing.ID, existing.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return r.db.WithContext(ctx).Save(policy).Error
+}
+
+func (r *gormApprovalPolicies) Delete(ctx context.Context, vnfID, id uint) error {
+	res := r.db.WithContext(ctx).Where("vnf_id = ? AND id = ?", vnfID, id).Delete(&model.ApprovalPolicy{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormApprovalPolicies) DeleteByVNF(ctx context.Context, vnfID uint) error {
+	return r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Delete(&model.ApprovalPolicy{}).Error
+}
+
+type gormOverlays struct{ db *gorm.DB }
+
+func (r *gormOverlays) ListByVNF(ctx context.Context, vnfID uint) ([]model.Overlay, error) {
+	var items []model.Overlay
+	err := r.db.WithContext(ctx).Preload("Values", orderByID).Where("vnf_id = ?", vnfID).Order("name asc").Find(&items).Error
+	return items, err
+}
+
+func (r *gormOverlays) Get(ctx context.Context, vnfID uint, name string) (*model.Overlay, error) {
+	var overlay model.Overlay
+	err := r.db.WithContext(ctx).Preload("Values", orderByID).
+		Where("vnf_id = ? AND name = ?", vnfID, name).First(&overlay).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &overlay, nil
+}
+
+func (r *gormOverlays) Create(ctx context.Context, overlay *model.Overlay) error {
+	return r.db.WithContext(ctx).Create(overlay).Error
+}
+
+func (r *gormOverlays) Update(ctx context.Context, overlay *model.Overlay) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(overlay).Error
+}
+
+func (r *gormOverlays) ReplaceValues(ctx context.Context, id uint, values []model.OverlayValue) error {
+	if err := r.db.WithContext(ctx).Where("overlay_id = ?", id).Delete(&model.OverlayValue{}).Error; err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	for i := range values {
+		values[i].ID, values[i].OverlayID = 0, id
+	}
+	return r.db.WithContext(ctx).Create(&values).Error
+}
+
+func (r *gormOverlays) Delete(ctx context.Context, vnfID, id uint) error {
+	ids := r.db.Model(&model.Overlay{}).Select("id").Where("vnf_id = ? AND id = ?", vnfID, id)
+	if err := r.db.WithContext(ctx).Where("overlay_id IN (?)", ids).Delete(&model.OverlayValue{}).Error; err != nil {
+		return err
+	}
+	res := r.db.WithContext(ctx).Where("vnf_id = ? AND id = ?", vnfID, id).Delete(&model.Overlay{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormOverlays) DeleteByVNF(ctx context.Context, vnfID uint) error {
+	ids := r.db.Model(&model.Overlay{}).Select("id").Where("vnf_id = ?", vnfID)
+	if err := r.db.WithContext(ctx).Where("overlay_id IN (?)", ids).Delete(&model.OverlayValue{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("vnf_id = ?", vnfID).Delete(&model.Overlay{}).Error
+}
+
+type gormOutbox struct{ db *gorm.DB }
+
+// outstandingStatuses 尚未完成的发件箱操作状态
+var outstandingStatuses = []string{model.OutboxStatusPending, model.OutboxStatusFailed}
+
+func (r *gormOutbox) Create(ctx context.Context, entry *model.MongoOutbox) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *gormOutbox) FindByIdempotencyKey(ctx context.Context, key string) (*model.MongoOutbox, error) {
+	var entry model.MongoOutbox
+	if err := r.db.WithContext(ctx).Where("idempotency_key = ?", key).First(&entry).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &entry, nil
+}
+
+func (r *gormOutbox) ListPending(ctx context.Context, afterID uint, limit int) ([]model.MongoOutbox, error) {
+	// 早期记录没有顺序键，由调用方按集合与过滤条件判断是否被阻塞
+	failedKeys := r.db.Model(&model.MongoOutbox{}).Select("ordering_key").
+		Where("status = ? AND ordering_key <> ''", model.OutboxStatusFailed)
+	var entries []model.MongoOutbox
+	err := r.db.WithContext(ctx).Where("status = ? AND id > ?", model.OutboxStatusPending, afterID).
+		Where("ordering_key = '' OR ordering_key NOT IN (?)", failedKeys).
+		Order("id asc").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormOutbox) ListOutstanding(ctx context.Context, ids []uint, limit int) ([]model.MongoOutbox, error) {
+	q := r.db.WithContext(ctx).Where("status IN ?", outstandingStatuses)
+	if len(ids) > 0 {
+		q = q.Where("id IN ?", ids)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var entries []model.MongoOutbox
+	err := q.Order("id asc").Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormOutbox) CountByStatus(ctx context.Context, status string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.MongoOutbox{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+func (r *gormOutbox) CountOutstanding(ctx context.Context, orderingKey string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.MongoOutbox{}).
+		Where("ordering_key = ? AND status IN ?", orderingKey, outstandingStatuses).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormOutbox) Update(ctx context.Context, id uint, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.MongoOutbox{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormOutbox) ResetFailed(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&model.MongoOutbox{}).
+		Where("id = ? AND status = ?", id, model.OutboxStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          model.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *gormOutbox) DiscardFailed(ctx context.Context, id uint) (bool, error) {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&model.MongoOutbox{}).
+		Where("id = ? AND status = ?", id, model.OutboxStatusFailed).
+		Updates(map[string]interface{}{
+			"status":     model.OutboxStatusDiscarded,
+			"applied_at": &now,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *gormOutbox) ListFailed(ctx context.Context) ([]model.MongoOutbox, error) {
+	var entries []model.MongoOutbox
+	err := r.db.WithContext(ctx).Select("ordering_key", "collection", "filter").
+		Where("status = ?", model.OutboxStatusFailed).Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormOutbox) DeleteApplied(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("status IN ? AND applied_at < ?",
+		[]string{model.OutboxStatusApplied, model.OutboxStatusDiscarded}, before).
+		Delete(&model.MongoOutbox{})
+	return res.RowsAffected, res.Error
+}
+
+type gormHistory struct{ db *gorm.DB }
+
+func (r *gormHistory) Append(ctx context.Context, record *model.ChangeRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+func (r *gormHistory) List(ctx context.Context, filter HistoryFilter) ([]model.ChangeRecord, int64, error) {
+	page, pageSize := paginate(filter.Page, filter.PageSize, 500)
+	q := r.db.WithContext(ctx).Model(&model.ChangeRecord{})
+	if filter.VNFID != 0 {
+		q = q.Where("vnf_id = ?", filter.VNFID)
+	}
+	if filter.EntityType != "" {
+		q = q.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		q = q.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ParameterName != "" {
+		q = q.Where("parameter_name = ?", filter.ParameterName)
+	}
+	if filter.Action != "" {
+		q = q.Where("action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		q = q.Where("actor = ?", filter.Actor)
+	}
+	if filter.RequestID != "" {
+		q = q.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q = q.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		q = q.Where("id < ?", filter.BeforeID)
+	}
+	if len(filter.ExcludeParameters) > 0 {
+		q = q.Where("parameter_name NOT IN ?", filter.ExcludeParameters)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.ChangeRecord
+	if err := q.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// sealedColumns 各位置对应的表与可能含密文的字段
+var sealedColumns = map[string]struct {
+	model   interface{}
+	columns []string
+}{
+	SealedOverlayValues:  {&model.OverlayValue{}, []string{"value"}},
+	SealedChangeSetItems: {&model.ChangeSetItem{}, []string{"base_value", "new_value"}},
+	SealedHistory:        {&model.ChangeRecord{}, []string{"before", "after"}},
+	SealedOutbox:         {&model.MongoOutbox{}, []string{"document"}},
+}
+
+type gormCiphertexts struct{ db *gorm.DB }
+
+func (r *gormCiphertexts) Scan(ctx context.Context, location, marker string, afterID uint, limit int) ([]SealedField, error) {
+	loc, ok := sealedColumns[location]
+	if !ok {
+		return nil, fmt.Errorf("未知的密文位置: %s", location)
+	}
+	pattern := "%" + marker + "%"
+	cond := r.db.Where("? LIKE ?", clause.Column{Name: loc.columns[0]}, pattern)
+	for _, col := range loc.columns[1:] {
+		cond = cond.Or("? LIKE ?", clause.Column{Name: col}, pattern)
+	}
+	var rows []map[string]interface{}
+	err := r.db.WithContext(ctx).Model(loc.model).Select(append([]string{"id"}, loc.columns...)).
+		Where("id > ?", afterID).Where(cond).Order("id asc").Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	var fields []SealedField
+	for _, row := range rows {
+		id, err := strconv.ParseUint(fmt.Sprint(row["id"]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		for _, col := range loc.columns {
+			var value string
+			switch v := row[col].(type) {
+			case string:
+				value = v
+			case []byte:
+				value = string(v)
+			}
+			if strings.Contains(value, marker) {
+				fields = append(fields, SealedField{ID: uint(id), Column: col, Value: value})
+			}
+		}
+	}
+	return fields, nil
+}
+
+func (r *gormCiphertexts) Replace(ctx context.Context, location string, field SealedField, value string) (bool, error) {
+	loc, ok := sealedColumns[location]
+	if !ok {
+		return false, fmt.Errorf("未知的密文位置: %s", location)
+	}
+	res := r.db.WithContext(ctx).Model(loc.model).
+		Where("id = ? AND ? = ?", field.ID, clause.Column{Name: field.Column}, field.Value).
+		UpdateColumn(field.Column, value)
+	return res.RowsAffected > 0, res.Error
+}
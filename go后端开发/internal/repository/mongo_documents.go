@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoDocuments 基于MongoDB的文档存储
+type mongoDocuments struct {
+	client   *mongo.Client
+	database string
+}
+
+// NewMongoDocuments 包装已连接的MongoDB客户端
+func NewMongoDocuments(client *mongo.Client, database string) DocumentRepository {
+	return &mongoDocuments{client: client, database: database}
+}
+
+func (r *mongoDocuments) coll(name string) *mongo.Collection {
+	return r.client.Database(r.database).Collection(name)
+}
+
+func (r *mongoDocuments) Replace(ctx context.Context, collection string, filter bson.M, doc interface{}) error {
+	_, err := r.coll(collection).ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *mongoDocuments) Delete(ctx context.Context, collection string, filter bson.M) error {
+	_, err := r.coll(collection).DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *mongoDocuments) Find(ctx context.Context, collection string, filter bson.M, out interface{}) error {
+	cursor, err := r.coll(collection).Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
+
+func (r *mongoDocuments) FindOne(ctx context.Context, collection string, filter bson.M, out interface{}) error {
+	err := r.coll(collection).FindOne(ctx, filter).Decode(out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *mongoDocuments) Backend() string { return "mongodb" }
+
+func (r *mongoDocuments) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx, nil)
+}
+
+func (r *mongoDocuments) Close(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"vnf-config/internal/model"
+)
+
+// ErrNotFound 记录或文档不存在
+var ErrNotFound = errors.New("记录不存在")
+
+// 文档集合名称
+const (
+	CollectionInstances   = "vnf_instances"
+	CollectionDefinitions = "vnf_definitions"
+)
+
+// InstanceRepository VNF实例存储
+type InstanceRepository interface {
+	List(ctx context.Context, page, pageSize int, keyword string) ([]model.VNFInstance, int64, error)
+	// ListAll 返回全部实例；id 非 0 时只返回该实例
+	ListAll(ctx context.Context, id uint) ([]model.VNFInstance, error)
+	Get(ctx context.Context, id uint) (*model.VNFInstance, error)
+	Create(ctx context.Context, inst *model.VNFInstance) error
+	// Save 按主键插入或更新
+	Save(ctx context.Context, inst *model.VNFInstance) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// DefinitionFilter 参数定义分页查询条件
+type DefinitionFilter struct {
+	VNFID        uint
+	ModifiedOnly bool
+	// ExcludeNames 不返回的参数（调用方无权查看）
+	ExcludeNames []string
+	Page         int
+	PageSize     int
+}
+
+// DefinitionRepository VNF参数定义存储
+type DefinitionRepository interface {
+	List(ctx context.Context, filter DefinitionFilter) ([]model.VNFDefinition, int64, error)
+	// ListByVNF 返回某VNF的全部定义；vnfID 为 0 时返回所有定义
+	ListByVNF(ctx context.Context, vnfID uint) ([]model.VNFDefinition, error)
+	Get(ctx context.Context, vnfID, defID uint) (*model.VNFDefinition, error)
+	Create(ctx context.Context, def *model.VNFDefinition) error
+	CreateBatch(ctx context.Context, defs []model.VNFDefinition) error
+	Save(ctx context.Context, def *model.VNFDefinition) error
+	Delete(ctx context.Context, vnfID, defID uint) error
+	DeleteByVNF(ctx context.Context, vnfID uint) error
+}
+
+// OutboxRepository 文档存储发件箱
+type OutboxRepository interface {
+	Create(ctx context.Context, entry *model.MongoOutbox) error
+	FindByIdempotencyKey(ctx context.Context, key string) (*model.MongoOutbox, error)
+	// ListPending 按记录顺序返回 afterID 之后待执行的操作，跳过被已放弃操作阻塞的顺序键
+	ListPending(ctx context.Context, afterID uint, limit int) ([]model.MongoOutbox, error)
+	// ListOutstanding 返回待执行或已放弃的操作；ids 非空时只在其中查找
+	ListOutstanding(ctx context.Context, ids []uint, limit int) ([]model.MongoOutbox, error)
+	CountByStatus(ctx context.Context, status string) (int64, error)
+	CountOutstanding(ctx context.Context, orderingKey string) (int64, error)
+	Update(ctx context.Context, id uint, fields map[string]interface{}) error
+	// ResetFailed 将已放弃的操作恢复为待执行，返回是否找到该操作
+	ResetFailed(ctx context.Context, id uint) (bool, error)
+	// DiscardFailed 丢弃已放弃的操作，返回是否找到该操作
+	DiscardFailed(ctx context.Context, id uint) (bool, error)
+	// ListFailed 返回已放弃的操作（只含顺序键、集合与过滤条件）
+	ListFailed(ctx context.Context) ([]model.MongoOutbox, error)
+	// DeleteApplied 删除 before 之前已成功或已丢弃的操作，返回删除的条数
+	DeleteApplied(ctx context.Context, before time.Time) (int64, error)
+}
+
+// HistoryFilter 变更历史查询条件，零值字段不参与过滤
+type HistoryFilter struct {
+	VNFID         uint
+	EntityType    string
+	EntityID      uint
+	ParameterName string
+	Action        string
+	Actor         string
+	RequestID     string
+	// Since/Until 按记录时间过滤，Until 不含
+	Since time.Time
+	Until time.Time
+	// BeforeID 只返回ID小于该值的记录，用于导出时按ID翻页，避免新记录插入导致重复
+	BeforeID uint
+	// ExcludeParameters 不返回这些参数的变更记录（调用方无权查看）
+	ExcludeParameters []string
+	Page              int
+	PageSize          int
+}
+
+// HistoryRepository 变更历史（只追加，不提供修改与删除；主密钥轮换经 CiphertextRepository 重新加密快照中的密文）
+type HistoryRepository interface {
+	Append(ctx context.Context, record *model.ChangeRecord) error
+	List(ctx context.Context, filter HistoryFilter) ([]model.ChangeRecord, int64, error)
+}
+
+// PermissionRepository 参数权限规则存储
+type PermissionRepository interface {
+	ListByVNF(ctx context.Context, vnfID uint) ([]model.ParameterPermission, error)
+	Get(ctx context.Context, vnfID, id uint) (*model.ParameterPermission, error)
+	// Upsert 按 VNF、作用范围与目标插入或覆盖规则
+	Upsert(ctx context.Context, rule *model.ParameterPermission) error
+	Delete(ctx context.Context, vnfID, id uint) error
+	DeleteByVNF(ctx context.Context, vnfID uint) error
+}
+
+// ChangeSetFilter 变更单查询条件，零值字段不参与过滤
+type ChangeSetFilter struct {
+	VNFID    uint
+	Status   string
+	Page     int
+	PageSize int
+}
+
+// ChangeSetRepository 变更单及其条目、审批意见
+type ChangeSetRepository interface {
+	// List 按ID倒序返回变更单，包含条目与审批意见
+	List(ctx context.Context, filter ChangeSetFilter) ([]model.ChangeSet, int64, error)
+	Get(ctx context.Context, vnfID, id uint) (*model.ChangeSet, error)
+	// Create 创建变更单及其条目
+	Create(ctx context.Context, cs *model.ChangeSet) error
+	// Update 仅当变更单仍处于 status 状态时保存变更单本身（不含条目与审批意见），返回是否已保存
+	Update(ctx context.Context, cs *model.ChangeSet, status string) (bool, error)
+	ReplaceItems(ctx context.Context, id uint, items []model.ChangeSetItem) error
+	AddReview(ctx context.Context, review *model.ChangeSetReview) error
+	ClearReviews(ctx context.Context, id uint) error
+	DeleteByVNF(ctx context.Context, vnfID uint) error
+}
+
+// ApprovalPolicyRepository 分组审批策略
+type ApprovalPolicyRepository interface {
+	ListByVNF(ctx context.Context, vnfID uint) ([]model.ApprovalPolicy, error)
+	// Upsert 按 VNF 与分组插入或覆盖策略
+	Upsert(ctx context.Context, policy *model.ApprovalPolicy) error
+	Delete(ctx context.Context, vnfID, id uint) error
+	DeleteByVNF(ctx context.Context, vnfID uint) error
+}
+
+// OverlayRepository 环境覆盖层及其取值，查询结果包含取值
+type OverlayRepository interface {
+	ListByVNF(ctx context.Context, vnfID uint) ([]model.Overlay, error)
+	Get(ctx context.Context, vnfID uint, name string) (*model.Overlay, error)
+	// Create 创建覆盖层及其取值
+	Create(ctx context.Context, overlay *model.Overlay) error
+	// Update 保存覆盖层本身（不含取值）
+	Update(ctx context.Context, overlay *model.Overlay) error
+	ReplaceValues(ctx context.Context, id uint, values []model.OverlayValue) error
+	Delete(ctx context.Context, vnfID, id uint) error
+	DeleteByVNF(ctx context.Context, vnfID uint) error
+}
+
+// 保存机密取值（或含机密取值的快照）的位置，主密钥轮换时统计并重新加密其中的密文。
+// 参数定义经双存储写入，不在其中
+const (
+	SealedOverlayValues  = "overlays"   // 覆盖层取值
+	SealedChangeSetItems = "changeSets" // 变更单条目的基准值与新值
+	SealedHistory        = "history"    // 变更历史的修改前后快照
+	SealedOutbox         = "outbox"     // 待派发到文档存储的定义文档
+)
+
+// SealedLocations 全部 Sealed* 位置
+var SealedLocations = []string{SealedOverlayValues, SealedChangeSetItems, SealedHistory, SealedOutbox}
+
+// SealedField 某条记录中含密文的一个字段
+type SealedField struct {
+	ID     uint
+	Column string
+	Value  string
+}
+
+// CiphertextRepository 按位置查找与改写含密文的字段
+type CiphertextRepository interface {
+	// Scan 按ID顺序返回 location 中 ID 大于 afterID、字段值包含 marker 的记录（最多 limit 条）的这些字段
+	Scan(ctx context.Context, location, marker string, afterID uint, limit int) ([]SealedField, error)
+	// Replace 字段仍为 field.Value 时改为 value，返回是否已修改
+	Replace(ctx context.Context, location string, field SealedField, value string) (bool, error)
+}
+
+// Store 关系型存储：实例、定义、权限规则、发件箱和变更历史，支持事务
+type Store interface {
+	Instances() InstanceRepository
+	Definitions() DefinitionRepository
+	Permissions() PermissionRepository
+	ChangeSets() ChangeSetRepository
+	ApprovalPolicies() ApprovalPolicyRepository
+	Overlays() OverlayRepository
+	Outbox() OutboxRepository
+	History() HistoryRepository
+	Ciphertexts() CiphertextRepository
+	// Transaction 在同一事务中执行 fn，fn 返回错误时回滚
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+	Backend() string
+	Ping(ctx context.Context) error
+	Stats() interface{}
+	Close() error
+}
+
+// DocumentRepository YAML文档存储（完整配置与定义文档）。
+// 过滤条件只支持顶层字段相等匹配，字符串字段的 $regex/$options，以及 $exists。
+type DocumentRepository interface {
+	// Replace 整体替换第一个匹配的文档，不存在时插入
+	Replace(ctx context.Context, collection string, filter bson.M, doc interface{}) error
+	Delete(ctx context.Context, collection string, filter bson.M) error
+	// Find 将全部匹配文档解码到 out（切片指针）
+	Find(ctx context.Context, collection string, filter bson.M, out interface{}) error
+	// FindOne 解码第一个匹配文档，未找到时返回 ErrNotFound
+	FindOne(ctx context.Context, collection string, filter bson.M, out interface{}) error
+	Backend() string
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+// Repositories 服务层使用的全部存储
+type Repositories struct {
+	Store     Store
+	Documents DocumentRepository
+	// DocumentsRequired 文档存储不可用时是否视为服务未就绪；
+	// 为 false 时服务降级运行：读接口回退到关系型存储，写入暂存在发件箱中
+	DocumentsRequired bool
+}
+
+// Close 关闭全部存储连接
+func (r *Repositories) Close(ctx context.Context) {
+	if r.Store != nil {
+		r.Store.Close()
+	}
+	if r.Documents != nil {
+		r.Documents.Close(ctx)
+	}
+}
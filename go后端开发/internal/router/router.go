@@ -3,14 +3,20 @@ package router
 import (
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
 	"vnf-config/internal/controller/v1"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/infra/metrics"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
 )
 
-func New() *gin.Engine {
+func New(repos *repository.Repositories, authenticator auth.Authenticator) *gin.Engine {
 	if os.Getenv("APP_ENV") != "production" {
 		gin.SetMode(gin.DebugMode)
 	} else {
@@ -18,33 +24,115 @@ func New() *gin.Engine {
 	}
 
 	r := gin.New()
+	// 服务层通过 gin.Context 读取请求上下文中的调用方
+	r.ContextWithFallback = true
 	r.Use(gin.Recovery())
-	r.Use(cors.Default())
+	r.Use(audit.Middleware())
+	r.Use(metrics.Middleware())
+	if cfg, ok := corsConfig(); ok {
+		r.Use(cors.New(cfg))
+	}
 
+	// 静态资源挂在 /static 下，避免根路径通配符与 /api 路由冲突
 	staticDir := defaultString(os.Getenv("STATIC_DIR"), "./web")
-	r.Static("/", staticDir)
+	r.Static("/static", staticDir)
+	r.GET("/", func(c *gin.Context) { c.File(filepath.Join(staticDir, "index.html")) })
+
+	// 健康检查
+	healthCtl := v1.NewHealthController(repos)
+	r.GET("/healthz", healthCtl.Healthz)
+	r.GET("/readyz", healthCtl.Readyz)
+	r.GET("/metrics", metrics.Handler())
 
-	api := r.Group("/api/v1")
+	api := r.Group("/api/v1", auth.Middleware(authenticator, "vnf-config"))
 	{
-		uploadCtl := v1.NewUploadController()
-		vnfCtl := v1.NewVNFController()
-		defCtl := v1.NewDefinitionController()
+		uploadCtl := v1.NewUploadController(repos)
+		vnfCtl := v1.NewVNFController(repos)
+		defCtl := v1.NewDefinitionController(repos)
+		storageCtl := v1.NewStorageController(repos)
+		permCtl := v1.NewPermissionController(repos)
+		auditCtl := v1.NewAuditController(repos)
+		changeSetCtl := v1.NewChangeSetController(repos)
+		overlayCtl := v1.NewOverlayController(repos)
+
+		viewer := auth.Require(auth.RoleViewer)
+		operator := auth.Require(auth.RoleOperator)
+		admin := auth.Require(auth.RoleAdmin)
+
+		api.GET("/auth/me", viewer, auth.Current)
 
 		// 上传相关
-		api.POST("/uploads", uploadCtl.UploadZip)
-		api.GET("/vnfs/:id/form-fields", uploadCtl.GetFormFields)
-		api.GET("/vnfs/:id/yaml-config", uploadCtl.GetYAMLConfig)
+		api.POST("/uploads", operator, uploadCtl.UploadZip)
+		api.GET("/vnfs/:id/form-fields", viewer, uploadCtl.GetFormFields)
+		api.GET("/vnfs/:id/yaml-config", viewer, uploadCtl.GetYAMLConfig)
 
 		// VNF实例管理
-		api.GET("/vnfs", vnfCtl.ListVNFInstances)
-		api.GET("/vnfs/:id", vnfCtl.GetVNFInstance)
-		api.DELETE("/vnfs/:id", vnfCtl.DeleteVNFInstance)
+		api.GET("/vnfs", viewer, vnfCtl.ListVNFInstances)
+		api.GET("/vnfs/compare", viewer, vnfCtl.CompareVNFInstances)
+		api.GET("/vnfs/compare/export", viewer, vnfCtl.ExportComparison)
+		api.GET("/vnfs/:id", viewer, vnfCtl.GetVNFInstance)
+		api.DELETE("/vnfs/:id", admin, vnfCtl.DeleteVNFInstance)
+		api.POST("/vnfs/:id/clone", operator, vnfCtl.CloneVNFInstance)
+		api.GET("/vnfs/:id/export", admin, vnfCtl.ExportVNFInstance)
+		api.POST("/vnfs/import", admin, vnfCtl.ImportVNFInstance)
+		api.GET("/vnfs/:id/render", viewer, vnfCtl.RenderVNFInstance)
+		api.GET("/vnfs/:id/history", viewer, vnfCtl.GetHistory)
+		api.GET("/vnfs/:id/history/export", viewer, vnfCtl.ExportHistory)
+
+		// 全部VNF的变更审计
+		api.GET("/audit", admin, auditCtl.ListAudit)
+		api.GET("/audit/export", admin, auditCtl.ExportAudit)
 
 		// VNF定义管理
-		api.GET("/vnfs/:id/definitions", defCtl.ListDefinitions)
-		api.POST("/vnfs/:id/definitions", defCtl.CreateDefinition)
-		api.PUT("/vnfs/:id/definitions/:defId", defCtl.UpdateDefinition)
-		api.DELETE("/vnfs/:id/definitions/:defId", defCtl.DeleteDefinition)
+		api.GET("/vnfs/:id/definitions", viewer, defCtl.ListDefinitions)
+		api.POST("/vnfs/:id/definitions", operator, defCtl.CreateDefinition)
+		api.PATCH("/vnfs/:id/definitions", operator, defCtl.BulkUpdateDefinitions)
+		api.PUT("/vnfs/:id/definitions/:defId", operator, defCtl.UpdateDefinition)
+		api.DELETE("/vnfs/:id/definitions/:defId", operator, defCtl.DeleteDefinition)
+		api.GET("/vnfs/:id/definitions/consistency", viewer, defCtl.CheckConsistency)
+		api.POST("/vnfs/:id/definitions/:defId/reveal", admin, defCtl.RevealDefinition)
+		api.POST("/vnfs/:id/definitions/reset", operator, defCtl.ResetDefinitions)
+		api.POST("/vnfs/:id/definitions/:defId/reset", operator, defCtl.ResetDefinition)
+		api.GET("/vnfs/:id/definitions/:defId/history", viewer, defCtl.GetValueHistory)
+		api.POST("/vnfs/:id/definitions/:defId/restore", operator, defCtl.RestoreDefinition)
+
+		// 变更单：暂存修改，经其他用户审批后整体应用
+		api.GET("/vnfs/:id/changesets", viewer, changeSetCtl.ListChangeSets)
+		api.POST("/vnfs/:id/changesets", operator, changeSetCtl.CreateChangeSet)
+		api.GET("/vnfs/:id/changesets/:csId", viewer, changeSetCtl.GetChangeSet)
+		api.PUT("/vnfs/:id/changesets/:csId", operator, changeSetCtl.UpdateChangeSet)
+		api.POST("/vnfs/:id/changesets/:csId/approve", viewer, changeSetCtl.ApproveChangeSet)
+		api.POST("/vnfs/:id/changesets/:csId/reject", viewer, changeSetCtl.RejectChangeSet)
+		api.POST("/vnfs/:id/changesets/:csId/apply", operator, changeSetCtl.ApplyChangeSet)
+		api.GET("/vnfs/:id/approval-policies", viewer, changeSetCtl.ListApprovalPolicies)
+		api.PUT("/vnfs/:id/approval-policies", admin, changeSetCtl.SetApprovalPolicy)
+		api.DELETE("/vnfs/:id/approval-policies/:policyId", admin, changeSetCtl.DeleteApprovalPolicy)
+
+		// 环境覆盖层，:name 为 base 时表示基础取值
+		api.GET("/vnfs/:id/overlays", viewer, overlayCtl.ListOverlays)
+		api.POST("/vnfs/:id/overlays", operator, overlayCtl.CreateOverlay)
+		api.GET("/vnfs/:id/overlays/diff", viewer, overlayCtl.DiffOverlays)
+		api.GET("/vnfs/:id/overlays/:name", viewer, overlayCtl.GetOverlay)
+		api.PUT("/vnfs/:id/overlays/:name", operator, overlayCtl.UpdateOverlay)
+		api.DELETE("/vnfs/:id/overlays/:name", operator, overlayCtl.DeleteOverlay)
+		api.GET("/vnfs/:id/overlays/:name/effective", viewer, overlayCtl.GetEffectiveValues)
+
+		// 参数权限规则，规则本身不含参数取值
+		api.GET("/vnfs/:id/permissions", viewer, permCtl.ListPermissions)
+		api.PUT("/vnfs/:id/permissions", admin, permCtl.SetPermission)
+		api.DELETE("/vnfs/:id/permissions/:ruleId", admin, permCtl.DeletePermission)
+
+		// 存储同步状态
+		api.GET("/storage/status", viewer, storageCtl.GetStorageStatus)
+		api.GET("/storage/outbox", viewer, storageCtl.GetOutboxStatus)
+		api.POST("/storage/outbox/:id/retry", admin, storageCtl.RetryOutboxItem)
+		api.POST("/storage/outbox/:id/discard", admin, storageCtl.DiscardOutboxItem)
+		api.POST("/storage/reconcile", admin, storageCtl.Reconcile)
+		api.GET("/storage/reconcile/last", viewer, storageCtl.GetLastReconcileReport)
+		api.GET("/storage/secrets", admin, storageCtl.GetSecretStatus)
+		api.POST("/storage/secrets/rotate", admin, storageCtl.RotateSecrets)
+		api.GET("/storage/gitops", viewer, storageCtl.GetGitOpsStatus)
+		api.POST("/storage/gitops/pull", admin, storageCtl.PullGitOps)
 	}
 
 	r.NoRoute(func(c *gin.Context) {
@@ -54,11 +142,32 @@ func New() *gin.Engine {
 	return r
 }
 
+// corsConfig 读取 CORS_ALLOWED_ORIGINS（逗号分隔，"*" 表示任意来源）。
+// 未设置时不启用CORS，只允许同源访问（内置Web界面与API同源）。
+func corsConfig() (cors.Config, bool) {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	if len(origins) == 0 {
+		return cors.Config{}, false
+	}
+	cfg := cors.DefaultConfig()
+	cfg.AddAllowHeaders("Authorization", audit.HeaderRequestID, audit.HeaderReason)
+	cfg.AddExposeHeaders(audit.HeaderRequestID, "Content-Disposition")
+	if len(origins) == 1 && origins[0] == "*" {
+		cfg.AllowAllOrigins = true
+	} else {
+		cfg.AllowOrigins = origins
+	}
+	return cfg, true
+}
+
 func defaultString(v string, d string) string {
 	if v == "" {
 		return d
 	}
 	return v
 }
-
-
@@ -0,0 +1,683 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
+)
+
+// 归档格式标识与版本，导入时校验
+const (
+	ArchiveFormat  = "vnf-config-archive"
+	ArchiveVersion = 1
+)
+
+// 归档内的文件：manifest.json 说明归档内容，参数集文档为 vnf.json 或 vnf.yaml，
+// 原始安装包为 package.zip（已去除机密参数的默认值，其密文随参数定义导出）
+const (
+	archiveManifest = "manifest.json"
+	archivePackage  = "package.zip"
+)
+
+// ExportFormatYAML 归档中参数集文档使用YAML
+const ExportFormatYAML = "yaml"
+
+// 导入时实例名称冲突的处理方式
+const (
+	ConflictFail   = "fail"
+	ConflictRename = "rename"
+)
+
+// archiveEntryLimit 归档中单个文件解压后的大小上限
+const archiveEntryLimit = 64 << 20
+
+var (
+	ErrArchiveFormat   = errors.New("不支持的归档格式（可选 json、yaml）")
+	ErrInvalidArchive  = errors.New("归档文件无效")
+	ErrConflictOption  = errors.New("onConflict 可选 fail、rename")
+	ErrArchiveTooLarge = errors.New("归档中的文件过大")
+)
+
+// ImportConflictError 导入的实例名称已被现有实例使用
+type ImportConflictError struct {
+	Name     string        `json:"name"`
+	Existing []CompareSide `json:"existing"`
+}
+
+func (e *ImportConflictError) Error() string {
+	return fmt.Sprintf("实例名称 %s 已存在", e.Name)
+}
+
+// ArchiveManifest 归档说明：格式版本、来源与各项数量，Document 为参数集文档的文件名
+type ArchiveManifest struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	ExportedBy string         `json:"exportedBy"`
+	Source     ArchiveSource  `json:"source"`
+	Document   string         `json:"document"`
+	Package    string         `json:"package,omitempty"`
+	Counts     map[string]int `json:"counts"`
+}
+
+// ArchiveSource 导出时的来源实例
+type ArchiveSource struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Backend   string    `json:"backend"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ArchiveOverlay 归档中的覆盖层，ID 与 ParentID 为来源实例中的ID，导入时重新分配
+type ArchiveOverlay struct {
+	ID          uint              `json:"id"`
+	Name        string            `json:"name"`
+	ParentID    *uint             `json:"parentId,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Values      map[string]string `json:"values"`
+}
+
+// ArchiveDocument 参数集文档：配置文档、参数定义、参数权限规则、审批策略与覆盖层。
+// 机密参数的取值保留导出环境的密文
+type ArchiveDocument struct {
+	Name             string                      `json:"name"`
+	Config           *YAMLConfig                 `json:"config"`
+	Definitions      []model.VNFDefinition       `json:"definitions"`
+	Permissions      []model.ParameterPermission `json:"permissions"`
+	ApprovalPolicies []model.ApprovalPolicy      `json:"approvalPolicies"`
+	Overlays         []ArchiveOverlay            `json:"overlays"`
+}
+
+// VNFArchive 导出的VNF参数集，pkg 为去除机密默认值后的原始安装包（未保留时为空）
+type VNFArchive struct {
+	Manifest ArchiveManifest
+	Document ArchiveDocument
+	pkg      []byte
+}
+
+// ImportOptions 导入选项：Name 为空时沿用归档中的实例名称
+type ImportOptions struct {
+	Name       string
+	OnConflict string
+	Reason     string
+}
+
+// ArchiveIDMap 归档中的ID到新建ID的映射
+type ArchiveIDMap struct {
+	Instance    map[uint]uint `json:"instance"`
+	Definitions map[uint]uint `json:"definitions"`
+	Overlays    map[uint]uint `json:"overlays"`
+}
+
+// ImportResult 导入结果，Warnings 列出未能原样导入的内容（如无法解密的机密参数）
+type ImportResult struct {
+	Instance     *model.VNFInstance `json:"instance"`
+	Renamed      bool               `json:"renamed"`
+	IDMap        ArchiveIDMap       `json:"idMap"`
+	Package      bool               `json:"package"`
+	Warnings     []string           `json:"warnings"`
+	MongoPending bool               `json:"mongoPending"`
+}
+
+// Export 读取VNF实例的完整参数集用于归档，由 WriteArchive 写出
+func (s *VNFService) Export(ctx context.Context, id uint) (*VNFArchive, error) {
+	instance, err := s.store.Instances().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	doc, source, err := s.dualStorage.GetVNFConfig(id)
+	if err != nil {
+		return nil, err
+	}
+	config, ok := doc.YAMLConfig.(*YAMLConfig)
+	if !ok {
+		return nil, errors.New("配置文档无法解析")
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.store.Permissions().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := s.store.ApprovalPolicies().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := s.store.Overlays().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &VNFArchive{Document: ArchiveDocument{
+		Name:             instance.Name,
+		Config:           config,
+		Definitions:      defs,
+		Permissions:      rules,
+		ApprovalPolicies: policies,
+		Overlays:         make([]ArchiveOverlay, 0, len(overlays)),
+	}}
+	for _, o := range overlays {
+		item := ArchiveOverlay{ID: o.ID, Name: o.Name, ParentID: o.ParentID, Description: o.Description, Values: make(map[string]string, len(o.Values))}
+		for _, v := range o.Values {
+			item.Values[v.ParameterName] = v.Value
+		}
+		archive.Document.Overlays = append(archive.Document.Overlays, item)
+	}
+	archive.Manifest = ArchiveManifest{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		ExportedBy: auth.ActorFrom(ctx),
+		Source:     ArchiveSource{ID: instance.ID, Name: instance.Name, Backend: source, CreatedAt: instance.CreatedAt},
+		Counts: map[string]int{
+			"definitions":      len(defs),
+			"permissions":      len(rules),
+			"approvalPolicies": len(policies),
+			"overlays":         len(overlays),
+		},
+	}
+	if path := packagePath(id); fileExists(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// 早期保存的安装包可能仍含机密参数的明文默认值
+		if archive.pkg, err = stripPackageSecrets(data); err != nil {
+			return nil, fmt.Errorf("原始安装包处理失败: %v", err)
+		}
+		archive.Manifest.Package = archivePackage
+	}
+	return archive, nil
+}
+
+// WriteArchive 按 format（json、yaml）写出ZIP归档：manifest.json、参数集文档与原始安装包
+func WriteArchive(archive *VNFArchive, format string, w io.Writer) error {
+	var doc []byte
+	var err error
+	switch format {
+	case ExportFormatJSON:
+		doc, err = json.MarshalIndent(archive.Document, "", "  ")
+	case ExportFormatYAML:
+		doc, err = marshalYAML(archive.Document)
+	default:
+		return ErrArchiveFormat
+	}
+	if err != nil {
+		return err
+	}
+	manifest := archive.Manifest
+	manifest.Document = "vnf." + format
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	names := []string{archiveManifest, manifest.Document}
+	contents := [][]byte{data, doc}
+	if archive.pkg != nil {
+		names, contents = append(names, archivePackage), append(contents, archive.pkg)
+	}
+	for i, name := range names {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(contents[i]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Import 由归档新建VNF实例：参数定义、参数权限规则、审批策略与覆盖层在同一事务中创建并重新分配ID。
+// 实例名称已存在时按 OnConflict 返回 *ImportConflictError 或改用带序号的名称；
+// 机密参数按本环境的主密钥重新加密，无法解密的取值置空并记入 Warnings
+func (s *VNFService) Import(ctx context.Context, r io.ReaderAt, size int64, opts ImportOptions) (*ImportResult, error) {
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+	if opts.OnConflict != ConflictFail && opts.OnConflict != ConflictRename {
+		return nil, ErrConflictOption
+	}
+	manifest, doc, pkg, err := readArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDescriptorPermissions(doc.Config); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Warnings: []string{}}
+	name := opts.Name
+	if name == "" {
+		name = doc.Name
+	}
+	conflict, err := s.nameConflict(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if conflict != nil {
+		if opts.OnConflict == ConflictFail {
+			return nil, conflict
+		}
+		if name, err = s.freeName(ctx, name); err != nil {
+			return nil, err
+		}
+		result.Renamed = true
+	}
+
+	defs := make([]model.VNFDefinition, len(doc.Definitions))
+	secret := make(map[string]bool)
+	for i, def := range doc.Definitions {
+		def.ID, def.VNFID = 0, 0
+		def.CreatedAt, def.UpdatedAt = time.Time{}, time.Time{}
+		if def.Type == TypeSecret {
+			secret[def.ParameterName] = true
+			owner := secretOwner(manifest.Source.ID, def.ParameterName)
+			def.DefaultValue = importSecret(owner, def.DefaultValue, def.ParameterName+" 的默认值", &result.Warnings)
+			def.CurrentValue = importSecret(owner, def.CurrentValue, def.ParameterName+" 的当前值", &result.Warnings)
+		}
+		defs[i] = def
+	}
+	rules := append([]model.ParameterPermission(nil), doc.Permissions...)
+	for i := range rules {
+		rules[i].ID, rules[i].VNFID = 0, 0
+		rules[i].CreatedAt, rules[i].UpdatedAt = time.Time{}, time.Time{}
+	}
+	policies := append([]model.ApprovalPolicy(nil), doc.ApprovalPolicies...)
+	for i := range policies {
+		policies[i].ID, policies[i].VNFID = 0, 0
+		policies[i].CreatedAt, policies[i].UpdatedAt = time.Time{}, time.Time{}
+	}
+	overlays := make([]model.Overlay, len(doc.Overlays))
+	for i, o := range doc.Overlays {
+		overlays[i] = model.Overlay{ID: o.ID, Name: o.Name, ParentID: o.ParentID, Description: o.Description}
+		params := make([]string, 0, len(o.Values))
+		for param := range o.Values {
+			params = append(params, param)
+		}
+		sort.Strings(params)
+		for _, param := range params {
+			value := o.Values[param]
+			if secret[param] {
+				value = importSecret(secretOwner(manifest.Source.ID, param), value, fmt.Sprintf("覆盖层 %s 中 %s 的取值", o.Name, param), &result.Warnings)
+			}
+			overlays[i].Values = append(overlays[i].Values, model.OverlayValue{ParameterName: param, Value: value})
+		}
+	}
+
+	if opts.Reason == "" && audit.Reason(ctx) == "" {
+		opts.Reason = fmt.Sprintf("导入自 %s（VNF #%d）", manifest.Source.Name, manifest.Source.ID)
+	}
+	ctx = audit.WithReason(ctx, opts.Reason)
+	instance := &model.VNFInstance{Name: name}
+	res := s.dualStorage.StoreVNFClone(ctx, instance, doc.Config, defs, rules, policies, overlays)
+	if !res.MySQLSuccess {
+		return nil, res.MySQLError
+	}
+	result.Instance, result.MongoPending = instance, res.MongoPending
+
+	result.IDMap = ArchiveIDMap{
+		Instance:    map[uint]uint{manifest.Source.ID: instance.ID},
+		Definitions: make(map[uint]uint, len(defs)),
+		Overlays:    make(map[uint]uint, len(overlays)),
+	}
+	for i, def := range defs {
+		result.IDMap.Definitions[doc.Definitions[i].ID] = def.ID
+	}
+	created, err := s.store.Overlays().ListByVNF(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range created {
+		for _, src := range doc.Overlays {
+			if src.Name == o.Name {
+				result.IDMap.Overlays[src.ID] = o.ID
+			}
+		}
+	}
+	if pkg != nil {
+		if err := writePackage(instance.ID, pkg); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("原始安装包保存失败: %v", err))
+		} else {
+			result.Package = true
+		}
+	}
+	return result, nil
+}
+
+// nameConflict 返回名称完全相同的现有实例
+func (s *VNFService) nameConflict(ctx context.Context, name string) (*ImportConflictError, error) {
+	items, _, err := s.store.Instances().List(ctx, 1, 100, name)
+	if err != nil {
+		return nil, err
+	}
+	conflict := &ImportConflictError{Name: name}
+	for _, item := range items {
+		if item.Name == name {
+			conflict.Existing = append(conflict.Existing, CompareSide{ID: item.ID, Name: item.Name})
+		}
+	}
+	if len(conflict.Existing) == 0 {
+		return nil, nil
+	}
+	return conflict, nil
+}
+
+// freeName 在名称后追加序号（name-2、name-3 …）直至不与现有实例重名
+func (s *VNFService) freeName(ctx context.Context, name string) (string, error) {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", name, n)
+		conflict, err := s.nameConflict(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if conflict == nil {
+			return candidate, nil
+		}
+	}
+}
+
+// readArchive 读取并校验归档：格式版本、参数名与覆盖层名称不重复、参数权限规则与审批策略有效
+func readArchive(r io.ReaderAt, size int64) (*ArchiveManifest, *ArchiveDocument, []byte, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	data, err := readEntry(files, archiveManifest)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	manifest := &ArchiveManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s 无法解析: %v", ErrInvalidArchive, archiveManifest, err)
+	}
+	if manifest.Format != ArchiveFormat || manifest.Version != ArchiveVersion {
+		return nil, nil, nil, fmt.Errorf("%w: 不支持的格式 %s 版本 %d", ErrInvalidArchive, manifest.Format, manifest.Version)
+	}
+	if data, err = readEntry(files, manifest.Document); err != nil {
+		return nil, nil, nil, err
+	}
+	doc := &ArchiveDocument{}
+	switch filepath.Ext(manifest.Document) {
+	case ".json":
+		err = json.Unmarshal(data, doc)
+	case ".yaml", ".yml":
+		err = unmarshalYAML(data, doc)
+	default:
+		err = errors.New("未知的文档类型")
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %s 无法解析: %v", ErrInvalidArchive, manifest.Document, err)
+	}
+	if err := validateArchive(doc); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	var pkg []byte
+	if manifest.Package != "" {
+		if pkg, err = readEntry(files, manifest.Package); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return manifest, doc, pkg, nil
+}
+
+func validateArchive(doc *ArchiveDocument) error {
+	if doc.Name == "" {
+		return errors.New("缺少实例名称")
+	}
+	if doc.Config == nil {
+		return errors.New("缺少配置文档")
+	}
+	names := make(map[string]bool, len(doc.Definitions))
+	for _, def := range doc.Definitions {
+		if def.ParameterName == "" || names[def.ParameterName] {
+			return fmt.Errorf("参数名 %q 为空或重复", def.ParameterName)
+		}
+		names[def.ParameterName] = true
+	}
+	overlays := make(map[string]bool, len(doc.Overlays))
+	for _, o := range doc.Overlays {
+		if o.Name == "" || o.Name == BaseOverlay || overlays[o.Name] {
+			return fmt.Errorf("覆盖层名称 %q 无效或重复", o.Name)
+		}
+		overlays[o.Name] = true
+	}
+	// 与通过接口设置时的校验一致，避免导入无效角色或越界的批准数
+	rules := make(map[string]bool, len(doc.Permissions))
+	for i := range doc.Permissions {
+		rule := &doc.Permissions[i]
+		if err := validatePermission(rule); err != nil {
+			return err
+		}
+		key := rule.Scope + ":" + rule.Target
+		if rules[key] {
+			return fmt.Errorf("%s %s 的权限规则重复", rule.Scope, rule.Target)
+		}
+		rules[key] = true
+	}
+	groups := make(map[string]bool, len(doc.ApprovalPolicies))
+	for i := range doc.ApprovalPolicies {
+		policy := &doc.ApprovalPolicies[i]
+		if err := validatePolicy(policy); err != nil {
+			return fmt.Errorf("分组 %q 的审批策略无效: %v", policy.Group, err)
+		}
+		if groups[policy.Group] {
+			return fmt.Errorf("分组 %q 的审批策略重复", policy.Group)
+		}
+		groups[policy.Group] = true
+	}
+	return nil
+}
+
+func readEntry(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidArchive, name)
+	}
+	return readZipFile(f)
+}
+
+// readZipFile 读取ZIP中的单个文件，超过 archiveEntryLimit 时返回 ErrArchiveTooLarge
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > archiveEntryLimit {
+		return nil, ErrArchiveTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, archiveEntryLimit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s 读取失败: %v", ErrInvalidArchive, f.Name, err)
+	}
+	if len(data) > archiveEntryLimit {
+		return nil, ErrArchiveTooLarge
+	}
+	return data, nil
+}
+
+// importSecret 将归档中的机密取值转为明文（由 StoreVNFClone 按新实例重新加密）：导出环境的密文属于源实例的参数 owner，
+// 用本环境的密钥解密，失败时置空并记录警告
+func importSecret(owner, value, label string, warnings *[]string) string {
+	if !secrets.IsEncrypted(value) {
+		return value
+	}
+	plain, err := secrets.Default().Decrypt(owner, value)
+	if err != nil {
+		*warnings = append(*warnings, fmt.Sprintf("%s无法解密（密钥 %s），已置空", label, secrets.KeyID(value)))
+		return ""
+	}
+	return plain
+}
+
+// marshalYAML 经JSON转换后输出YAML，字段名与JSON文档一致
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(tree)
+}
+
+func unmarshalYAML(data []byte, v interface{}) error {
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// packagePath 实例原始安装包的保存路径，导出时随归档输出
+func packagePath(vnfID uint) string {
+	dir := defaultString(os.Getenv("PACKAGE_DIR"), "./data/packages")
+	return filepath.Join(dir, fmt.Sprintf("vnf-%d.zip", vnfID))
+}
+
+// savePackage 保存上传的原始安装包
+func savePackage(vnfID uint, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return writePackage(vnfID, data)
+}
+
+// writePackage 去除机密参数的明文默认值后保存安装包
+func writePackage(vnfID uint, data []byte) error {
+	data, err := stripPackageSecrets(data)
+	if err != nil {
+		return err
+	}
+	path := packagePath(vnfID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// removePackage 删除实例的原始安装包，不存在时忽略
+func removePackage(vnfID uint) {
+	if err := os.Remove(packagePath(vnfID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除VNF #%d 的原始安装包失败: %v", vnfID, err)
+	}
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// stripPackageSecrets 去除安装包内YAML描述文件中机密参数（type: secret）的默认值，
+// 其余文件原样保留；没有需要去除的内容时返回原数据
+func stripPackageSecrets(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	stripped := false
+	for _, f := range zr.File {
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if ext := strings.ToLower(filepath.Ext(f.Name)); ext == ".yaml" || ext == ".yml" {
+			if out, changed, err := stripSecretDefaults(content); err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			} else if changed {
+				content, stripped = out, true
+			}
+		}
+		header := f.FileHeader
+		fw, err := zw.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if !stripped {
+		return data, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// stripSecretDefaults 删除YAML中 type 为 secret 的映射节点的 default 键
+func stripSecretDefaults(content []byte) ([]byte, bool, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, false, err
+	}
+	changed := false
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			secret, defaultAt := false, -1
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				switch node.Content[i].Value {
+				case "type":
+					secret = node.Content[i+1].Value == TypeSecret
+				case "default":
+					defaultAt = i
+				}
+			}
+			if secret && defaultAt >= 0 {
+				node.Content = append(node.Content[:defaultAt], node.Content[defaultAt+2:]...)
+				changed = true
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(&root)
+	if !changed {
+		return content, false, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"vnf-config/internal/model"
+)
+
+// TestStripPackageSecrets 安装包中机密参数的默认值被去除，其余内容与文件保留
+func TestStripPackageSecrets(t *testing.T) {
+	descriptor := `database_config:
+  properties:
+    host:
+      type: "string"
+      default: "localhost"
+    password:
+      type: "secret"
+      default: "hunter2"
+      required: true
+api_key:
+  type: secret
+  default: k-123
+`
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"config.yaml": descriptor, "README.txt": "default: hunter2"} {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := stripPackageSecrets(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		data, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	config := files["config.yaml"]
+	if strings.Contains(config, "hunter2") || strings.Contains(config, "k-123") {
+		t.Fatalf("机密参数的默认值应被去除:\n%s", config)
+	}
+	if !strings.Contains(config, "localhost") || !strings.Contains(config, "required: true") {
+		t.Fatalf("其他内容应保留:\n%s", config)
+	}
+	if files["README.txt"] != "default: hunter2" {
+		t.Fatalf("非YAML文件应原样保留，得到 %q", files["README.txt"])
+	}
+
+	// 没有机密默认值时返回原数据
+	again, err := stripPackageSecrets(out)
+	if err != nil || !bytes.Equal(again, out) {
+		t.Fatalf("已去除的安装包不应改变: %v", err)
+	}
+}
+
+// TestValidateArchivePolicies 归档中的权限规则与审批策略按接口设置时的规则校验
+func TestValidateArchivePolicies(t *testing.T) {
+	valid := func() *ArchiveDocument {
+		return &ArchiveDocument{
+			Name:        "vnf",
+			Config:      &YAMLConfig{},
+			Permissions: []model.ParameterPermission{{Scope: model.PermissionScopeGroup, Target: "compute", EditRole: "admin"}},
+			ApprovalPolicies: []model.ApprovalPolicy{
+				{Group: "compute", RequiredApprovals: 1, ApproverRole: "admin"},
+			},
+		}
+	}
+	if err := validateArchive(valid()); err != nil {
+		t.Fatalf("有效的归档被拒绝: %v", err)
+	}
+	cases := map[string]func(doc *ArchiveDocument){
+		"无效角色":   func(doc *ArchiveDocument) { doc.Permissions[0].EditRole = "root" },
+		"无效作用范围": func(doc *ArchiveDocument) { doc.Permissions[0].Scope = "vnf" },
+		"规则重复":   func(doc *ArchiveDocument) { doc.Permissions = append(doc.Permissions, doc.Permissions[0]) },
+		"批准数为负":  func(doc *ArchiveDocument) { doc.ApprovalPolicies[0].RequiredApprovals = -1 },
+		"批准数过大":  func(doc *ArchiveDocument) { doc.ApprovalPolicies[0].RequiredApprovals = maxRequiredApprovals + 1 },
+		"审批角色为空": func(doc *ArchiveDocument) { doc.ApprovalPolicies[0].ApproverRole = "" },
+		"策略重复": func(doc *ArchiveDocument) {
+			doc.ApprovalPolicies = append(doc.ApprovalPolicies, doc.ApprovalPolicies[0])
+		},
+	}
+	for name, mutate := range cases {
+		doc := valid()
+		mutate(doc)
+		if err := validateArchive(doc); err == nil {
+			t.Errorf("%s: 应拒绝导入", name)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+)
+
+// 审计导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// ErrExportFormat 不支持的导出格式
+var ErrExportFormat = errors.New("不支持的导出格式（可选 csv、json）")
+
+// exportPageSize 导出时每次读取的记录数
+const exportPageSize = 500
+
+// auditCSVHeader 审计CSV的列，oldValue/newValue 为定义变更前后的当前值（屏蔽规则同历史快照）
+var auditCSVHeader = []string{
+	"id", "createdAt", "vnfId", "entityType", "entityId", "parameterName", "action",
+	"actor", "requestId", "reason", "oldValue", "newValue", "before", "after",
+}
+
+// AuditService 变更审计：按VNF、参数、操作人、请求ID与时间查询变更历史，并导出为CSV或JSON
+type AuditService struct {
+	store       repository.Store
+	permissions *PermissionService
+}
+
+func NewAuditService(repos *repository.Repositories) *AuditService {
+	return &AuditService{store: repos.Store, permissions: NewPermissionService(repos)}
+}
+
+// List 分页查询变更历史。按VNF查询时不返回调用方无权查看的参数，
+// 屏蔽参数与机密参数的快照中不含取值
+func (s *AuditService) List(ctx context.Context, filter repository.HistoryFilter) ([]model.ChangeRecord, int64, error) {
+	if filter.VNFID != 0 {
+		hidden, err := s.hidden(ctx, filter.VNFID)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.ExcludeParameters = hidden
+	}
+	items, total, err := s.store.History().List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.redact(ctx, items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Export 按 format 将符合条件的全部记录写入 w，记录按ID倒序
+func (s *AuditService) Export(ctx context.Context, filter repository.HistoryFilter, format string, w io.Writer) error {
+	switch format {
+	case ExportFormatCSV:
+		return s.exportCSV(ctx, filter, w)
+	case ExportFormatJSON:
+		return s.exportJSON(ctx, filter, w)
+	}
+	return ErrExportFormat
+}
+
+func (s *AuditService) exportCSV(ctx context.Context, filter repository.HistoryFilter, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	err := s.each(ctx, filter, func(rec model.ChangeRecord) error {
+		oldValue, newValue := snapshotValue(rec.Before), snapshotValue(rec.After)
+		row := []string{
+			strconv.FormatUint(uint64(rec.ID), 10),
+			rec.CreatedAt.Format(time.RFC3339),
+			strconv.FormatUint(uint64(rec.VNFID), 10),
+			rec.EntityType,
+			strconv.FormatUint(uint64(rec.EntityID), 10),
+			rec.ParameterName,
+			rec.Action,
+			rec.Actor,
+			rec.RequestID,
+			rec.Reason,
+			oldValue,
+			newValue,
+			rec.Before,
+			rec.After,
+		}
+		for i := range row {
+			row[i] = csvSafe(row[i])
+		}
+		return cw.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *AuditService) exportJSON(ctx context.Context, filter repository.HistoryFilter, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := s.each(ctx, filter, func(rec model.ChangeRecord) error {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ",\n"); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// each 按ID翻页遍历符合条件的记录（已按权限过滤与屏蔽）
+func (s *AuditService) each(ctx context.Context, filter repository.HistoryFilter, fn func(model.ChangeRecord) error) error {
+	filter.Page, filter.PageSize = 1, exportPageSize
+	for {
+		items, _, err := s.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, rec := range items {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if len(items) < exportPageSize {
+			return nil
+		}
+		filter.BeforeID = items[len(items)-1].ID
+	}
+}
+
+// hidden 返回调用方在该VNF下无权查看的参数，未配置权限规则时为空
+func (s *AuditService) hidden(ctx context.Context, vnfID uint) ([]string, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil || policy.Empty() {
+		return nil, err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Hidden(auth.FromContext(ctx), defs), nil
+}
+
+// redact 按各记录所属VNF的权限规则屏蔽快照
+func (s *AuditService) redact(ctx context.Context, items []model.ChangeRecord) error {
+	caller := auth.FromContext(ctx)
+	policies := make(map[uint]*PermissionPolicy)
+	for i := range items {
+		policy, ok := policies[items[i].VNFID]
+		if !ok {
+			var err error
+			if policy, err = s.permissions.Policy(ctx, items[i].VNFID); err != nil {
+				return err
+			}
+			policies[items[i].VNFID] = policy
+		}
+		policy.RedactHistory(caller, items[i:i+1])
+	}
+	return nil
+}
+
+// snapshotValue 从定义快照中读取当前值
+func snapshotValue(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var def struct {
+		CurrentValue string `json:"currentValue"`
+	}
+	if json.Unmarshal([]byte(raw), &def) != nil {
+		return ""
+	}
+	return def.CurrentValue
+}
+
+// csvSafe 以公式字符开头的单元格加前缀单引号，避免在电子表格中被当作公式执行
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,615 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
+)
+
+// 未配置审批策略时变更单的默认要求：一名 operator 或更高角色的其他用户批准
+const (
+	DefaultRequiredApprovals = 1
+	DefaultApproverRole      = auth.RoleOperator
+)
+
+// maxRequiredApprovals 审批策略允许的最大批准数
+const maxRequiredApprovals = 10
+
+var (
+	ErrChangeSetState   = errors.New("变更单当前状态不允许该操作")
+	ErrNotAuthor        = errors.New("只有提交人可以修改变更单")
+	ErrSelfReview       = errors.New("不能审批自己提交的变更单")
+	ErrAlreadyReviewed  = errors.New("已审批过该变更单")
+	ErrReviewForbidden  = errors.New("无权审批该变更单")
+	ErrApprovalRequired = errors.New("该参数所在分组需要审批，请通过变更单修改")
+	// ErrGroupMoveForbidden 变更单只能修改取值，参数移入或移出需要审批的分组只能由管理员操作
+	ErrGroupMoveForbidden = errors.New("只有管理员可以将参数移入或移出需要审批的分组")
+)
+
+// ChangeSetConflictError 应用时参数的当前值已与提交变更单时不同，或参数已删除、不再允许修改
+type ChangeSetConflictError struct {
+	Parameters []string
+}
+
+func (e *ChangeSetConflictError) Error() string {
+	return "以下参数在变更单提交后已被修改或删除，请更新变更单后重新审批: " + strings.Join(e.Parameters, ", ")
+}
+
+// ChangeSetDiff 变更单中一项修改的对比，调用方无权查看、屏蔽参数与机密参数不输出取值
+type ChangeSetDiff struct {
+	DefinitionID  uint   `json:"definitionId"`
+	ParameterName string `json:"parameterName"`
+	Group         string `json:"group"`
+	BaseValue     string `json:"baseValue"`    // 提交时的当前值
+	CurrentValue  string `json:"currentValue"` // 现在的当前值
+	NewValue      string `json:"newValue"`
+	Masked        bool   `json:"masked"`
+	// Conflict 提交后当前值已变化或参数已删除，应用时将被拒绝（仅待审批与待应用的变更单）
+	Conflict bool `json:"conflict"`
+	Deleted  bool `json:"deleted,omitempty"`
+}
+
+// ChangeSetView 变更单及其逐项对比
+type ChangeSetView struct {
+	model.ChangeSet
+	Approvals int             `json:"approvals"`
+	Diff      []ChangeSetDiff `json:"diff"`
+}
+
+// approvalRules 某VNF的审批策略
+type approvalRules struct {
+	groups   map[string]model.ApprovalPolicy
+	fallback *model.ApprovalPolicy
+}
+
+func loadApprovalRules(ctx context.Context, store repository.Store, vnfID uint) (*approvalRules, error) {
+	policies, err := store.ApprovalPolicies().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	rules := &approvalRules{groups: make(map[string]model.ApprovalPolicy)}
+	for i := range policies {
+		if policies[i].Group == "" {
+			rules.fallback = &policies[i]
+		} else {
+			rules.groups[policies[i].Group] = policies[i]
+		}
+	}
+	return rules, nil
+}
+
+// policy 返回分组的生效策略：分组策略优先，其次为未分组策略；均未配置时 ok 为 false，返回默认要求
+func (r *approvalRules) policy(group string) (policy model.ApprovalPolicy, ok bool) {
+	if p, found := r.groups[group]; found && group != "" {
+		return p, true
+	}
+	if r.fallback != nil {
+		return *r.fallback, true
+	}
+	return model.ApprovalPolicy{RequiredApprovals: DefaultRequiredApprovals, ApproverRole: DefaultApproverRole}, false
+}
+
+// requirement 修改这些分组的参数所需的批准数与审批角色，取各分组中最严格的要求
+func (r *approvalRules) requirement(groups []string) (required int, role string) {
+	role = auth.RoleViewer
+	for _, group := range groups {
+		p, _ := r.policy(group)
+		if p.RequiredApprovals > required {
+			required = p.RequiredApprovals
+		}
+		role = auth.HigherRole(role, p.ApproverRole)
+	}
+	return required, role
+}
+
+// enforced 分组是否配置了需要审批的策略，此类参数不能直接修改当前值
+func (r *approvalRules) enforced(group string) bool {
+	p, ok := r.policy(group)
+	return ok && p.RequiredApprovals > 0
+}
+
+// ChangeSetService 变更单：暂存参数当前值的修改，经其他用户审批后整体应用
+type ChangeSetService struct {
+	store       repository.Store
+	dualStorage *DualStorageService
+	permissions *PermissionService
+}
+
+func NewChangeSetService(repos *repository.Repositories) *ChangeSetService {
+	return &ChangeSetService{store: repos.Store, dualStorage: NewDualStorageService(repos), permissions: NewPermissionService(repos)}
+}
+
+// List 分页列出VNF的变更单，可按状态过滤
+func (s *ChangeSetService) List(ctx context.Context, vnfID uint, status string, page, pageSize int) ([]ChangeSetView, int64, error) {
+	items, total, err := s.store.ChangeSets().List(ctx, repository.ChangeSetFilter{VNFID: vnfID, Status: status, Page: page, PageSize: pageSize})
+	if err != nil {
+		return nil, 0, err
+	}
+	policy, defs, err := s.context(ctx, vnfID)
+	if err != nil {
+		return nil, 0, err
+	}
+	views := make([]ChangeSetView, 0, len(items))
+	for i := range items {
+		views = append(views, s.view(ctx, policy, defs, &items[i]))
+	}
+	return views, total, nil
+}
+
+func (s *ChangeSetService) Get(ctx context.Context, vnfID, id uint) (*ChangeSetView, error) {
+	cs, err := s.store.ChangeSets().Get(ctx, vnfID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.viewOf(ctx, cs)
+}
+
+// Create 提交变更单；所需批准数为0时直接进入待应用状态
+func (s *ChangeSetService) Create(ctx context.Context, vnfID uint, req dto.ChangeSetRequest) (*ChangeSetView, error) {
+	if _, err := s.store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	cs := &model.ChangeSet{VNFID: vnfID, Author: auth.ActorFrom(ctx)}
+	if err := s.stage(ctx, cs, req); err != nil {
+		return nil, err
+	}
+	if err := s.store.ChangeSets().Create(ctx, cs); err != nil {
+		return nil, err
+	}
+	return s.viewOf(ctx, cs)
+}
+
+// Update 提交人整体替换待审批或待应用变更单的内容，已有的审批意见作废
+func (s *ChangeSetService) Update(ctx context.Context, vnfID, id uint, req dto.ChangeSetRequest) (*ChangeSetView, error) {
+	cs, err := s.store.ChangeSets().Get(ctx, vnfID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Status != model.ChangeSetStatusOpen && cs.Status != model.ChangeSetStatusApproved {
+		return nil, ErrChangeSetState
+	}
+	if cs.Author != auth.ActorFrom(ctx) {
+		return nil, ErrNotAuthor
+	}
+	status := cs.Status
+	if err := s.stage(ctx, cs, req); err != nil {
+		return nil, err
+	}
+	cs.Reviews = nil
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := s.transition(ctx, tx, cs, status); err != nil {
+			return err
+		}
+		if err := tx.ChangeSets().ReplaceItems(ctx, cs.ID, cs.Items); err != nil {
+			return err
+		}
+		return tx.ChangeSets().ClearReviews(ctx, cs.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.viewOf(ctx, cs)
+}
+
+// stage 校验请求并填充变更单的条目与审批要求；调用方须能修改每个参数，
+// 取值按批量修改的规则校验，任一参数不通过时返回 *ValidationError
+func (s *ChangeSetService) stage(ctx context.Context, cs *model.ChangeSet, req dto.ChangeSetRequest) error {
+	policy, err := s.permissions.Policy(ctx, cs.VNFID)
+	if err != nil {
+		return err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, cs.VNFID)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]*model.VNFDefinition, len(defs))
+	byName := make(map[string]*model.VNFDefinition, len(defs))
+	for i := range defs {
+		byID[defs[i].ID], byName[defs[i].ParameterName] = &defs[i], &defs[i]
+	}
+
+	caller := auth.FromContext(ctx)
+	seen := make(map[uint]bool)
+	updates := make(map[string]string, len(req.Items))
+	var items []model.ChangeSetItem
+	var groups []string
+	for _, it := range req.Items {
+		def, ref := byID[it.DefinitionID], it.ParameterName
+		if it.DefinitionID == 0 {
+			def = byName[it.ParameterName]
+		} else {
+			ref = strconv.FormatUint(uint64(it.DefinitionID), 10)
+		}
+		if def == nil {
+			return fmt.Errorf("%w: %s", repository.ErrNotFound, ref)
+		}
+		access := policy.Access(caller, def.ParameterName, def.Group)
+		if !access.CanView {
+			return fmt.Errorf("%w: %s", repository.ErrNotFound, ref)
+		}
+		if !access.CanEdit {
+			return fmt.Errorf("%w: %s", ErrPermissionDenied, def.ParameterName)
+		}
+		if !def.CanBeUpdated {
+			return fmt.Errorf("参数无法更新: %s", def.ParameterName)
+		}
+		if seen[def.ID] {
+			return fmt.Errorf("参数重复: %s", def.ParameterName)
+		}
+		seen[def.ID] = true
+		value := *it.Value
+		if (access.Masked || def.Type == TypeSecret) && value == MaskedValue {
+			return fmt.Errorf("屏蔽参数 %s 需要提供新的取值", def.ParameterName)
+		}
+		updates[def.ParameterName] = value
+		if def.Type == TypeSecret {
+			if value, err = secrets.Default().Encrypt(secretOwner(def.VNFID, def.ParameterName), value); err != nil {
+				return err
+			}
+		}
+		items = append(items, model.ChangeSetItem{
+			DefinitionID:  def.ID,
+			ParameterName: def.ParameterName,
+			Group:         def.Group,
+			BaseValue:     def.CurrentValue,
+			NewValue:      value,
+		})
+		groups = append(groups, def.Group)
+	}
+	if err := checkItems(defs, updates); err != nil {
+		return err
+	}
+
+	rules, err := loadApprovalRules(ctx, s.store, cs.VNFID)
+	if err != nil {
+		return err
+	}
+	cs.Title, cs.Description, cs.Items = req.Title, req.Description, items
+	cs.RequiredApprovals, cs.ApproverRole = rules.requirement(groups)
+	cs.Status = model.ChangeSetStatusOpen
+	if cs.RequiredApprovals == 0 {
+		cs.Status = model.ChangeSetStatusApproved
+	}
+	return nil
+}
+
+// Approve 批准变更单。审批人不能是提交人（未启用认证时除外），须具备策略要求的角色并能查看全部参数
+func (s *ChangeSetService) Approve(ctx context.Context, vnfID, id uint, comment string) (*ChangeSetView, error) {
+	cs, err := s.store.ChangeSets().Get(ctx, vnfID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Status != model.ChangeSetStatusOpen {
+		return nil, ErrChangeSetState
+	}
+	if err := s.checkReviewer(ctx, cs); err != nil {
+		return nil, err
+	}
+	review := model.ChangeSetReview{ChangeSetID: cs.ID, Reviewer: auth.ActorFrom(ctx), Decision: model.ReviewDecisionApprove, Comment: comment}
+	if countApprovals(cs.Reviews)+1 >= cs.RequiredApprovals {
+		cs.Status = model.ChangeSetStatusApproved
+	}
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.ChangeSets().AddReview(ctx, &review); err != nil {
+			return err
+		}
+		return s.transition(ctx, tx, cs, model.ChangeSetStatusOpen)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cs.Reviews = append(cs.Reviews, review)
+	return s.viewOf(ctx, cs)
+}
+
+// Reject 驳回变更单：审批人驳回，或提交人撤回
+func (s *ChangeSetService) Reject(ctx context.Context, vnfID, id uint, comment string) (*ChangeSetView, error) {
+	cs, err := s.store.ChangeSets().Get(ctx, vnfID, id)
+	if err != nil {
+		return nil, err
+	}
+	status := cs.Status
+	if status != model.ChangeSetStatusOpen && status != model.ChangeSetStatusApproved {
+		return nil, ErrChangeSetState
+	}
+	actor := auth.ActorFrom(ctx)
+	var review *model.ChangeSetReview
+	if actor != cs.Author {
+		if err := s.checkReviewer(ctx, cs); err != nil {
+			return nil, err
+		}
+		review = &model.ChangeSetReview{ChangeSetID: cs.ID, Reviewer: actor, Decision: model.ReviewDecisionReject, Comment: comment}
+	}
+	now := time.Now()
+	cs.Status, cs.ClosedBy, cs.ClosedAt = model.ChangeSetStatusRejected, actor, &now
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		if review != nil {
+			if err := tx.ChangeSets().AddReview(ctx, review); err != nil {
+				return err
+			}
+		}
+		return s.transition(ctx, tx, cs, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if review != nil {
+		cs.Reviews = append(cs.Reviews, *review)
+	}
+	return s.viewOf(ctx, cs)
+}
+
+func (s *ChangeSetService) checkReviewer(ctx context.Context, cs *model.ChangeSet) error {
+	caller, actor := auth.FromContext(ctx), auth.ActorFrom(ctx)
+	// 关闭认证时所有请求为同一调用方，无法区分审批人与提交人
+	if caller == nil || caller.Method == auth.MethodAnonymous {
+		return fmt.Errorf("%w：未启用认证时无法审批变更单", ErrReviewForbidden)
+	}
+	if actor == cs.Author {
+		return ErrSelfReview
+	}
+	if !caller.HasRole(cs.ApproverRole) {
+		return fmt.Errorf("%w：需要 %s 或更高角色", ErrReviewForbidden, cs.ApproverRole)
+	}
+	for _, r := range cs.Reviews {
+		if r.Reviewer == actor {
+			return ErrAlreadyReviewed
+		}
+	}
+	policy, err := s.permissions.Policy(ctx, cs.VNFID)
+	if err != nil {
+		return err
+	}
+	for _, item := range cs.Items {
+		if !policy.Access(caller, item.ParameterName, item.Group).CanView {
+			return fmt.Errorf("%w：无权查看参数 %s", ErrReviewForbidden, item.ParameterName)
+		}
+	}
+	return nil
+}
+
+// Apply 在同一事务中应用已批准的变更单；任一参数的当前值在提交后发生变化时整体拒绝，
+// 按应用时的整组取值校验不通过时返回 *ValidationError
+func (s *ChangeSetService) Apply(ctx context.Context, vnfID, id uint) (*ChangeSetView, error) {
+	cs, err := s.store.ChangeSets().Get(ctx, vnfID, id)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Status != model.ChangeSetStatusApproved {
+		return nil, ErrChangeSetState
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.FromContext(ctx)
+	for _, item := range cs.Items {
+		if !policy.Access(caller, item.ParameterName, item.Group).CanEdit {
+			return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, item.ParameterName)
+		}
+	}
+	if audit.Reason(ctx) == "" {
+		ctx = audit.WithReason(ctx, fmt.Sprintf("变更单 #%d：%s", cs.ID, cs.Title))
+	}
+
+	res := s.dualStorage.SaveVNFDefinitions(ctx, "apply_changeset", func(tx repository.Store) (before, after []model.VNFDefinition, err error) {
+		var conflicts []string
+		updates := make(map[string]string, len(cs.Items))
+		for _, item := range cs.Items {
+			current, err := tx.Definitions().Get(ctx, vnfID, item.DefinitionID)
+			if errors.Is(err, repository.ErrNotFound) {
+				conflicts = append(conflicts, item.ParameterName)
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			plain, err := openDefinition(*current)
+			if err != nil {
+				return nil, nil, err
+			}
+			owner := secretOwner(cs.VNFID, item.ParameterName)
+			base, err := secrets.Default().Decrypt(owner, item.BaseValue)
+			if err != nil {
+				return nil, nil, err
+			}
+			if plain.CurrentValue != base || !current.CanBeUpdated {
+				conflicts = append(conflicts, item.ParameterName)
+				continue
+			}
+			updated := plain
+			if updated.CurrentValue, err = secrets.Default().Decrypt(owner, item.NewValue); err != nil {
+				return nil, nil, err
+			}
+			updated.Modified = updated.CurrentValue != updated.DefaultValue
+			if err := resealDefinition(&updated, *current, plain); err != nil {
+				return nil, nil, err
+			}
+			updates[item.ParameterName] = updated.CurrentValue
+			before, after = append(before, *current), append(after, updated)
+		}
+		if len(conflicts) > 0 {
+			return nil, nil, &ChangeSetConflictError{Parameters: conflicts}
+		}
+		// 其他参数可能在提交后被修改（如隐藏条件引用的参数），按应用时的整组取值重新校验
+		defs, err := tx.Definitions().ListByVNF(ctx, vnfID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := checkItems(defs, updates); err != nil {
+			return nil, nil, err
+		}
+		now := time.Now()
+		cs.Status, cs.ClosedBy, cs.ClosedAt = model.ChangeSetStatusApplied, auth.ActorFrom(ctx), &now
+		return before, after, s.transition(ctx, tx, cs, model.ChangeSetStatusApproved)
+	})
+	if !res.MySQLSuccess {
+		return nil, res.MySQLError
+	}
+	return s.viewOf(ctx, cs)
+}
+
+// checkItems 按批量修改的规则校验变更单：defs 为该VNF的全部定义，updates 为参数名到新取值（明文）。
+// 逐个检查新取值，再按修改后的整组取值做跨参数必填检查，任一参数不通过时返回 *ValidationError
+func checkItems(defs []model.VNFDefinition, updates map[string]string) error {
+	byName := make(map[string]model.VNFDefinition, len(defs))
+	values := make(map[string]string, len(defs))
+	for _, def := range defs {
+		plain, err := openDefinition(def)
+		if err != nil {
+			return err
+		}
+		byName[def.ParameterName], values[def.ParameterName] = def, plain.CurrentValue
+	}
+	names := make([]string, 0, len(updates))
+	for name := range updates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	verr, changed := &ValidationError{}, make(map[string]bool, len(updates))
+	for _, name := range names {
+		if msg := validateValue(byName[name], updates[name]); msg != "" {
+			verr.add(name, msg)
+			continue
+		}
+		values[name], changed[name] = updates[name], true
+	}
+	verr.checkRequired(defs, values, changed)
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// transition 仅当变更单仍处于 from 状态时保存，避免并发审批或重复应用
+func (s *ChangeSetService) transition(ctx context.Context, tx repository.Store, cs *model.ChangeSet, from string) error {
+	saved, err := tx.ChangeSets().Update(ctx, cs, from)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrChangeSetState
+	}
+	return nil
+}
+
+func (s *ChangeSetService) viewOf(ctx context.Context, cs *model.ChangeSet) (*ChangeSetView, error) {
+	policy, defs, err := s.context(ctx, cs.VNFID)
+	if err != nil {
+		return nil, err
+	}
+	view := s.view(ctx, policy, defs, cs)
+	return &view, nil
+}
+
+func (s *ChangeSetService) context(ctx context.Context, vnfID uint) (*PermissionPolicy, map[uint]model.VNFDefinition, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint]model.VNFDefinition, len(defs))
+	for _, def := range defs {
+		byID[def.ID] = def
+	}
+	return policy, byID, nil
+}
+
+// view 生成变更单的逐项对比，按调用方权限屏蔽取值
+func (s *ChangeSetService) view(ctx context.Context, policy *PermissionPolicy, defs map[uint]model.VNFDefinition, cs *model.ChangeSet) ChangeSetView {
+	caller := auth.FromContext(ctx)
+	view := ChangeSetView{ChangeSet: *cs, Approvals: countApprovals(cs.Reviews), Diff: make([]ChangeSetDiff, 0, len(cs.Items))}
+	pending := cs.Status == model.ChangeSetStatusOpen || cs.Status == model.ChangeSetStatusApproved
+	if view.Reviews == nil {
+		view.Reviews = []model.ChangeSetReview{}
+	}
+	for _, item := range cs.Items {
+		diff := ChangeSetDiff{DefinitionID: item.DefinitionID, ParameterName: item.ParameterName, Group: item.Group}
+		def, exists := defs[item.DefinitionID]
+		owner := secretOwner(cs.VNFID, item.ParameterName)
+		base, _ := secrets.Default().Decrypt(owner, item.BaseValue)
+		newValue, _ := secrets.Default().Decrypt(owner, item.NewValue)
+		current := ""
+		if exists {
+			plain, _ := openDefinition(def)
+			current = plain.CurrentValue
+		}
+		diff.Deleted = !exists
+		diff.Conflict = pending && (!exists || current != base)
+		access := policy.Access(caller, item.ParameterName, item.Group)
+		secret := def.Type == TypeSecret || secrets.IsEncrypted(item.BaseValue) || secrets.IsEncrypted(item.NewValue)
+		diff.Masked = !access.CanView || access.Masked || secret
+		if diff.Masked {
+			base, current, newValue = maskValue(base), maskValue(current), maskValue(newValue)
+		}
+		diff.BaseValue, diff.CurrentValue, diff.NewValue = base, current, newValue
+		view.Diff = append(view.Diff, diff)
+	}
+	return view
+}
+
+func countApprovals(reviews []model.ChangeSetReview) int {
+	n := 0
+	for _, r := range reviews {
+		if r.Decision == model.ReviewDecisionApprove {
+			n++
+		}
+	}
+	return n
+}
+
+// ListPolicies 列出VNF的分组审批策略
+func (s *ChangeSetService) ListPolicies(ctx context.Context, vnfID uint) ([]model.ApprovalPolicy, error) {
+	if _, err := s.store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	return s.store.ApprovalPolicies().ListByVNF(ctx, vnfID)
+}
+
+// SetPolicy 新增或覆盖分组的审批策略，只影响之后提交或修改的变更单
+func (s *ChangeSetService) SetPolicy(ctx context.Context, vnfID uint, req dto.ApprovalPolicyRequest) (*model.ApprovalPolicy, error) {
+	if _, err := s.store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	role := req.ApproverRole
+	if role == "" {
+		role = DefaultApproverRole
+	}
+	policy := &model.ApprovalPolicy{VNFID: vnfID, Group: req.Group, RequiredApprovals: *req.RequiredApprovals, ApproverRole: role}
+	if err := validatePolicy(policy); err != nil {
+		return nil, err
+	}
+	if err := s.store.ApprovalPolicies().Upsert(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// validatePolicy 校验审批策略的批准数与审批角色，导入归档时同样适用
+func validatePolicy(policy *model.ApprovalPolicy) error {
+	if policy.RequiredApprovals < 0 || policy.RequiredApprovals > maxRequiredApprovals {
+		return fmt.Errorf("批准数应在0到%d之间", maxRequiredApprovals)
+	}
+	if !auth.ValidRole(policy.ApproverRole) {
+		return fmt.Errorf("无效的审批角色: %q", policy.ApproverRole)
+	}
+	return nil
+}
+
+func (s *ChangeSetService) DeletePolicy(ctx context.Context, vnfID, id uint) error {
+	return s.store.ApprovalPolicies().Delete(ctx, vnfID, id)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+)
+
+// 比较结果中参数的状态
+const (
+	CompareOnlyInLeft  = "only_in_left"
+	CompareOnlyInRight = "only_in_right"
+	CompareDiffering   = "differing"
+)
+
+// compareCSVHeader 比较结果CSV的列，differences 为以 ; 分隔的不同字段
+var compareCSVHeader = []string{
+	"parameterName", "status", "differences",
+	"leftGroup", "rightGroup", "leftType", "rightType",
+	"leftValue", "rightValue", "leftModified", "rightModified",
+	"leftConstraints", "rightConstraints",
+}
+
+// CompareFilter 比较条件。Group 与 ModifiedOnly 任一侧满足即保留该参数
+type CompareFilter struct {
+	Left         uint
+	Right        uint
+	Group        string
+	ModifiedOnly bool
+}
+
+// CompareSide 参与比较的实例
+type CompareSide struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// CompareValue 参数在一侧实例中的定义，屏蔽参数的取值以 MaskedValue 输出
+type CompareValue struct {
+	Group        string `json:"group"`
+	Type         string `json:"type"`
+	CurrentValue string `json:"currentValue"`
+	Modified     bool   `json:"modified"`
+	Constraints  string `json:"constraints"`
+	Masked       bool   `json:"masked,omitempty"`
+}
+
+// CompareItem 一个参数的比较结果，Differences 列出取值不同的字段（currentValue、type、constraints）
+type CompareItem struct {
+	ParameterName string        `json:"parameterName"`
+	Status        string        `json:"status"`
+	Differences   []string      `json:"differences,omitempty"`
+	Left          *CompareValue `json:"left,omitempty"`
+	Right         *CompareValue `json:"right,omitempty"`
+}
+
+// CompareResult 两个实例按参数名逐项比较的结果，Identical 为三项均相同的参数数量
+type CompareResult struct {
+	Left        CompareSide   `json:"left"`
+	Right       CompareSide   `json:"right"`
+	OnlyInLeft  []CompareItem `json:"onlyInLeft"`
+	OnlyInRight []CompareItem `json:"onlyInRight"`
+	Differing   []CompareItem `json:"differing"`
+	Identical   int           `json:"identical"`
+}
+
+// CompareService 按参数名比较两个VNF实例的参数定义
+type CompareService struct {
+	store       repository.Store
+	permissions *PermissionService
+}
+
+func NewCompareService(repos *repository.Repositories) *CompareService {
+	return &CompareService{store: repos.Store, permissions: NewPermissionService(repos)}
+}
+
+// compareSide 一侧实例中调用方可见的参数（明文）
+type compareSide struct {
+	instance *model.VNFInstance
+	defs     map[string]model.VNFDefinition
+	masked   map[string]bool
+}
+
+func (s *CompareService) side(ctx context.Context, vnfID uint) (*compareSide, error) {
+	instance, err := s.store.Instances().Get(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.FromContext(ctx)
+	side := &compareSide{instance: instance, defs: make(map[string]model.VNFDefinition, len(defs)), masked: make(map[string]bool)}
+	for _, def := range defs {
+		access := policy.Access(caller, def.ParameterName, def.Group)
+		if !access.CanView {
+			continue
+		}
+		plain, err := openDefinition(def)
+		if err != nil {
+			return nil, err
+		}
+		side.defs[def.ParameterName] = plain
+		side.masked[def.ParameterName] = access.Masked || def.Type == TypeSecret
+	}
+	return side, nil
+}
+
+func (side *compareSide) value(name string) *CompareValue {
+	def, ok := side.defs[name]
+	if !ok {
+		return nil
+	}
+	v := &CompareValue{
+		Group:        def.Group,
+		Type:         def.Type,
+		CurrentValue: def.CurrentValue,
+		Modified:     def.Modified,
+		Constraints:  def.Constraints,
+		Masked:       side.masked[name],
+	}
+	if v.Masked {
+		v.CurrentValue = maskValue(v.CurrentValue)
+	}
+	return v
+}
+
+// Compare 按参数名比较两个实例：只在一侧存在的参数，以及当前值、类型或校验规则不同的参数。
+// 调用方无权查看的参数按不存在处理，屏蔽参数按明文比较但不输出取值
+func (s *CompareService) Compare(ctx context.Context, filter CompareFilter) (*CompareResult, error) {
+	left, err := s.side(ctx, filter.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.side(ctx, filter.Right)
+	if err != nil {
+		return nil, err
+	}
+	result := &CompareResult{
+		Left:        CompareSide{ID: left.instance.ID, Name: left.instance.Name},
+		Right:       CompareSide{ID: right.instance.ID, Name: right.instance.Name},
+		OnlyInLeft:  []CompareItem{},
+		OnlyInRight: []CompareItem{},
+		Differing:   []CompareItem{},
+	}
+	names := make(map[string]bool, len(left.defs)+len(right.defs))
+	for name := range left.defs {
+		names[name] = true
+	}
+	for name := range right.defs {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		l, lok := left.defs[name]
+		r, rok := right.defs[name]
+		if filter.Group != "" && !(lok && l.Group == filter.Group) && !(rok && r.Group == filter.Group) {
+			continue
+		}
+		if filter.ModifiedOnly && !(lok && l.Modified) && !(rok && r.Modified) {
+			continue
+		}
+		item := CompareItem{ParameterName: name, Left: left.value(name), Right: right.value(name)}
+		switch {
+		case !rok:
+			item.Status = CompareOnlyInLeft
+			result.OnlyInLeft = append(result.OnlyInLeft, item)
+			continue
+		case !lok:
+			item.Status = CompareOnlyInRight
+			result.OnlyInRight = append(result.OnlyInRight, item)
+			continue
+		}
+		if l.CurrentValue != r.CurrentValue {
+			item.Differences = append(item.Differences, "currentValue")
+		}
+		if l.Type != r.Type {
+			item.Differences = append(item.Differences, "type")
+		}
+		if !sameConstraints(l.Constraints, r.Constraints) {
+			item.Differences = append(item.Differences, "constraints")
+		}
+		if len(item.Differences) == 0 {
+			result.Identical++
+			continue
+		}
+		item.Status = CompareDiffering
+		result.Differing = append(result.Differing, item)
+	}
+	return result, nil
+}
+
+// sameConstraints 校验规则按解析后的内容比较，键顺序与格式不同视为相同
+func sameConstraints(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := parseConstraints(a), parseConstraints(b)
+	if ra == nil || rb == nil {
+		return strings.TrimSpace(a) == strings.TrimSpace(b)
+	}
+	ja, erra := json.Marshal(ra)
+	jb, errb := json.Marshal(rb)
+	return erra == nil && errb == nil && string(ja) == string(jb)
+}
+
+// WriteCompareResult 按 format（csv、json）写出比较结果，CSV 每行一个参数
+func WriteCompareResult(result *CompareResult, format string, w io.Writer) error {
+	switch format {
+	case ExportFormatCSV:
+	case ExportFormatJSON:
+		return json.NewEncoder(w).Encode(result)
+	default:
+		return ErrExportFormat
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(compareCSVHeader); err != nil {
+		return err
+	}
+	items := append(append(append([]CompareItem{}, result.Differing...), result.OnlyInLeft...), result.OnlyInRight...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ParameterName < items[j].ParameterName })
+	for _, item := range items {
+		l, r := item.Left, item.Right
+		if l == nil {
+			l = &CompareValue{}
+		}
+		if r == nil {
+			r = &CompareValue{}
+		}
+		row := []string{
+			item.ParameterName, item.Status, strings.Join(item.Differences, ";"),
+			l.Group, r.Group, l.Type, r.Type,
+			l.CurrentValue, r.CurrentValue, boolCell(item.Left, l.Modified), boolCell(item.Right, r.Modified),
+			l.Constraints, r.Constraints,
+		}
+		for i := range row {
+			row[i] = csvSafe(row[i])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// boolCell 参数不在该侧时输出空单元格
+func boolCell(v *CompareValue, b bool) string {
+	if v == nil {
+		return ""
+	}
+	if b {
+		return "true"
+	}
+	return "false"
+}
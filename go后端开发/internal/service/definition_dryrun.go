@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/model"
+	"vnf-shared/auth"
+	"vnf-shared/textdiff"
+)
+
+// DefinitionDryRun 定义修改的试运行结果，不写入数据库、MongoDB 与 GitOps 仓库。
+// Document 为修改后按 GitOps 渲染格式（vnfs/<id>/values.yaml）输出的基础取值，只含调用方可查看的参数，
+// 机密与屏蔽参数的取值已屏蔽；Diff 为相对当前内容的统一diff；Items 为修改后的定义，Changed 列出有变化的参数。
+// Valid 为 false 时实际提交会被拒绝，Errors 按参数列出原因
+type DefinitionDryRun struct {
+	DryRun   bool             `json:"dryRun"`
+	Items    []DefinitionView `json:"items"`
+	Changed  []string         `json:"changed"`
+	Document string           `json:"document"`
+	Diff     string           `json:"diff"`
+	Valid    bool             `json:"valid"`
+	Errors   []FieldError     `json:"errors"`
+}
+
+// DryRunUpdate 按 Update 的规则试运行单个定义的修改，校验同 Update
+func (s *DefinitionService) DryRunUpdate(ctx context.Context, vnfID, defID uint, req dto.DefinitionUpdateRequest) (*DefinitionDryRun, error) {
+	plan, err := s.planUpdate(ctx, vnfID, defID, req)
+	if err != nil {
+		return nil, err
+	}
+	defs, verr, err := s.validateUpdate(ctx, vnfID, plan)
+	if err != nil {
+		return nil, err
+	}
+	return s.dryRun(ctx, vnfID, plan.policy, defs, []model.VNFDefinition{plan.item}, verr)
+}
+
+// validateUpdate 校验单个定义的修改：取值、类型或校验规则有变化时按修改后的类型与校验规则检查当前值，
+// 定义有变化时按修改后的整组取值做跨参数的必填检查。返回修改前的整组定义与校验结果
+func (s *DefinitionService) validateUpdate(ctx context.Context, vnfID uint, plan *definitionPlan) ([]model.VNFDefinition, *ValidationError, error) {
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := plan.item.ParameterName
+	after := make([]model.VNFDefinition, len(defs))
+	values := make(map[string]string, len(defs))
+	for i, def := range defs {
+		if def.ID == plan.item.ID {
+			after[i], values[name] = plan.plain, plan.plain.CurrentValue
+			continue
+		}
+		plain, err := openDefinition(def)
+		if err != nil {
+			return nil, nil, err
+		}
+		after[i], values[def.ParameterName] = def, plain.CurrentValue
+	}
+
+	verr := &ValidationError{}
+	changed := map[string]bool{}
+	if definitionChanged(plan.current, plan.item) {
+		changed[name] = true
+		current := plan.current
+		if current.Type == TypeSecret {
+			if current, err = openDefinition(current); err != nil {
+				return nil, nil, err
+			}
+		}
+		if current.CurrentValue != plan.plain.CurrentValue || current.Type != plan.plain.Type || current.Constraints != plan.plain.Constraints {
+			if msg := validateValue(plan.plain, plan.plain.CurrentValue); msg != "" {
+				verr.add(name, msg)
+			}
+		}
+	}
+	verr.checkRequired(after, values, changed)
+	return defs, verr, nil
+}
+
+// DryRunBulkUpdate 按 BulkUpdate 的规则试运行批量修改。存在校验错误时实际提交不做任何修改，
+// 这里仍输出通过校验的参数修改后的内容，便于逐项修正
+func (s *DefinitionService) DryRunBulkUpdate(ctx context.Context, vnfID uint, req dto.DefinitionBulkUpdateRequest) (*DefinitionDryRun, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadApprovalRules(ctx, s.store, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planBulkUpdate(ctx, s.store, vnfID, policy, rules, req.Values)
+	if err != nil {
+		return nil, err
+	}
+	return s.dryRun(ctx, vnfID, policy, plan.defs, plan.after, plan.invalid)
+}
+
+// dryRun 将修改后的定义 items 合入整组定义 defs，渲染修改前后的文档并生成diff
+func (s *DefinitionService) dryRun(ctx context.Context, vnfID uint, policy *PermissionPolicy, defs, items []model.VNFDefinition, verr *ValidationError) (*DefinitionDryRun, error) {
+	instance, err := s.store.Instances().Get(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.FromContext(ctx)
+	byID := make(map[uint]model.VNFDefinition, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	after := make([]model.VNFDefinition, len(defs))
+	result := &DefinitionDryRun{DryRun: true, Items: []DefinitionView{}, Changed: []string{}, Errors: verr.Fields}
+	for i, def := range defs {
+		after[i] = def
+		if item, ok := byID[def.ID]; ok {
+			after[i] = item
+			result.Items = append(result.Items, policy.View(caller, item))
+			if definitionChanged(def, item) {
+				result.Changed = append(result.Changed, def.ParameterName)
+			}
+		}
+	}
+	sort.Strings(result.Changed)
+	if result.Errors == nil {
+		result.Errors = []FieldError{}
+	}
+	result.Valid = len(result.Errors) == 0
+
+	vnf := RenderedVNF{ID: instance.ID, Name: instance.Name}
+	before, err := yaml.Marshal(RenderedConfig{VNF: vnf, Parameters: visibleValues(policy, caller, defs)})
+	if err != nil {
+		return nil, err
+	}
+	document, err := yaml.Marshal(RenderedConfig{VNF: vnf, Parameters: visibleValues(policy, caller, after)})
+	if err != nil {
+		return nil, err
+	}
+	file := fmt.Sprintf("%s/%d/values.yaml", gitopsRoot, vnfID)
+	result.Document = string(document)
+	result.Diff = textdiff.Unified("a/"+file, "b/"+file, before, document)
+	return result, nil
+}
+
+// visibleValues 调用方可查看的参数按类型输出的当前值，机密与屏蔽参数的取值已屏蔽
+func visibleValues(policy *PermissionPolicy, caller *auth.Identity, defs []model.VNFDefinition) map[string]interface{} {
+	values := make(map[string]string, len(defs))
+	types := make(map[string]string, len(defs))
+	for _, def := range defs {
+		view := policy.View(caller, def)
+		if !view.Access.CanView {
+			continue
+		}
+		values[def.ParameterName], types[def.ParameterName] = view.CurrentValue, def.Type
+	}
+	return typedValues(values, types)
+}
+
+// definitionChanged 修改前后的定义是否有差异（比较可修改的属性，机密取值比较密文）
+func definitionChanged(a, b model.VNFDefinition) bool {
+	optional := func(v *bool) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(*v)
+	}
+	return a.DefaultValue != b.DefaultValue || a.DescriptionText != b.DescriptionText || a.Type != b.Type ||
+		a.Group != b.Group || a.CanBeUpdated != b.CanBeUpdated || a.HiddenCondition != b.HiddenCondition ||
+		optional(a.Optional) != optional(b.Optional) || a.Constraints != b.Constraints || a.CurrentValue != b.CurrentValue
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
+)
+
+// ValueChange 参数当前值的一次变更，取自变更历史
+type ValueChange struct {
+	RecordID  uint      `json:"recordId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"requestId,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	OldValue  string    `json:"oldValue"`
+	NewValue  string    `json:"newValue"`
+}
+
+// ResetResult 批量重置的结果，skipped 列出无法重置的已修改参数及原因
+type ResetResult struct {
+	Items   []DefinitionView `json:"items"`
+	Skipped []FieldError     `json:"skipped"`
+}
+
+// Reset 将参数的当前值重置为默认值，权限、CanBeUpdated 与审批策略的检查同直接修改
+func (s *DefinitionService) Reset(ctx context.Context, vnfID, defID uint, reason string) (*DefinitionView, error) {
+	if reason == "" && audit.Reason(ctx) == "" {
+		reason = "重置为默认值"
+	}
+	return s.setCurrentValue(ctx, vnfID, defID, reason, func(plain model.VNFDefinition) (string, error) {
+		return plain.DefaultValue, nil
+	})
+}
+
+// ResetAll 在同一事务中将一个分组（group 为 nil 时为整个VNF）内已修改的参数重置为默认值。
+// 调用方无权查看的参数不处理；无权修改、不可更新或须经变更单审批的参数跳过并在结果中列出
+func (s *DefinitionService) ResetAll(ctx context.Context, vnfID uint, req dto.DefinitionResetRequest) (*ResetResult, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadApprovalRules(ctx, s.store, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.FromContext(ctx)
+	if req.Reason == "" && audit.Reason(ctx) == "" {
+		req.Reason = "重置为默认值"
+	}
+	ctx = audit.WithReason(ctx, req.Reason)
+
+	result := &ResetResult{Items: []DefinitionView{}, Skipped: []FieldError{}}
+	res := s.dualStorage.SaveVNFDefinitions(ctx, "reset_definitions", func(tx repository.Store) (before, after []model.VNFDefinition, err error) {
+		result.Skipped = result.Skipped[:0]
+		defs, err := tx.Definitions().ListByVNF(ctx, vnfID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, def := range defs {
+			if req.Group != nil && def.Group != *req.Group {
+				continue
+			}
+			access := policy.Access(caller, def.ParameterName, def.Group)
+			if !access.CanView {
+				continue
+			}
+			plain, err := openDefinition(def)
+			if err != nil {
+				return nil, nil, err
+			}
+			if plain.CurrentValue == plain.DefaultValue && !def.Modified {
+				continue
+			}
+			skip := ""
+			switch {
+			case !access.CanEdit:
+				skip = ErrPermissionDenied.Error()
+			case !def.CanBeUpdated:
+				skip = "参数无法更新"
+			case rules.enforced(def.Group):
+				skip = ErrApprovalRequired.Error()
+			}
+			if skip != "" {
+				result.Skipped = append(result.Skipped, FieldError{ParameterName: def.ParameterName, Message: skip})
+				continue
+			}
+			item := plain
+			item.CurrentValue, item.Modified = plain.DefaultValue, false
+			if err := resealDefinition(&item, def, plain); err != nil {
+				return nil, nil, err
+			}
+			before, after = append(before, def), append(after, item)
+		}
+		return before, after, nil
+	})
+	if !res.MySQLSuccess {
+		return nil, res.MySQLError
+	}
+	items, _ := res.Data.([]model.VNFDefinition)
+	for _, item := range items {
+		result.Items = append(result.Items, policy.View(caller, item))
+	}
+	return result, nil
+}
+
+// ValueHistory 按时间倒序列出参数当前值的历次变更（创建与修改），屏蔽参数的取值不输出
+func (s *DefinitionService) ValueHistory(ctx context.Context, vnfID, defID uint) ([]ValueChange, error) {
+	current, err := s.store.Definitions().Get(ctx, vnfID, defID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	view := policy.View(auth.FromContext(ctx), *current)
+	if !view.Access.CanView {
+		return nil, repository.ErrNotFound
+	}
+	changes := []ValueChange{}
+	filter := repository.HistoryFilter{VNFID: vnfID, EntityType: "definition", EntityID: defID, Page: 1, PageSize: exportPageSize}
+	for {
+		records, _, err := s.store.History().List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range records {
+			if rec.Action != model.ChangeActionCreate && rec.Action != model.ChangeActionUpdate {
+				continue
+			}
+			oldValue, newValue := snapshotValue(rec.Before), snapshotValue(rec.After)
+			if rec.Action == model.ChangeActionUpdate && oldValue == newValue {
+				continue
+			}
+			if view.Access.Masked {
+				oldValue, newValue = maskValue(oldValue), maskValue(newValue)
+			}
+			changes = append(changes, ValueChange{
+				RecordID:  rec.ID,
+				CreatedAt: rec.CreatedAt,
+				Action:    rec.Action,
+				Actor:     rec.Actor,
+				RequestID: rec.RequestID,
+				Reason:    rec.Reason,
+				OldValue:  oldValue,
+				NewValue:  newValue,
+			})
+		}
+		if len(records) < exportPageSize {
+			return changes, nil
+		}
+		filter.BeforeID = records[len(records)-1].ID
+	}
+}
+
+// Restore 将参数的当前值恢复为取值历史中某条记录的新值，检查同直接修改
+func (s *DefinitionService) Restore(ctx context.Context, vnfID, defID uint, req dto.DefinitionRestoreRequest) (*DefinitionView, error) {
+	records, _, err := s.store.History().List(ctx, repository.HistoryFilter{
+		VNFID: vnfID, EntityType: "definition", EntityID: defID, BeforeID: req.RecordID + 1, Page: 1, PageSize: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || records[0].ID != req.RecordID || records[0].After == "" ||
+		(records[0].Action != model.ChangeActionCreate && records[0].Action != model.ChangeActionUpdate) {
+		return nil, fmt.Errorf("%w: 变更记录 #%d", repository.ErrNotFound, req.RecordID)
+	}
+	if req.Reason == "" && audit.Reason(ctx) == "" {
+		req.Reason = fmt.Sprintf("恢复为变更记录 #%d 的取值", req.RecordID)
+	}
+	return s.setCurrentValue(ctx, vnfID, defID, req.Reason, func(plain model.VNFDefinition) (string, error) {
+		return secrets.Default().Decrypt(secretOwner(plain.VNFID, plain.ParameterName), snapshotValue(records[0].After))
+	})
+}
+
+// setCurrentValue 将当前值改为 value 返回的明文；取值未变化时只检查权限，不产生变更记录
+func (s *DefinitionService) setCurrentValue(ctx context.Context, vnfID, defID uint, reason string, value func(plain model.VNFDefinition) (string, error)) (*DefinitionView, error) {
+	current, err := s.store.Definitions().Get(ctx, vnfID, defID)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := openDefinition(*current)
+	if err != nil {
+		return nil, err
+	}
+	v, err := value(plain)
+	if err != nil {
+		return nil, err
+	}
+	if v != plain.CurrentValue {
+		return s.Update(ctx, vnfID, defID, dto.DefinitionUpdateRequest{CurrentValue: &v, Reason: reason})
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, policy, current.ParameterName, current.Group); err != nil {
+		return nil, err
+	}
+	view := policy.View(auth.FromContext(ctx), *current)
+	return &view, nil
+}
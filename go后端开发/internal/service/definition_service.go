@@ -3,37 +3,71 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
+	"strconv"
 
 	"vnf-config/internal/dto"
-	"vnf-config/internal/infra/db"
+	"vnf-config/internal/infra/audit"
 	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
 )
 
-type DefinitionService struct{}
+type DefinitionService struct {
+	store       repository.Store
+	dualStorage *DualStorageService
+	permissions *PermissionService
+}
 
-func NewDefinitionService() *DefinitionService { return &DefinitionService{} }
+func NewDefinitionService(repos *repository.Repositories) *DefinitionService {
+	return &DefinitionService{store: repos.Store, dualStorage: NewDualStorageService(repos), permissions: NewPermissionService(repos)}
+}
 
-func (s *DefinitionService) List(ctx context.Context, vnfID uint, page, pageSize int, modifiedOnly bool) ([]model.VNFDefinition, int64, error) {
-	if page <= 0 { page = 1 }
-	if pageSize <= 0 || pageSize > 200 { pageSize = 10 }
-	q := db.MySQLDB.Model(&model.VNFDefinition{}).Where("vnf_id = ?", vnfID)
-	if modifiedOnly {
-		q = q.Where("modified = ?", true)
+// List 分页列出调用方有权查看的定义，屏蔽参数的取值不输出
+func (s *DefinitionService) List(ctx context.Context, vnfID uint, page, pageSize int, modifiedOnly bool) ([]DefinitionView, int64, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil { return nil, 0, err }
+	caller := auth.FromContext(ctx)
+	filter := repository.DefinitionFilter{VNFID: vnfID, ModifiedOnly: modifiedOnly, Page: page, PageSize: pageSize}
+	if !policy.Empty() {
+		all, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+		if err != nil { return nil, 0, err }
+		filter.ExcludeNames = policy.Hidden(caller, all)
+	}
+	items, total, err := s.store.Definitions().List(ctx, filter)
+	if err != nil { return nil, 0, err }
+	views := make([]DefinitionView, 0, len(items))
+	for _, item := range items {
+		views = append(views, policy.View(caller, item))
 	}
-	var total int64
-	if err := q.Count(&total).Error; err != nil { return nil, 0, err }
-	var items []model.VNFDefinition
-	if err := q.Order("id asc").Limit(pageSize).Offset((page-1)*pageSize).Find(&items).Error; err != nil { return nil, 0, err }
-	return items, total, nil
+	return views, total, nil
+}
+
+// authorize 检查调用方能否修改该参数；无权查看的参数按不存在处理
+func (s *DefinitionService) authorize(ctx context.Context, policy *PermissionPolicy, name, group string) error {
+	access := policy.Access(auth.FromContext(ctx), name, group)
+	if !access.CanView { return repository.ErrNotFound }
+	if !access.CanEdit { return ErrPermissionDenied }
+	return nil
 }
 
-func (s *DefinitionService) Create(ctx context.Context, vnfID uint, req dto.DefinitionCreateRequest) (*model.VNFDefinition, error) {
+func (s *DefinitionService) Create(ctx context.Context, vnfID uint, req dto.DefinitionCreateRequest) (*DefinitionView, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil { return nil, err }
+	if err := s.authorize(ctx, policy, req.ParameterName, req.Group); err != nil { return nil, err }
+	if req.CurrentValue != nil && *req.CurrentValue != req.DefaultValue {
+		// 与修改相同，需要审批的分组中的参数只能以默认值创建，再通过变更单修改当前值
+		rules, err := loadApprovalRules(ctx, s.store, vnfID)
+		if err != nil { return nil, err }
+		if rules.enforced(req.Group) { return nil, ErrApprovalRequired }
+	}
 	item := &model.VNFDefinition{
 		VNFID:           vnfID,
 		ParameterName:   req.ParameterName,
 		DefaultValue:    req.DefaultValue,
 		DescriptionText: req.DescriptionText,
 		Type:            req.Type,
+		Group:           req.Group,
 		CanBeUpdated:    req.CanBeUpdated,
 		HiddenCondition: req.HiddenCondition,
 		Optional:        req.Optional,
@@ -43,31 +77,208 @@ func (s *DefinitionService) Create(ctx context.Context, vnfID uint, req dto.Defi
 		item.CurrentValue = *req.CurrentValue
 		item.Modified = item.CurrentValue != item.DefaultValue
 	}
-	if err := db.MySQLDB.Create(item).Error; err != nil { return nil, err }
-	return item, nil
+	if err := sealDefinition(item); err != nil { return nil, err }
+	ctx = audit.WithReason(ctx, req.Reason)
+	if res := s.dualStorage.CreateVNFDefinition(ctx, item); !res.MySQLSuccess { return nil, res.MySQLError }
+	view := policy.View(auth.FromContext(ctx), *item)
+	return &view, nil
+}
+
+// Update 修改定义。权限与 CanBeUpdated 均按修改前的定义检查，
+// 同一请求中打开 canBeUpdated 并修改取值不会绕过限制；校验同试运行，不通过时返回 *ValidationError
+func (s *DefinitionService) Update(ctx context.Context, vnfID, defID uint, req dto.DefinitionUpdateRequest) (*DefinitionView, error) {
+	plan, err := s.planUpdate(ctx, vnfID, defID, req)
+	if err != nil { return nil, err }
+	_, verr, err := s.validateUpdate(ctx, vnfID, plan)
+	if err != nil { return nil, err }
+	if len(verr.Fields) > 0 { return nil, verr }
+	before, item := plan.current, plan.item
+	ctx = audit.WithReason(ctx, req.Reason)
+	if res := s.dualStorage.SaveVNFDefinition(ctx, &before, &item); !res.MySQLSuccess { return nil, res.MySQLError }
+	view := plan.policy.View(auth.FromContext(ctx), item)
+	return &view, nil
+}
+
+// definitionPlan 单个定义修改的计算结果：current 为修改前的定义，plain 为修改后的明文，item 为重新加密后待保存的定义
+type definitionPlan struct {
+	policy  *PermissionPolicy
+	current model.VNFDefinition
+	plain   model.VNFDefinition
+	item    model.VNFDefinition
 }
 
-func (s *DefinitionService) Update(ctx context.Context, vnfID, defID uint, req dto.DefinitionUpdateRequest) (*model.VNFDefinition, error) {
-	var item model.VNFDefinition
-	if err := db.MySQLDB.Where("id = ? AND vnf_id = ?", defID, vnfID).First(&item).Error; err != nil { return nil, err }
+// planUpdate 按 Update 的规则检查并计算修改后的定义，不写入
+func (s *DefinitionService) planUpdate(ctx context.Context, vnfID, defID uint, req dto.DefinitionUpdateRequest) (*definitionPlan, error) {
+	current, err := s.store.Definitions().Get(ctx, vnfID, defID)
+	if err != nil { return nil, err }
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil { return nil, err }
+	if err := s.authorize(ctx, policy, current.ParameterName, current.Group); err != nil { return nil, err }
+	rules, err := loadApprovalRules(ctx, s.store, vnfID)
+	if err != nil { return nil, err }
+	group := current.Group
+	if req.Group != nil && *req.Group != current.Group {
+		// 移入其他分组同样需要具备目标分组的修改权限
+		if err := s.authorize(ctx, policy, current.ParameterName, *req.Group); err != nil { return nil, err }
+		// 移出需要审批的分组后可直接修改取值，移入则会绕过变更单已审批的取值，源分组与目标分组均需检查
+		if (rules.enforced(current.Group) || rules.enforced(*req.Group)) && !auth.FromContext(ctx).HasRole(auth.RoleAdmin) {
+			return nil, ErrGroupMoveForbidden
+		}
+		// 移入规则更宽松的分组（如不再屏蔽取值）会对其他用户放开该参数，只有管理员可以操作
+		if policy.Loosens(current.ParameterName, current.Group, *req.Group) && !auth.FromContext(ctx).HasRole(auth.RoleAdmin) {
+			return nil, ErrPermissionDenied
+		}
+		group = *req.Group
+	}
+	if req.CurrentValue != nil && !current.CanBeUpdated {
+		return nil, errors.New("参数无法更新")
+	}
+	if req.Type != nil && (*req.Type == TypeSecret) != (current.Type == TypeSecret) && !auth.FromContext(ctx).HasRole(auth.RoleAdmin) {
+		// 改为普通类型会以明文输出取值
+		return nil, errors.New("只有管理员可以更改机密参数的类型")
+	}
+
+	// 在明文上修改并计算 Modified，保存前重新加密机密参数
+	plain := *current
+	if current.Type == TypeSecret {
+		if plain, err = openDefinition(*current); err != nil { return nil, err }
+	}
+	view := policy.View(auth.FromContext(ctx), *current)
+	if view.Access.Masked {
+		// 客户端原样回传的屏蔽值视为未修改
+		if req.DefaultValue != nil && *req.DefaultValue == MaskedValue { req.DefaultValue = nil }
+		if req.CurrentValue != nil && *req.CurrentValue == MaskedValue { req.CurrentValue = nil }
+	}
+	if req.CurrentValue != nil && *req.CurrentValue != plain.CurrentValue {
+		// 配置了审批策略的分组只能通过变更单修改当前值，同时移动分组时按移动前后的分组检查
+		if rules.enforced(current.Group) || rules.enforced(group) { return nil, ErrApprovalRequired }
+	}
+
+	item := plain
 	if req.DefaultValue != nil { item.DefaultValue = *req.DefaultValue }
 	if req.DescriptionText != nil { item.DescriptionText = *req.DescriptionText }
 	if req.Type != nil { item.Type = *req.Type }
+	if req.Group != nil { item.Group = *req.Group }
 	if req.CanBeUpdated != nil { item.CanBeUpdated = *req.CanBeUpdated }
 	if req.HiddenCondition != nil { item.HiddenCondition = *req.HiddenCondition }
 	if req.Optional != nil { item.Optional = req.Optional }
 	if req.Constraints != nil { item.Constraints = *req.Constraints }
 	if req.CurrentValue != nil { item.CurrentValue = *req.CurrentValue }
 	item.Modified = item.CurrentValue != item.DefaultValue
-	if !item.CanBeUpdated && req.CurrentValue != nil {
-		return nil, errors.New("参数无法更新")
-	}
-	if err := db.MySQLDB.Save(&item).Error; err != nil { return nil, err }
-	return &item, nil
+	plan := &definitionPlan{policy: policy, current: *current, plain: item, item: item}
+	if err := resealDefinition(&plan.item, *current, plain); err != nil { return nil, err }
+	return plan, nil
 }
 
 func (s *DefinitionService) Delete(ctx context.Context, vnfID, defID uint) error {
-	return db.MySQLDB.Where("vnf_id = ? AND id = ?", vnfID, defID).Delete(&model.VNFDefinition{}).Error
+	current, err := s.store.Definitions().Get(ctx, vnfID, defID)
+	if err != nil { return err }
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil { return err }
+	if err := s.authorize(ctx, policy, current.ParameterName, current.Group); err != nil { return err }
+	if res := s.dualStorage.DeleteVNFDefinition(ctx, vnfID, defID); !res.MySQLSuccess { return res.MySQLError }
+	return nil
+}
+
+// BulkUpdate 在同一事务中批量修改当前值并同步到MongoDB。整组取值一起校验（类型、校验规则，
+// 以及隐藏条件带来的跨参数必填约束），任一参数不通过时不做任何修改，返回按参数列出的 *ValidationError
+func (s *DefinitionService) BulkUpdate(ctx context.Context, vnfID uint, req dto.DefinitionBulkUpdateRequest) ([]DefinitionView, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil { return nil, err }
+	rules, err := loadApprovalRules(ctx, s.store, vnfID)
+	if err != nil { return nil, err }
+	caller := auth.FromContext(ctx)
+	ctx = audit.WithReason(ctx, req.Reason)
+
+	res := s.dualStorage.SaveVNFDefinitions(ctx, "bulk_update_definitions", func(tx repository.Store) (before, after []model.VNFDefinition, err error) {
+		plan, err := planBulkUpdate(ctx, tx, vnfID, policy, rules, req.Values)
+		if err != nil { return nil, nil, err }
+		if len(plan.invalid.Fields) > 0 { return nil, nil, plan.invalid }
+		return plan.before, plan.after, nil
+	})
+	if !res.MySQLSuccess { return nil, res.MySQLError }
+	items, _ := res.Data.([]model.VNFDefinition)
+	views := make([]DefinitionView, 0, len(items))
+	for _, item := range items {
+		views = append(views, policy.View(caller, item))
+	}
+	return views, nil
+}
+
+// bulkPlan 批量修改的计算结果：defs 为修改前的整组定义，before、after 为通过校验的修改（after 已重新加密），
+// invalid 为未通过校验的参数
+type bulkPlan struct {
+	defs    []model.VNFDefinition
+	before  []model.VNFDefinition
+	after   []model.VNFDefinition
+	invalid *ValidationError
+}
+
+// planBulkUpdate 按 BulkUpdate 的规则在 store 上读取整组定义、校验并计算修改，不写入
+func planBulkUpdate(ctx context.Context, store repository.Store, vnfID uint, policy *PermissionPolicy, rules *approvalRules, input map[string]interface{}) (*bulkPlan, error) {
+	caller := auth.FromContext(ctx)
+	names := make([]string, 0, len(input))
+	for name := range input { names = append(names, name) }
+	sort.Strings(names)
+	defs, err := store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil { return nil, err }
+	// 校验在明文上进行，values 为修改后的整组取值
+	byName := make(map[string]int, len(defs))
+	plain := make([]model.VNFDefinition, len(defs))
+	values := make(map[string]string, len(defs))
+	for i, def := range defs {
+		if plain[i], err = openDefinition(def); err != nil { return nil, err }
+		byName[def.ParameterName] = i
+		values[def.ParameterName] = plain[i].CurrentValue
+	}
+
+	plan := &bulkPlan{defs: defs, invalid: &ValidationError{}}
+	verr, changed := plan.invalid, make(map[string]bool)
+	for _, name := range names {
+		value, ok := formatValue(input[name])
+		if !ok { verr.add(name, "取值须为字符串、数字或布尔值"); continue }
+		i, exists := byName[name]
+		access := policy.Access(caller, name, "")
+		if exists { access = policy.Access(caller, name, defs[i].Group) }
+		if !exists || !access.CanView { verr.add(name, "参数不存在"); continue }
+		if !access.CanEdit { verr.add(name, ErrPermissionDenied.Error()); continue }
+		if (access.Masked || defs[i].Type == TypeSecret) && value == MaskedValue { continue } // 原样回传的屏蔽值视为未修改
+		if value == plain[i].CurrentValue { continue }
+		if !defs[i].CanBeUpdated { verr.add(name, "参数无法更新"); continue }
+		if rules.enforced(defs[i].Group) { verr.add(name, ErrApprovalRequired.Error()); continue }
+		if msg := validateValue(defs[i], value); msg != "" { verr.add(name, msg); continue }
+		values[name], changed[name] = value, true
+	}
+	verr.checkRequired(defs, values, changed)
+
+	for _, name := range names {
+		if !changed[name] { continue }
+		i := byName[name]
+		item := plain[i]
+		item.CurrentValue = values[name]
+		item.Modified = item.CurrentValue != item.DefaultValue
+		if err := resealDefinition(&item, defs[i], plain[i]); err != nil { return nil, err }
+		plan.before, plan.after = append(plan.before, defs[i]), append(plan.after, item)
+	}
+	return plan, nil
+}
+
+// formatValue 将JSON取值转换为定义中保存的字符串形式
+func formatValue(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// CheckConsistency 校验该VNF的定义在MySQL与MongoDB中是否一致
+func (s *DefinitionService) CheckConsistency(ctx context.Context, vnfID uint) (*DefinitionConsistency, error) {
+	return s.dualStorage.CheckDefinitionConsistency(vnfID)
 }
 
 
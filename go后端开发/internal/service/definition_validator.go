@@ -0,0 +1,231 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/model"
+)
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	ParameterName string `json:"parameterName"`
+	Message       string `json:"message"`
+}
+
+// ValidationError 批量修改的校验结果，按参数列出全部错误
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d 个参数校验失败", len(e.Fields))
+}
+
+// add 记录参数的校验错误，每个参数只保留第一条
+func (e *ValidationError) add(name, format string, args ...interface{}) {
+	for _, f := range e.Fields {
+		if f.ParameterName == name {
+			return
+		}
+	}
+	e.Fields = append(e.Fields, FieldError{ParameterName: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkRequired 跨参数必填检查：changed 中的参数，以及隐藏条件引用了它们的参数，
+// 按修改后的整组取值 values 不隐藏且必填时不能为空
+func (e *ValidationError) checkRequired(defs []model.VNFDefinition, values map[string]string, changed map[string]bool) {
+	for _, def := range defs {
+		affected := changed[def.ParameterName]
+		for _, ref := range conditionRefs(def.HiddenCondition) {
+			affected = affected || changed[ref]
+		}
+		if !affected || !required(def) || values[def.ParameterName] != "" {
+			continue
+		}
+		if hidden, ok := evalCondition(def.HiddenCondition, values); ok && hidden {
+			continue
+		}
+		if def.HiddenCondition != "" {
+			e.add(def.ParameterName, "必填（%s 不成立时）", def.HiddenCondition)
+		} else {
+			e.add(def.ParameterName, "必填")
+		}
+	}
+}
+
+// validateValue 按参数类型与描述文件中的校验规则（constraints）检查取值，空值由必填检查处理
+func validateValue(def model.VNFDefinition, value string) string {
+	if value == "" {
+		return ""
+	}
+	switch def.Type {
+	case "number", "integer":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "应为数字"
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return "应为 true 或 false"
+		}
+	case "array":
+		var items []interface{}
+		if json.Unmarshal([]byte(value), &items) != nil || items == nil {
+			return "应为JSON数组"
+		}
+	case "object":
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(value), &fields) != nil || fields == nil {
+			return "应为JSON对象"
+		}
+	}
+	rules := parseConstraints(def.Constraints)
+	if len(rules) == 0 {
+		return ""
+	}
+	fail := func(msg string) string {
+		if custom, ok := rules["message"].(string); ok && custom != "" {
+			return custom
+		}
+		return msg
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		if min, ok := number(rules["min"]); ok && n < min {
+			return fail(fmt.Sprintf("不能小于 %v", rules["min"]))
+		}
+		if max, ok := number(rules["max"]); ok && n > max {
+			return fail(fmt.Sprintf("不能大于 %v", rules["max"]))
+		}
+	}
+	length := len([]rune(value))
+	if min, ok := number(rules["min_length"]); ok && float64(length) < min {
+		return fail(fmt.Sprintf("长度不能小于 %v", rules["min_length"]))
+	}
+	if max, ok := number(rules["max_length"]); ok && float64(length) > max {
+		return fail(fmt.Sprintf("长度不能大于 %v", rules["max_length"]))
+	}
+	if pattern, ok := rules["pattern"].(string); ok && pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(value) {
+			return fail("格式不正确")
+		}
+	}
+	for _, key := range []string{"options", "enum"} {
+		options, ok := rules[key].([]interface{})
+		if !ok || len(options) == 0 {
+			continue
+		}
+		for _, o := range options {
+			if fmt.Sprint(o) == value {
+				return ""
+			}
+		}
+		return fail(fmt.Sprintf("应为以下取值之一: %v", options))
+	}
+	return ""
+}
+
+// parseConstraints 解析以YAML保存的校验规则，不是对象时返回 nil
+func parseConstraints(raw string) map[string]interface{} {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var rules map[string]interface{}
+	if yaml.Unmarshal([]byte(raw), &rules) != nil {
+		return nil
+	}
+	return rules
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// conditionPattern 隐藏条件中的单个比较，如 ssl_enabled == false、replicas >= 3
+var conditionPattern = regexp.MustCompile(`^\s*([A-Za-z0-9_.\-]+)\s*(==|!=|>=|<=|>|<)\s*(.+?)\s*$`)
+
+// evalCondition 计算隐藏条件（比较式以 && 与 || 连接，&& 优先），values 为参数名到当前值的映射。
+// 无法解析的条件 ok 为 false，调用方按“不隐藏”处理
+func evalCondition(expr string, values map[string]string) (result, ok bool) {
+	if strings.TrimSpace(expr) == "" {
+		return false, false
+	}
+	for _, any := range strings.Split(expr, "||") {
+		all := true
+		for _, term := range strings.Split(any, "&&") {
+			m := conditionPattern.FindStringSubmatch(term)
+			if m == nil {
+				return false, false
+			}
+			if !compare(values[m[1]], m[2], strings.Trim(m[3], `"'`)) {
+				all = false
+			}
+		}
+		if all {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// conditionRefs 返回隐藏条件引用的参数名
+func conditionRefs(expr string) []string {
+	var refs []string
+	for _, any := range strings.Split(expr, "||") {
+		for _, term := range strings.Split(any, "&&") {
+			if m := conditionPattern.FindStringSubmatch(term); m != nil {
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	return refs
+}
+
+func compare(left, op, right string) bool {
+	l, lerr := strconv.ParseFloat(left, 64)
+	r, rerr := strconv.ParseFloat(right, 64)
+	if lerr == nil && rerr == nil {
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		case ">=":
+			return l >= r
+		case "<=":
+			return l <= r
+		case ">":
+			return l > r
+		case "<":
+			return l < r
+		}
+	}
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	return false
+}
+
+// required 参数是否必填：optional 显式为 false
+func required(def model.VNFDefinition) bool {
+	return def.Optional != nil && !*def.Optional
+}
@@ -3,29 +3,39 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"gorm.io/gorm"
 
-	"vnf-config/internal/infra/db"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/infra/metrics"
 	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
 )
 
-// DualStorageService 双数据库存储服务
+// DualStorageService 双存储服务：关系型存储（MySQL/SQLite）保存结构化数据，
+// 文档存储（MongoDB/本地文件）保存完整配置
 type DualStorageService struct {
-	mysqlDB *gorm.DB
-	mongoDB *mongo.Client
+	repos     *repository.Repositories
+	store     repository.Store
+	documents repository.DocumentRepository
+	outbox    *OutboxService
+	gitops    *GitOpsService
 }
 
-func NewDualStorageService() *DualStorageService {
+func NewDualStorageService(repos *repository.Repositories) *DualStorageService {
 	return &DualStorageService{
-		mysqlDB: db.MySQLDB,
-		mongoDB: db.MongoDB,
+		repos:     repos,
+		store:     repos.Store,
+		documents: repos.Documents,
+		outbox:    NewOutboxService(repos),
+		gitops:    NewGitOpsService(repos),
 	}
 }
 
@@ -35,6 +45,7 @@ type StorageResult struct {
 	MongoSuccess bool
 	MySQLError   error
 	MongoError   error
+	MongoPending bool // MongoDB操作已记录在发件箱中，等待后台重试
 	Data         interface{}
 }
 
@@ -53,11 +64,13 @@ type VNFInstanceMongo struct {
 // VNFDefinitionMongo VNF定义MongoDB模型
 type VNFDefinitionMongo struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	DefinitionID    uint               `bson:"definition_id" json:"definitionId"` // 对应MySQL vnf_definitions.id
 	VNFID           uint               `bson:"vnf_id" json:"vnfId"`
 	ParameterName   string             `bson:"parameter_name" json:"parameterName"`
 	DefaultValue    interface{}        `bson:"default_value" json:"defaultValue"`
 	DescriptionText string             `bson:"description_text" json:"descriptionTxt"`
 	Type            string             `bson:"type" json:"type"`
+	Group           string             `bson:"group" json:"group"`
 	CanBeUpdated    bool               `bson:"can_be_updated" json:"canBeUpdated"`
 	HiddenCondition string             `bson:"hidden_condition" json:"hiddenCondition"`
 	Optional        *bool              `bson:"optional" json:"optional"`
@@ -69,19 +82,361 @@ type VNFDefinitionMongo struct {
 	Metadata       map[string]interface{} `bson:"metadata" json:"metadata"`
 }
 
-// StoreVNFInstance 存储VNF实例到双数据库
-func (s *DualStorageService) StoreVNFInstance(instance *model.VNFInstance, yamlConfig *YAMLConfig) *StorageResult {
-	result := &StorageResult{}
+// StoreVNFInstance 存储VNF实例到双数据库。
+// MySQL写入与MongoDB发件箱记录在同一事务中提交，随后立即尝试派发；
+// 派发失败的操作保留在发件箱中由后台重试，最终两边一致。
+func (s *DualStorageService) StoreVNFInstance(ctx context.Context, instance *model.VNFInstance, yamlConfig *YAMLConfig) *StorageResult {
+	result := &StorageResult{Data: instance}
 
-	// 存储到MySQL
-	if err := s.mysqlDB.Create(instance).Error; err != nil {
+	var entryIDs []uint
+	err := s.transaction(ctx, "store_instance", func(tx repository.Store) error {
+		if err := tx.Instances().Create(ctx, instance); err != nil {
+			return err
+		}
+		entry, err := s.enqueueInstanceUpsert(ctx, tx, instance, yamlConfig)
+		if err != nil {
+			return err
+		}
+		entryIDs = append(entryIDs, entry.ID)
+		return recordInstanceChange(ctx, tx, model.ChangeActionCreate, nil, instance)
+	})
+	if err != nil {
 		result.MySQLError = err
-	} else {
+		return result
+	}
+	result.MySQLSuccess = true
+
+	s.flushOutbox(result, entryIDs)
+	return result
+}
+
+// StoreVNFClone 在同一事务中创建克隆或导入的实例及其定义、参数权限规则、审批策略与覆盖层，
+// 配置文档与定义经发件箱同步到MongoDB；各项的 VNFID 与覆盖层的上级ID由此处填写。
+// 机密参数的定义取值与覆盖层取值以明文传入，在此按新实例加密（密文绑定所属实例）
+func (s *DualStorageService) StoreVNFClone(ctx context.Context, instance *model.VNFInstance, yamlConfig *YAMLConfig, definitions []model.VNFDefinition, permissions []model.ParameterPermission, policies []model.ApprovalPolicy, overlays []model.Overlay) *StorageResult {
+	result := &StorageResult{Data: instance}
+
+	var entryIDs []uint
+	err := s.transaction(ctx, "store_clone", func(tx repository.Store) error {
+		if err := tx.Instances().Create(ctx, instance); err != nil {
+			return err
+		}
+		entry, err := s.enqueueInstanceUpsert(ctx, tx, instance, yamlConfig)
+		if err != nil {
+			return err
+		}
+		entryIDs = append(entryIDs, entry.ID)
+		if err := recordInstanceChange(ctx, tx, model.ChangeActionCreate, nil, instance); err != nil {
+			return err
+		}
+		secret := make(map[string]bool)
+		for i := range definitions {
+			definitions[i].VNFID = instance.ID
+			secret[definitions[i].ParameterName] = definitions[i].Type == TypeSecret
+			if err := sealDefinition(&definitions[i]); err != nil {
+				return fmt.Errorf("参数 %s 加密失败: %v", definitions[i].ParameterName, err)
+			}
+		}
+		if len(definitions) > 0 {
+			if err := tx.Definitions().CreateBatch(ctx, definitions); err != nil {
+				return err
+			}
+		}
+		for i := range definitions {
+			def := &definitions[i]
+			entry, err := s.enqueueDefinitionUpsert(ctx, tx, def)
+			if err != nil {
+				return err
+			}
+			entryIDs = append(entryIDs, entry.ID)
+			if err := recordDefinitionChange(ctx, tx, model.ChangeActionCreate, nil, def); err != nil {
+				return err
+			}
+		}
+		for i := range permissions {
+			permissions[i].VNFID = instance.ID
+			if err := tx.Permissions().Upsert(ctx, &permissions[i]); err != nil {
+				return err
+			}
+		}
+		for i := range policies {
+			policies[i].VNFID = instance.ID
+			if err := tx.ApprovalPolicies().Upsert(ctx, &policies[i]); err != nil {
+				return err
+			}
+		}
+		return cloneOverlays(ctx, tx, instance.ID, overlays, secret)
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
+	}
+	result.MySQLSuccess = true
+
+	s.flushOutbox(result, entryIDs)
+	s.gitops.Publish(ctx, instance.ID, model.ChangeActionCreate, parameterNames(definitions))
+	return result
+}
+
+// cloneOverlays 按继承顺序（上级在前）复制覆盖层及其取值，并将上级ID映射为新覆盖层的ID；secret 中参数的取值为明文，按新实例加密
+func cloneOverlays(ctx context.Context, tx repository.Store, vnfID uint, overlays []model.Overlay, secret map[string]bool) error {
+	ids := make(map[uint]uint, len(overlays))
+	for len(ids) < len(overlays) {
+		progressed := false
+		for _, src := range overlays {
+			if _, done := ids[src.ID]; done {
+				continue
+			}
+			var parentID *uint
+			if src.ParentID != nil {
+				id, ok := ids[*src.ParentID]
+				if !ok {
+					continue
+				}
+				parentID = &id
+			}
+			overlay := model.Overlay{VNFID: vnfID, Name: src.Name, ParentID: parentID, Description: src.Description}
+			if err := tx.Overlays().Create(ctx, &overlay); err != nil {
+				return err
+			}
+			values := make([]model.OverlayValue, len(src.Values))
+			for i, v := range src.Values {
+				value := v.Value
+				if secret[v.ParameterName] {
+					var err error
+					if value, err = secrets.Default().Encrypt(secretOwner(vnfID, v.ParameterName), value); err != nil {
+						return fmt.Errorf("参数 %s 加密失败: %v", v.ParameterName, err)
+					}
+				}
+				values[i] = model.OverlayValue{ParameterName: v.ParameterName, Value: value}
+			}
+			if err := tx.Overlays().ReplaceValues(ctx, overlay.ID, values); err != nil {
+				return err
+			}
+			ids[src.ID], progressed = overlay.ID, true
+		}
+		if !progressed {
+			return errors.New("覆盖层的上级不存在或继承关系成环")
+		}
+	}
+	return nil
+}
+
+// StoreVNFDefinitions 存储VNF定义到双数据库（发件箱方式，同StoreVNFInstance）
+func (s *DualStorageService) StoreVNFDefinitions(ctx context.Context, definitions []model.VNFDefinition) *StorageResult {
+	result := &StorageResult{Data: definitions}
+	if len(definitions) == 0 {
 		result.MySQLSuccess = true
+		result.MongoSuccess = true
+		return result
+	}
+
+	var entryIDs []uint
+	err := s.transaction(ctx, "store_definitions", func(tx repository.Store) error {
+		if err := tx.Definitions().CreateBatch(ctx, definitions); err != nil {
+			return err
+		}
+		for i := range definitions {
+			def := &definitions[i]
+			entry, err := s.enqueueDefinitionUpsert(ctx, tx, def)
+			if err != nil {
+				return err
+			}
+			entryIDs = append(entryIDs, entry.ID)
+			if err := recordDefinitionChange(ctx, tx, model.ChangeActionCreate, nil, def); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
+	}
+	result.MySQLSuccess = true
+
+	s.flushOutbox(result, entryIDs)
+	s.gitops.Publish(ctx, definitions[0].VNFID, model.ChangeActionCreate, parameterNames(definitions))
+	result.Data = definitions
+	return result
+}
+
+// CreateVNFDefinition 新建单个VNF定义并同步到MongoDB
+func (s *DualStorageService) CreateVNFDefinition(ctx context.Context, def *model.VNFDefinition) *StorageResult {
+	return s.writeDefinition(ctx, model.ChangeActionCreate, def, func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Definitions().Create(ctx, def); err != nil {
+			return err
+		}
+		return recordDefinitionChange(ctx, tx, model.ChangeActionCreate, nil, def)
+	})
+}
+
+// SaveVNFDefinition 保存已修改的VNF定义并同步到MongoDB，before 为修改前的内容
+func (s *DualStorageService) SaveVNFDefinition(ctx context.Context, before, def *model.VNFDefinition) *StorageResult {
+	return s.writeDefinition(ctx, model.ChangeActionUpdate, def, func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Definitions().Save(ctx, def); err != nil {
+			return err
+		}
+		return recordDefinitionChange(ctx, tx, model.ChangeActionUpdate, before, def)
+	})
+}
+
+// SaveVNFDefinitions 在同一事务中执行 write 并保存其返回的定义修改（before 与 after 一一对应），
+// write 或任一写入失败时整体回滚；全部定义经发件箱同步到MongoDB
+func (s *DualStorageService) SaveVNFDefinitions(ctx context.Context, operation string, write func(tx repository.Store) (before, after []model.VNFDefinition, err error)) *StorageResult {
+	result := &StorageResult{}
+	var entryIDs []uint
+	err := s.transaction(ctx, operation, func(tx repository.Store) error {
+		before, after, err := write(tx)
+		if err != nil {
+			return err
+		}
+		for i := range after {
+			def := &after[i]
+			if err := tx.Definitions().Save(ctx, def); err != nil {
+				return err
+			}
+			if err := recordDefinitionChange(ctx, tx, model.ChangeActionUpdate, &before[i], def); err != nil {
+				return err
+			}
+			entry, err := s.enqueueDefinitionUpsert(ctx, tx, def)
+			if err != nil {
+				return err
+			}
+			entryIDs = append(entryIDs, entry.ID)
+		}
+		result.Data = after
+		return nil
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
 	}
+	result.MySQLSuccess = true
+	s.flushOutbox(result, entryIDs)
+	if after, _ := result.Data.([]model.VNFDefinition); len(after) > 0 {
+		s.gitops.Publish(ctx, after[0].VNFID, model.ChangeActionUpdate, parameterNames(after))
+	}
+	return result
+}
+
+func (s *DualStorageService) writeDefinition(ctx context.Context, action string, def *model.VNFDefinition, write func(ctx context.Context, tx repository.Store) error) *StorageResult {
+	result := &StorageResult{Data: def}
+	var entryIDs []uint
+	err := s.transaction(ctx, "save_definition", func(tx repository.Store) error {
+		if err := write(ctx, tx); err != nil {
+			return err
+		}
+		entry, err := s.enqueueDefinitionUpsert(ctx, tx, def)
+		if err != nil {
+			return err
+		}
+		entryIDs = append(entryIDs, entry.ID)
+		return nil
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
+	}
+	result.MySQLSuccess = true
+	s.flushOutbox(result, entryIDs)
+	s.gitops.Publish(ctx, def.VNFID, action, []string{def.ParameterName})
+	return result
+}
+
+// DeleteVNFDefinition 删除VNF定义，并删除MongoDB中对应文档
+func (s *DualStorageService) DeleteVNFDefinition(ctx context.Context, vnfID, defID uint) *StorageResult {
+	result := &StorageResult{}
+	var entryIDs []uint
+	var name string
+	err := s.transaction(ctx, "delete_definition", func(tx repository.Store) error {
+		before, err := tx.Definitions().Get(ctx, vnfID, defID)
+		if err != nil {
+			return err
+		}
+		name = before.ParameterName
+		if err := tx.Definitions().Delete(ctx, vnfID, defID); err != nil {
+			return err
+		}
+		entry, err := s.outbox.EnqueueDelete(ctx, tx, repository.CollectionDefinitions, definitionFilter(vnfID, defID), time.Now())
+		if err != nil {
+			return err
+		}
+		entryIDs = append(entryIDs, entry.ID)
+		return recordDefinitionChange(ctx, tx, model.ChangeActionDelete, before, nil)
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
+	}
+	result.MySQLSuccess = true
+	s.flushOutbox(result, entryIDs)
+	s.gitops.Publish(ctx, vnfID, model.ChangeActionDelete, []string{name})
+	return result
+}
 
-	// 存储到MongoDB
-	mongoInstance := &VNFInstanceMongo{
+// DeleteVNFInstance 删除VNF实例及其全部定义，并级联删除MongoDB中的实例与定义文档
+func (s *DualStorageService) DeleteVNFInstance(ctx context.Context, id uint) *StorageResult {
+	result := &StorageResult{}
+	var entryIDs []uint
+	err := s.transaction(ctx, "delete_instance", func(tx repository.Store) error {
+		before, err := tx.Instances().Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Definitions().DeleteByVNF(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.Permissions().DeleteByVNF(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.ChangeSets().DeleteByVNF(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.ApprovalPolicies().DeleteByVNF(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.Overlays().DeleteByVNF(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.Instances().Delete(ctx, id); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, collection := range []string{repository.CollectionDefinitions, repository.CollectionInstances} {
+			entry, err := s.outbox.EnqueueDelete(ctx, tx, collection, bson.M{"vnf_id": id}, now)
+			if err != nil {
+				return err
+			}
+			entryIDs = append(entryIDs, entry.ID)
+		}
+		return recordInstanceChange(ctx, tx, model.ChangeActionDelete, before, nil)
+	})
+	if err != nil {
+		result.MySQLError = err
+		return result
+	}
+	result.MySQLSuccess = true
+	s.flushOutbox(result, entryIDs)
+	s.gitops.Publish(ctx, id, model.ChangeActionDelete, nil)
+	return result
+}
+
+// transaction 在关系型存储事务中执行写入，并记录耗时与失败次数
+func (s *DualStorageService) transaction(ctx context.Context, operation string, fn func(tx repository.Store) error) error {
+	start := time.Now()
+	err := s.store.Transaction(ctx, fn)
+	metrics.ObserveStorage(s.store.Backend(), operation, start, err)
+	return err
+}
+
+func (s *DualStorageService) enqueueDefinitionUpsert(ctx context.Context, tx repository.Store, def *model.VNFDefinition) (*model.MongoOutbox, error) {
+	return s.outbox.EnqueueUpsert(ctx, tx, repository.CollectionDefinitions, definitionFilter(def.VNFID, def.ID), toDefinitionMongo(*def), def.UpdatedAt)
+}
+
+// enqueueInstanceUpsert 记录写入实例配置文档的发件箱操作
+func (s *DualStorageService) enqueueInstanceUpsert(ctx context.Context, tx repository.Store, instance *model.VNFInstance, yamlConfig *YAMLConfig) (*model.MongoOutbox, error) {
+	doc := &VNFInstanceMongo{
 		VNFID:      instance.ID,
 		Name:       instance.Name,
 		CreatedAt:  instance.CreatedAt,
@@ -90,133 +445,184 @@ func (s *DualStorageService) StoreVNFInstance(instance *model.VNFInstance, yamlC
 		FormFields: yamlConfig.Fields,
 		Metadata:   yamlConfig.Metadata,
 	}
+	return s.outbox.EnqueueUpsert(ctx, tx, repository.CollectionInstances, bson.M{"vnf_id": instance.ID}, doc, instance.UpdatedAt)
+}
 
-	collection := db.GetMongoCollection("vnf_instances")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if _, err := collection.InsertOne(ctx, mongoInstance); err != nil {
-		result.MongoError = err
-	} else {
-		result.MongoSuccess = true
+// parameterNames 返回定义的参数名
+func parameterNames(defs []model.VNFDefinition) []string {
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		names[i] = def.ParameterName
 	}
+	return names
+}
 
-	result.Data = instance
-	return result
+// definitionFilter MongoDB中定义文档以MySQL定义ID关联
+func definitionFilter(vnfID, defID uint) bson.M {
+	return bson.M{"vnf_id": vnfID, "definition_id": defID}
 }
 
-// StoreVNFDefinitions 存储VNF定义到双数据库
-func (s *DualStorageService) StoreVNFDefinitions(definitions []model.VNFDefinition) *StorageResult {
-	result := &StorageResult{}
+// recordInstanceChange 在事务中追加实例变更历史
+func recordInstanceChange(ctx context.Context, tx repository.Store, action string, before, after *model.VNFInstance) error {
+	record := &model.ChangeRecord{EntityType: "instance", Action: action}
+	for _, inst := range []*model.VNFInstance{after, before} {
+		if inst != nil {
+			record.VNFID = inst.ID
+			record.EntityID = inst.ID
+		}
+	}
+	return appendChange(ctx, tx, record, before, after)
+}
 
-	// 存储到MySQL
-	if err := s.mysqlDB.Create(&definitions).Error; err != nil {
-		result.MySQLError = err
-	} else {
-		result.MySQLSuccess = true
+// recordDefinitionChange 在事务中追加定义变更历史
+func recordDefinitionChange(ctx context.Context, tx repository.Store, action string, before, after *model.VNFDefinition) error {
+	record := &model.ChangeRecord{EntityType: "definition", Action: action}
+	for _, def := range []*model.VNFDefinition{after, before} {
+		if def != nil {
+			record.VNFID = def.VNFID
+			record.EntityID = def.ID
+			record.ParameterName = def.ParameterName
+		}
 	}
+	return appendChange(ctx, tx, record, before, after)
+}
 
-	// 存储到MongoDB
-	var mongoDefs []interface{}
-	for _, def := range definitions {
-		mongoDef := &VNFDefinitionMongo{
-			VNFID:           def.VNFID,
-			ParameterName:   def.ParameterName,
-			DefaultValue:    def.DefaultValue,
-			DescriptionText: def.DescriptionText,
-			Type:            def.Type,
-			CanBeUpdated:    def.CanBeUpdated,
-			HiddenCondition: def.HiddenCondition,
-			Optional:        def.Optional,
-			Constraints:     def.Constraints,
-			CurrentValue:    def.CurrentValue,
-			Modified:        def.Modified,
-			CreatedAt:       def.CreatedAt,
-			UpdatedAt:       def.UpdatedAt,
-			Metadata:        make(map[string]interface{}),
-		}
-		mongoDefs = append(mongoDefs, mongoDef)
-	}
-
-	if len(mongoDefs) > 0 {
-		collection := db.GetMongoCollection("vnf_definitions")
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		if _, err := collection.InsertMany(ctx, mongoDefs); err != nil {
-			result.MongoError = err
-		} else {
-			result.MongoSuccess = true
+// maxReasonLength 变更原因的最大字符数，与 ChangeRecord.Reason 列宽一致
+const maxReasonLength = 1024
+
+// appendChange 追加变更历史，操作人、请求ID与变更原因取自ctx
+func appendChange(ctx context.Context, tx repository.Store, record *model.ChangeRecord, before, after interface{}) error {
+	record.Actor = auth.ActorFrom(ctx)
+	record.RequestID = audit.RequestID(ctx)
+	if record.Reason == "" {
+		record.Reason = audit.Reason(ctx)
+	}
+	if r := []rune(record.Reason); len(r) > maxReasonLength {
+		record.Reason = string(r[:maxReasonLength])
+	}
+	for _, snap := range []struct {
+		value interface{}
+		dest  *string
+	}{{before, &record.Before}, {after, &record.After}} {
+		if isNilSnapshot(snap.value) {
+			continue
 		}
+		data, err := json.Marshal(snap.value)
+		if err != nil {
+			return err
+		}
+		*snap.dest = string(data)
 	}
+	return tx.History().Append(ctx, record)
+}
 
-	result.Data = definitions
-	return result
+func isNilSnapshot(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case *model.VNFInstance:
+		return x == nil
+	case *model.VNFDefinition:
+		return x == nil
+	}
+	return false
+}
+
+// flushOutbox 立即派发刚记录的操作，失败时标记为待同步
+func (s *DualStorageService) flushOutbox(result *StorageResult, entryIDs []uint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.outbox.Flush(ctx, entryIDs); err != nil {
+		result.MongoError = err
+		result.MongoPending = true
+		return
+	}
+	result.MongoSuccess = true
+}
+
+// toDefinitionMongo 将MySQL定义转换为MongoDB文档
+func toDefinitionMongo(def model.VNFDefinition) *VNFDefinitionMongo {
+	return &VNFDefinitionMongo{
+		DefinitionID:    def.ID,
+		VNFID:           def.VNFID,
+		ParameterName:   def.ParameterName,
+		DefaultValue:    def.DefaultValue,
+		DescriptionText: def.DescriptionText,
+		Type:            def.Type,
+		Group:           def.Group,
+		CanBeUpdated:    def.CanBeUpdated,
+		HiddenCondition: def.HiddenCondition,
+		Optional:        def.Optional,
+		Constraints:     def.Constraints,
+		CurrentValue:    def.CurrentValue,
+		Modified:        def.Modified,
+		CreatedAt:       def.CreatedAt,
+		UpdatedAt:       def.UpdatedAt,
+		Metadata:        make(map[string]interface{}),
+	}
 }
 
 // GetVNFInstanceFromMongo 从MongoDB获取VNF实例
 func (s *DualStorageService) GetVNFInstanceFromMongo(vnfID uint) (*VNFInstanceMongo, error) {
-	collection := db.GetMongoCollection("vnf_instances")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var instance VNFInstanceMongo
-	err := collection.FindOne(ctx, bson.M{"vnf_id": vnfID}).Decode(&instance)
+	start := time.Now()
+	err := s.documents.FindOne(ctx, repository.CollectionInstances, bson.M{"vnf_id": vnfID}, &instance)
+	metrics.ObserveStorage(s.documents.Backend(), "find_instance", start, err)
 	if err != nil {
 		return nil, err
 	}
-
+	instance.YAMLConfig = plainValue(instance.YAMLConfig)
+	instance.FormFields = plainValue(instance.FormFields)
 	return &instance, nil
 }
 
+// plainValue 将BSON解码出的 primitive.D/A 递归转换为普通 map/切片，便于JSON输出
+func plainValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(x))
+		for _, e := range x {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.M:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[k] = plainValue(val)
+		}
+		return m
+	case primitive.A:
+		out := make([]interface{}, len(x))
+		for i, val := range x {
+			out[i] = plainValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
 // GetVNFDefinitionsFromMongo 从MongoDB获取VNF定义
 func (s *DualStorageService) GetVNFDefinitionsFromMongo(vnfID uint) ([]VNFDefinitionMongo, error) {
-	collection := db.GetMongoCollection("vnf_definitions")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.M{"vnf_id": vnfID})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
 	var definitions []VNFDefinitionMongo
-	if err := cursor.All(ctx, &definitions); err != nil {
+	start := time.Now()
+	err := s.documents.Find(ctx, repository.CollectionDefinitions, bson.M{"vnf_id": vnfID}, &definitions)
+	metrics.ObserveStorage(s.documents.Backend(), "find_definitions", start, err)
+	if err != nil {
 		return nil, err
 	}
-
 	return definitions, nil
 }
 
-// UpdateVNFDefinitionInMongo 在MongoDB中更新VNF定义
-func (s *DualStorageService) UpdateVNFDefinitionInMongo(vnfID uint, defID uint, updates map[string]interface{}) error {
-	collection := db.GetMongoCollection("vnf_definitions")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	updates["updated_at"] = time.Now()
-	_, err := collection.UpdateOne(
-		ctx,
-		bson.M{"vnf_id": vnfID, "_id": defID},
-		bson.M{"$set": updates},
-	)
-	return err
-}
-
-// DeleteVNFDefinitionFromMongo 从MongoDB删除VNF定义
-func (s *DualStorageService) DeleteVNFDefinitionFromMongo(vnfID uint, defID uint) error {
-	collection := db.GetMongoCollection("vnf_definitions")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err := collection.DeleteOne(ctx, bson.M{"vnf_id": vnfID, "_id": defID})
-	return err
-}
-
 // SearchVNFInstancesInMongo 在MongoDB中搜索VNF实例
 func (s *DualStorageService) SearchVNFInstancesInMongo(query map[string]interface{}) ([]VNFInstanceMongo, error) {
-	collection := db.GetMongoCollection("vnf_instances")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -230,18 +636,74 @@ func (s *DualStorageService) SearchVNFInstancesInMongo(query map[string]interfac
 		}
 	}
 
-	cursor, err := collection.Find(ctx, filter)
+	var instances []VNFInstanceMongo
+	start := time.Now()
+	err := s.documents.Find(ctx, repository.CollectionInstances, filter, &instances)
+	metrics.ObserveStorage(s.documents.Backend(), "search_instances", start, err)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
+	return instances, nil
+}
 
-	var instances []VNFInstanceMongo
-	if err := cursor.All(ctx, &instances); err != nil {
-		return nil, err
+// 读取来源
+const (
+	ReadSourceMongo = "mongodb"
+	ReadSourceMySQL = "mysql" // 降级：由结构化数据重建
+)
+
+// GetVNFConfig 读取VNF的完整配置文档。文档存储不可用或尚未同步（写入仍在发件箱中）时
+// 由关系型存储中的实例和参数定义重建，返回实际的读取来源
+func (s *DualStorageService) GetVNFConfig(vnfID uint) (*VNFInstanceMongo, string, error) {
+	instance, err := s.GetVNFInstanceFromMongo(vnfID)
+	if err == nil {
+		// 统一为与重建结果相同的结构，两种来源的响应格式一致
+		if config, err := decodeYAMLConfig(instance.YAMLConfig); err == nil {
+			instance.YAMLConfig = config
+			instance.FormFields = config.Fields
+		}
+		return instance, ReadSourceMongo, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("读取MongoDB失败，回退到%s: %v", s.store.Backend(), err)
 	}
 
-	return instances, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	inst, err := s.store.Instances().Get(ctx, vnfID)
+	if err != nil {
+		return nil, "", err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, "", err
+	}
+	config := yamlConfigFromDefinitions(defs)
+	return &VNFInstanceMongo{
+		VNFID:      inst.ID,
+		Name:       inst.Name,
+		YAMLConfig: config,
+		FormFields: config.Fields,
+		Metadata:   config.Metadata,
+		CreatedAt:  inst.CreatedAt,
+		UpdatedAt:  inst.UpdatedAt,
+	}, ReadSourceMySQL, nil
+}
+
+// decodeYAMLConfig 将文档中的 yaml_config 字段解码为 YAMLConfig
+func decodeYAMLConfig(v interface{}) (*YAMLConfig, error) {
+	if v == nil {
+		return nil, errors.New("文档缺少 yaml_config")
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var config YAMLConfig
+	if err := bson.Unmarshal(raw, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
 }
 
 // GetFormFieldsFromMongo 从MongoDB获取表单项
@@ -251,89 +713,137 @@ func (s *DualStorageService) GetFormFieldsFromMongo(vnfID uint) (map[string]inte
 		return nil, err
 	}
 
-	return instance.FormFields, nil
+	fields := make(map[string]interface{})
+	switch v := instance.FormFields.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case primitive.M:
+		return v, nil
+	case primitive.D:
+		for _, e := range v {
+			fields[e.Key] = e.Value
+		}
+	}
+	return fields, nil
 }
 
-// SyncDataBetweenDatabases 在数据库之间同步数据
-func (s *DualStorageService) SyncDataBetweenDatabases() error {
-	log.Println("开始同步MySQL和MongoDB数据...")
+// DefinitionConsistency 单个VNF的定义在两个数据库中的一致性检查结果
+type DefinitionConsistency struct {
+	VNFID             uint             `json:"vnfId"`
+	Consistent        bool             `json:"consistent"`
+	MySQLCount        int              `json:"mysqlCount"`
+	MongoCount        int              `json:"mongoCount"`
+	PendingOperations int64            `json:"pendingOperations"`
+	MissingInMongo    []uint           `json:"missingInMongo"`
+	OrphanedInMongo   []string         `json:"orphanedInMongo"`
+	Differing         []DefinitionDiff `json:"differing"`
+}
+
+// DefinitionDiff 两边内容不一致的定义及其字段
+type DefinitionDiff struct {
+	DefinitionID  uint     `json:"definitionId"`
+	ParameterName string   `json:"parameterName"`
+	Fields        []string `json:"fields"`
+}
+
+// CheckDefinitionConsistency 按定义ID逐字段比对MySQL与MongoDB中的VNF定义
+func (s *DualStorageService) CheckDefinitionConsistency(vnfID uint) (*DefinitionConsistency, error) {
+	ctx := context.Background()
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	mongoDefs, err := s.GetVNFDefinitionsFromMongo(vnfID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 从MySQL获取所有VNF实例
-	var instances []model.VNFInstance
-	if err := s.mysqlDB.Find(&instances).Error; err != nil {
-		return fmt.Errorf("从MySQL获取VNF实例失败: %v", err)
+	report := &DefinitionConsistency{
+		VNFID:           vnfID,
+		MySQLCount:      len(defs),
+		MongoCount:      len(mongoDefs),
+		MissingInMongo:  []uint{},
+		OrphanedInMongo: []string{},
+		Differing:       []DefinitionDiff{},
 	}
 
-	// 同步到MongoDB
-	for _, instance := range instances {
-		// 检查MongoDB中是否已存在
-		existing, err := s.GetVNFInstanceFromMongo(instance.ID)
-		if err != nil && err != mongo.ErrNoDocuments {
-			log.Printf("检查VNF实例 %d 失败: %v", instance.ID, err)
+	byID := make(map[uint]VNFDefinitionMongo, len(mongoDefs))
+	for _, doc := range mongoDefs {
+		if _, dup := byID[doc.DefinitionID]; dup || doc.DefinitionID == 0 {
+			// 无定义ID或重复的文档无法与MySQL对应
+			report.OrphanedInMongo = append(report.OrphanedInMongo, doc.ID.Hex())
 			continue
 		}
+		byID[doc.DefinitionID] = doc
+	}
+	for _, def := range defs {
+		doc, ok := byID[def.ID]
+		if !ok {
+			report.MissingInMongo = append(report.MissingInMongo, def.ID)
+			continue
+		}
+		delete(byID, def.ID)
+		if fields := diffDefinition(def, doc); len(fields) > 0 {
+			report.Differing = append(report.Differing, DefinitionDiff{
+				DefinitionID:  def.ID,
+				ParameterName: def.ParameterName,
+				Fields:        fields,
+			})
+		}
+	}
+	for _, doc := range byID {
+		report.OrphanedInMongo = append(report.OrphanedInMongo, doc.ID.Hex())
+	}
 
-		if existing == nil {
-			// 创建新的MongoDB记录
-			mongoInstance := &VNFInstanceMongo{
-				VNFID:     instance.ID,
-				Name:      instance.Name,
-				CreatedAt: instance.CreatedAt,
-				UpdatedAt: instance.UpdatedAt,
-			}
+	if report.PendingOperations, err = s.outbox.PendingCount(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	report.Consistent = len(report.MissingInMongo) == 0 && len(report.OrphanedInMongo) == 0 && len(report.Differing) == 0
+	return report, nil
+}
 
-			collection := db.GetMongoCollection("vnf_instances")
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+// diffDefinition 返回MySQL定义与MongoDB文档之间取值不同的字段名
+func diffDefinition(def model.VNFDefinition, doc VNFDefinitionMongo) []string {
+	var fields []string
+	for _, drift := range definitionFieldDrifts(def, doc) {
+		fields = append(fields, drift.Field)
+	}
+	return fields
+}
 
-			if _, err := collection.InsertOne(ctx, mongoInstance); err != nil {
-				log.Printf("同步VNF实例 %d 到MongoDB失败: %v", instance.ID, err)
-			} else {
-				log.Printf("成功同步VNF实例 %d 到MongoDB", instance.ID)
-			}
-		}
+func boolString(b *bool) string {
+	if b == nil {
+		return "<nil>"
 	}
+	return fmt.Sprint(*b)
+}
 
-	log.Println("数据同步完成")
+// SyncDataBetweenDatabases 以MySQL为准对两库做一次全量对账修复
+func (s *DualStorageService) SyncDataBetweenDatabases() error {
+	log.Println("开始同步MySQL和MongoDB数据...")
+	report, err := NewReconcileService(s.repos).Run(context.Background(), ReconcileOptions{Source: ReconcileSourceMySQL, Repair: true})
+	if err != nil {
+		return fmt.Errorf("数据同步失败: %v", err)
+	}
+	log.Printf("数据同步完成: 缺失 %d, 孤立 %d, 不一致 %d",
+		report.Summary[DriftMissing], report.Summary[DriftOrphaned], report.Summary[DriftDiffering])
 	return nil
 }
 
-// GetStorageStatus 获取存储状态
+// GetStorageStatus 获取存储状态，键为后端名称（mysql/sqlite、mongodb/file）
 func (s *DualStorageService) GetStorageStatus() map[string]interface{} {
+	report := NewHealthService(s.repos).Check(context.Background())
 	status := make(map[string]interface{})
-
-	// MySQL状态
-	if s.mysqlDB != nil {
-		sqlDB, err := s.mysqlDB.DB()
-		if err == nil {
-			status["mysql"] = map[string]interface{}{
-				"connected": true,
-				"stats":     sqlDB.Stats(),
-			}
+	for _, h := range report.Stores {
+		entry := map[string]interface{}{"connected": h.Connected, "required": h.Required}
+		if h.Connected {
+			entry["stats"] = h.Stats
 		} else {
-			status["mysql"] = map[string]interface{}{
-				"connected": false,
-				"error":     err.Error(),
-			}
+			entry["error"] = h.Error
 		}
+		status[h.Backend] = entry
 	}
-
-	// MongoDB状态
-	if s.mongoDB != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		if err := s.mongoDB.Ping(ctx, nil); err != nil {
-			status["mongodb"] = map[string]interface{}{
-				"connected": false,
-				"error":     err.Error(),
-			}
-		} else {
-			status["mongodb"] = map[string]interface{}{
-				"connected": true,
-			}
-		}
-	}
-
+	status["status"] = report.Status
+	status["outboxPending"] = report.OutboxPending
 	return status
 }
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/gitops"
+	"vnf-shared/secrets"
+)
+
+// gitopsRoot 仓库中存放渲染结果的目录：vnfs/<id>/values.yaml 为基础取值，
+// vnfs/<id>/overlays/<name>.yaml 为各覆盖层的生效取值
+const gitopsRoot = "vnfs"
+
+// gitopsValuesPath 匹配外部提交中可导回的基础取值文件
+var gitopsValuesPath = regexp.MustCompile(`^` + gitopsRoot + `/(\d+)/values\.yaml$`)
+
+// 提交说明中的动作
+var gitopsActions = map[string]string{
+	model.ChangeActionCreate: "新建",
+	model.ChangeActionUpdate: "更新",
+	model.ChangeActionDelete: "删除",
+}
+
+// RenderedConfig 渲染到仓库中的配置文件内容，机密参数为密文
+type RenderedConfig struct {
+	VNF        RenderedVNF            `yaml:"vnf"`
+	Overlay    string                 `yaml:"overlay,omitempty"`
+	Parameters map[string]interface{} `yaml:"parameters"`
+}
+
+// RenderedVNF 配置文件所属的实例
+type RenderedVNF struct {
+	ID   uint   `yaml:"id"`
+	Name string `yaml:"name"`
+}
+
+// PullResult 一次拉取的结果：From 到 To 之间的外部提交逐个导入
+type PullResult struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Commits []PulledCommit `json:"commits"`
+}
+
+// PulledCommit 一个外部提交的导入结果，同一提交中各VNF的修改分别以一次批量修改应用
+type PulledCommit struct {
+	Hash    string         `json:"hash"`
+	Author  gitops.Author  `json:"author"`
+	Subject string         `json:"subject"`
+	Applied []PulledChange `json:"applied"`
+	Failed  []PulledChange `json:"failed"`
+}
+
+// PulledChange 提交对一个VNF的修改，失败时 Error 与 Fields 说明原因
+type PulledChange struct {
+	VNFID      uint         `json:"vnfId"`
+	Parameters []string     `json:"parameters,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Fields     []FieldError `json:"fields,omitempty"`
+}
+
+// GitOpsService 将参数变更后的生效配置渲染为YAML提交到本地git仓库（GITOPS_REPO_PATH），
+// 并将仓库中的外部提交导回为参数变更。未启用时全部操作为空操作或返回 gitops.ErrDisabled
+type GitOpsService struct {
+	repos *repository.Repositories
+	store repository.Store
+}
+
+func NewGitOpsService(repos *repository.Repositories) *GitOpsService {
+	return &GitOpsService{repos: repos, store: repos.Store}
+}
+
+// Publish 在后台渲染VNF的配置并提交，作者取自ctx中的调用方，提交说明列出本次修改的参数。
+// 渲染在仓库锁内读取最新数据，并发的提交不会以旧内容覆盖新内容；提交失败只记录日志
+func (s *GitOpsService) Publish(ctx context.Context, vnfID uint, action string, params []string) {
+	repo := gitops.Default()
+	if repo == nil {
+		return
+	}
+	author := repo.AuthorFor(auth.ActorFrom(ctx))
+	requestID, reason := audit.RequestID(ctx), audit.Reason(ctx)
+	params = append([]string(nil), params...)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		scope := path.Join(gitopsRoot, strconv.FormatUint(uint64(vnfID), 10))
+		_, err := repo.Commit(ctx, scope, author, func(root string) (string, error) {
+			name, err := s.render(ctx, root, vnfID)
+			return gitopsMessage(vnfID, name, action, params, requestID, reason), err
+		})
+		if err != nil {
+			log.Printf("GitOps提交VNF #%d 失败: %v", vnfID, err)
+		}
+	}()
+}
+
+// render 将VNF的基础取值与各覆盖层的生效取值写入工作区，实例已删除时删除其目录；返回实例名称
+func (s *GitOpsService) render(ctx context.Context, root string, vnfID uint) (string, error) {
+	dir := filepath.Join(root, gitopsRoot, strconv.FormatUint(uint64(vnfID), 10))
+	instance, err := s.store.Instances().Get(ctx, vnfID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", os.RemoveAll(dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return "", err
+	}
+	overlays, err := s.store.Overlays().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return "", err
+	}
+
+	base := make(map[string]string, len(defs))
+	types := make(map[string]string, len(defs))
+	for _, def := range defs {
+		base[def.ParameterName], types[def.ParameterName] = def.CurrentValue, def.Type
+	}
+	vnf := RenderedVNF{ID: instance.ID, Name: instance.Name}
+	if err := os.RemoveAll(filepath.Join(dir, "overlays")); err != nil {
+		return "", err
+	}
+	if err := writeRendered(filepath.Join(dir, "values.yaml"), RenderedConfig{VNF: vnf, Parameters: typedValues(base, types)}); err != nil {
+		return "", err
+	}
+	byID := make(map[uint]model.Overlay, len(overlays))
+	for _, o := range overlays {
+		byID[o.ID] = o
+	}
+	for _, o := range overlays {
+		// 生效取值按 基础 → 上级 → 本层 逐层覆盖，只覆盖基础中存在的参数
+		var chain []model.Overlay
+		for cur, ok := o, true; ok && len(chain) <= len(overlays); cur, ok = byID[derefID(cur.ParentID)] {
+			chain = append([]model.Overlay{cur}, chain...)
+		}
+		values := make(map[string]string, len(base))
+		for name, v := range base {
+			values[name] = v
+		}
+		for _, layer := range chain {
+			for _, v := range layer.Values {
+				if _, ok := values[v.ParameterName]; ok {
+					values[v.ParameterName] = v.Value
+				}
+			}
+		}
+		file := filepath.Join(dir, "overlays", o.Name+".yaml")
+		if err := writeRendered(file, RenderedConfig{VNF: vnf, Overlay: o.Name, Parameters: typedValues(values, types)}); err != nil {
+			return "", err
+		}
+	}
+	return instance.Name, nil
+}
+
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func writeRendered(file string, config RenderedConfig) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// typedValues 按参数类型输出取值：number、integer 为数字，boolean 为布尔值，array、object 按JSON解码为列表与映射，
+// 其余（含密文）以及无法按类型解析的取值为字符串
+func typedValues(values, types map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		out[name] = v
+		switch types[name] {
+		case "number", "integer":
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				out[name] = n
+			} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+				out[name] = f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(v); err == nil {
+				out[name] = b
+			}
+		case "array":
+			// 按YAML解码（JSON的超集），整数与数字参数一样为整数
+			var items []interface{}
+			if json.Valid([]byte(v)) && yaml.Unmarshal([]byte(v), &items) == nil && items != nil {
+				out[name] = items
+			}
+		case "object":
+			var fields map[string]interface{}
+			if json.Valid([]byte(v)) && yaml.Unmarshal([]byte(v), &fields) == nil && fields != nil {
+				out[name] = fields
+			}
+		}
+	}
+	return out
+}
+
+// gitopsMessage 提交说明：标题列出动作与参数（超过3个时只给数量），正文逐行列出参数、变更原因与请求ID
+func gitopsMessage(vnfID uint, name, action string, params []string, requestID, reason string) string {
+	label := gitopsActions[action]
+	if label == "" {
+		label = action
+	}
+	sort.Strings(params)
+	subject := fmt.Sprintf("vnf-%d", vnfID)
+	if name != "" {
+		subject += " (" + name + ")"
+	}
+	subject += ": " + label
+	switch {
+	case len(params) == 0:
+		subject += "实例"
+	case len(params) <= 3:
+		subject += " " + strings.Join(params, ", ")
+	default:
+		subject += fmt.Sprintf(" %d 个参数", len(params))
+	}
+	var b strings.Builder
+	b.WriteString(subject + "\n")
+	if len(params) > 0 {
+		b.WriteString("\n")
+		for _, p := range params {
+			b.WriteString("- " + p + "\n")
+		}
+	}
+	if reason != "" || requestID != "" {
+		b.WriteString("\n")
+	}
+	if reason != "" {
+		b.WriteString("Reason: " + reason + "\n")
+	}
+	if requestID != "" {
+		b.WriteString("Request-Id: " + requestID + "\n")
+	}
+	return b.String()
+}
+
+// Pull 将仓库中上次拉取之后的外部提交（提交者不是本服务）导回为参数变更：
+// 逐个提交读取修改过的 vnfs/<id>/values.yaml，与当前取值不同的参数以 git:<作者邮箱> 的名义批量修改。
+// 提交作者未经验证，因此按 operator 检查参数权限；校验、审批策略与机密参数加密同批量修改接口。覆盖层文件不导回。
+// 首次拉取只处理最新提交；失败的修改记录在结果中，不会在下次拉取时重试
+func (s *GitOpsService) Pull(ctx context.Context) (*PullResult, error) {
+	repo := gitops.Default()
+	if repo == nil {
+		return nil, gitops.ErrDisabled
+	}
+	if err := repo.Update(ctx); err != nil {
+		return nil, err
+	}
+	head, err := repo.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &PullResult{From: repo.Pulled(ctx), To: head, Commits: []PulledCommit{}}
+	if head == "" || head == result.From {
+		return result, nil
+	}
+	var commits []gitops.Commit
+	if result.From != "" {
+		// 上次的位置已不在历史中（如远端被改写）时返回错误，不跳过其间的提交
+		if commits, err = repo.Commits(ctx, result.From, gitopsRoot); err != nil {
+			return nil, fmt.Errorf("读取上次拉取位置 %s 之后的提交失败: %v", result.From, err)
+		}
+	} else {
+		// 首次拉取只处理最新一个修改了配置的提交
+		if commits, err = repo.Commits(ctx, "", gitopsRoot); err != nil {
+			return nil, err
+		}
+		if len(commits) > 1 {
+			commits = commits[len(commits)-1:]
+		}
+	}
+	definitions := NewDefinitionService(s.repos)
+	for _, c := range commits {
+		if c.Committer.Email == repo.Committer().Email {
+			continue
+		}
+		pulled := PulledCommit{Hash: c.Hash, Author: c.Author, Subject: c.Subject, Applied: []PulledChange{}, Failed: []PulledChange{}}
+		identity := &auth.Identity{Subject: "git:" + c.Author.Email, Roles: []string{auth.RoleOperator}, Method: auth.MethodGit}
+		commitCtx := audit.WithReason(auth.WithIdentity(ctx, identity), fmt.Sprintf("git %.12s: %s", c.Hash, c.Subject))
+		for _, file := range c.Files {
+			m := gitopsValuesPath.FindStringSubmatch(file)
+			if m == nil {
+				continue
+			}
+			id, _ := strconv.ParseUint(m[1], 10, 64)
+			change := PulledChange{VNFID: uint(id)}
+			values, err := s.pulledValues(ctx, repo, c.Hash, file, uint(id))
+			if err == nil {
+				var views []DefinitionView
+				views, err = definitions.BulkUpdate(commitCtx, uint(id), dto.DefinitionBulkUpdateRequest{Values: values})
+				for _, v := range views {
+					change.Parameters = append(change.Parameters, v.ParameterName)
+				}
+			}
+			if err != nil {
+				change.Error = err.Error()
+				var invalid *ValidationError
+				if errors.As(err, &invalid) {
+					change.Fields = invalid.Fields
+				}
+				pulled.Failed = append(pulled.Failed, change)
+				continue
+			}
+			if len(change.Parameters) > 0 {
+				pulled.Applied = append(pulled.Applied, change)
+			}
+		}
+		result.Commits = append(result.Commits, pulled)
+	}
+	if err := repo.SetPulled(ctx, head); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pulledValues 读取提交中的基础取值文件，转换为批量修改的取值；机密参数的密文解密为明文。
+// 只返回与当前取值不同的参数（原样保留的密文解密后与当前明文相同），未修改的参数不检查修改权限
+func (s *GitOpsService) pulledValues(ctx context.Context, repo *gitops.Repo, rev, file string, vnfID uint) (map[string]interface{}, error) {
+	data, ok, err := repo.Show(ctx, rev, file)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if !ok {
+		return values, nil
+	}
+	var config RenderedConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s 无法解析: %v", file, err)
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]string, len(defs))
+	for _, def := range defs {
+		plain, err := openDefinition(def)
+		if err != nil {
+			return nil, err
+		}
+		current[def.ParameterName] = plain.CurrentValue
+	}
+	for name, v := range config.Parameters {
+		switch x := v.(type) {
+		case nil:
+			v = ""
+		case int:
+			v = float64(x)
+		case int64:
+			v = float64(x)
+		case []interface{}, map[string]interface{}:
+			// 数组与对象按保存时的JSON形式比较
+			b, err := json.Marshal(x)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 无法转换: %v", name, err)
+			}
+			v = string(b)
+		case string:
+			plain, err := secrets.Default().Decrypt(secretOwner(vnfID, name), x)
+			if err != nil {
+				return nil, fmt.Errorf("参数 %s 无法解密: %v", name, err)
+			}
+			v = plain
+		}
+		if prev, ok := current[name]; ok {
+			if value, ok := formatValue(v); ok && value == prev {
+				continue
+			}
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// RenderAll 同步渲染全部VNF并以一次提交写入仓库（如主密钥轮换后以新主密钥的密文替换仓库中的旧密文），
+// 没有改动时返回空的 hash；未启用时返回 gitops.ErrDisabled
+func (s *GitOpsService) RenderAll(ctx context.Context, subject string) (string, error) {
+	repo := gitops.Default()
+	if repo == nil {
+		return "", gitops.ErrDisabled
+	}
+	instances, err := s.store.Instances().ListAll(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+	author := repo.AuthorFor(auth.ActorFrom(ctx))
+	return repo.Commit(ctx, gitopsRoot, author, func(root string) (string, error) {
+		for _, inst := range instances {
+			if _, err := s.render(ctx, root, inst.ID); err != nil {
+				return "", err
+			}
+		}
+		var trailers string
+		if reason := audit.Reason(ctx); reason != "" {
+			trailers += "Reason: " + reason + "\n"
+		}
+		if requestID := audit.RequestID(ctx); requestID != "" {
+			trailers += "Request-Id: " + requestID + "\n"
+		}
+		if trailers != "" {
+			return subject + "\n\n" + trailers, nil
+		}
+		return subject + "\n", nil
+	})
+}
+
+// Ciphertexts 按主密钥统计仓库当前提交中渲染文件所含的密文；历史提交中的密文不统计
+func (s *GitOpsService) Ciphertexts(ctx context.Context) (map[string]int, error) {
+	repo := gitops.Default()
+	if repo == nil {
+		return nil, gitops.ErrDisabled
+	}
+	files, err := repo.Files(ctx, "HEAD", gitopsRoot)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, file := range files {
+		data, ok, err := repo.Show(ctx, "HEAD", file)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, token := range secrets.Find(string(data)) {
+			counts[secrets.KeyID(token)]++
+		}
+	}
+	return counts, nil
+}
+
+// GitOpsStatus GitOps同步状态
+type GitOpsStatus struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path,omitempty"`
+	Head    string `json:"head,omitempty"`
+	Pulled  string `json:"pulled,omitempty"`
+}
+
+// Status 查看仓库路径、当前提交与最近一次拉取处理到的提交
+func (s *GitOpsService) Status(ctx context.Context) (*GitOpsStatus, error) {
+	repo := gitops.Default()
+	if repo == nil {
+		return &GitOpsStatus{}, nil
+	}
+	head, err := repo.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GitOpsStatus{Enabled: true, Path: repo.Path(), Head: head, Pulled: repo.Pulled(ctx)}, nil
+}
+
+// RunPull 按固定间隔拉取外部提交，直到ctx取消
+func (s *GitOpsService) RunPull(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Pull(ctx)
+			if err != nil {
+				log.Printf("GitOps定时拉取失败: %v", err)
+				continue
+			}
+			if len(result.Commits) > 0 {
+				log.Printf("GitOps定时拉取完成: 导入提交 %d 个（%.12s..%.12s）", len(result.Commits), result.From, result.To)
+			}
+		}
+	}
+}
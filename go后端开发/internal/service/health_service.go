@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+)
+
+// 服务整体健康状态
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"    // 可选存储不可用，读接口降级、写入暂存发件箱
+	HealthUnavailable = "unavailable" // 必需存储不可用
+)
+
+// StoreHealth 单个存储的检查结果
+type StoreHealth struct {
+	Backend   string      `json:"backend"`
+	Required  bool        `json:"required"`
+	Connected bool        `json:"connected"`
+	LatencyMs int64       `json:"latencyMs"`
+	Error     string      `json:"error,omitempty"`
+	Stats     interface{} `json:"stats,omitempty"`
+}
+
+// HealthReport 就绪检查结果
+type HealthReport struct {
+	Status        string                 `json:"status"`
+	CheckedAt     time.Time              `json:"checkedAt"`
+	Stores        map[string]StoreHealth `json:"stores"`
+	OutboxPending int64                  `json:"outboxPending"`
+}
+
+// Ready 必需存储全部可用时服务可以接收流量
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthUnavailable
+}
+
+type HealthService struct {
+	repos *repository.Repositories
+}
+
+func NewHealthService(repos *repository.Repositories) *HealthService {
+	return &HealthService{repos: repos}
+}
+
+// Check 探测关系型存储与文档存储，关系型存储始终是必需的
+func (s *HealthService) Check(ctx context.Context) *HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	report := &HealthReport{
+		Status:    HealthOK,
+		CheckedAt: time.Now(),
+		Stores:    make(map[string]StoreHealth),
+	}
+
+	store := s.repos.Store
+	relational := probe(ctx, store.Backend(), true, store.Ping)
+	if relational.Connected {
+		relational.Stats = store.Stats()
+		if n, err := store.Outbox().CountByStatus(ctx, model.OutboxStatusPending); err == nil {
+			report.OutboxPending = n
+		}
+	}
+	report.Stores["relational"] = relational
+
+	documents := probe(ctx, s.repos.Documents.Backend(), s.repos.DocumentsRequired, s.repos.Documents.Ping)
+	report.Stores["documents"] = documents
+
+	for _, h := range report.Stores {
+		if h.Connected {
+			continue
+		}
+		if h.Required {
+			report.Status = HealthUnavailable
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+func probe(ctx context.Context, backend string, required bool, ping func(context.Context) error) StoreHealth {
+	start := time.Now()
+	err := ping(ctx)
+	h := StoreHealth{
+		Backend:   backend,
+		Required:  required,
+		Connected: err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		h.Error = err.Error()
+	}
+	return h
+}
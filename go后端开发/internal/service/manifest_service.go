@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/formats"
+)
+
+// 渲染的清单格式，此外还可按 formats 包支持的格式（json、toml、env、properties、yaml）输出参数文档
+const (
+	RenderFormatConfigMap = "configmap" // ConfigMap，机密参数另输出一个同名 Secret
+	RenderFormatSecret    = "secret"    // 全部参数输出为一个 Secret
+	RenderFormatKustomize = "kustomize" // kustomization.yaml，以 configMapGenerator / secretGenerator 生成
+)
+
+// 清单渲染相关错误
+var (
+	ErrRenderFormat = errors.New("format 须为 configmap、secret、kustomize、json、toml、env、properties 或 yaml")
+	ErrRenderOption = errors.New("清单参数无效")
+	ErrRenderDenied = errors.New("无权渲染以下参数（机密或屏蔽参数需要管理员权限）")
+)
+
+var (
+	dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	dnsLabelPattern     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	labelNamePattern    = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	configKeyInvalid    = regexp.MustCompile(`[^-._A-Za-z0-9]`)
+	nameInvalid         = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ManifestOptions 清单的格式、取值来源与元数据。Name 为空时由实例名称（及覆盖层名称）生成，
+// Namespace 为空时不写入，Labels 合并到默认标签之上
+type ManifestOptions struct {
+	Format    string
+	Overlay   string // 覆盖层名称，为空或 base 时为参数当前值
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	Reason    string // 清单包含机密参数时记入查看记录
+}
+
+// Manifest 渲染结果：Kubernetes清单的 Objects 为依次输出的YAML文档，参数文档已按格式输出到 Data
+type Manifest struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Objects     []interface{}
+	Data        []byte
+}
+
+// k8sObjectMeta Kubernetes对象的元数据
+type k8sObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// k8sDataObject ConfigMap 或 Secret
+type k8sDataObject struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sObjectMeta     `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// kustomization kustomize 的 kustomization.yaml
+type kustomization struct {
+	APIVersion         string               `yaml:"apiVersion"`
+	Kind               string               `yaml:"kind"`
+	Namespace          string               `yaml:"namespace,omitempty"`
+	ConfigMapGenerator []kustomizeGenerator `yaml:"configMapGenerator,omitempty"`
+	SecretGenerator    []kustomizeGenerator `yaml:"secretGenerator,omitempty"`
+}
+
+type kustomizeGenerator struct {
+	Name     string                 `yaml:"name"`
+	Type     string                 `yaml:"type,omitempty"`
+	Literals []string               `yaml:"literals"`
+	Options  kustomizeGeneratorOpts `yaml:"options"`
+}
+
+type kustomizeGeneratorOpts struct {
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// ManifestService 将VNF参数的当前值或覆盖层的生效取值渲染为可直接应用的Kubernetes清单
+type ManifestService struct {
+	store    repository.Store
+	overlays *OverlayService
+}
+
+func NewManifestService(repos *repository.Repositories) *ManifestService {
+	return &ManifestService{store: repos.Store, overlays: NewOverlayService(repos)}
+}
+
+// Render 渲染清单或参数文档。输出须包含全部参数，调用方无权查看任一参数时返回 ErrRenderDenied；
+// 机密与屏蔽参数对管理员以明文写入，并像查看明文一样逐个记入变更历史。其他调用方渲染参数文档时
+// 这些参数以 MaskedValue 输出，渲染清单（将被部署）时返回 ErrRenderDenied。
+// 参数文档按参数名中的 . 嵌套，取值按参数类型输出，无法以该格式表示时返回 formats.ErrUnrepresentable；
+// 有子参数的对象参数（如 database_config）在参数文档与清单中都只输出子参数
+func (s *ManifestService) Render(ctx context.Context, vnfID uint, opts ManifestOptions) (*Manifest, error) {
+	kube := opts.Format == RenderFormatConfigMap || opts.Format == RenderFormatSecret || opts.Format == RenderFormatKustomize
+	if !kube && !formats.Valid(opts.Format) {
+		return nil, ErrRenderFormat
+	}
+	if opts.Overlay == "" {
+		opts.Overlay = BaseOverlay
+	}
+	instance, err := s.store.Instances().Get(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	oc, err := s.overlays.loadPolicy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	values, _, err := oc.effective(opts.Overlay)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := manifestMeta(instance, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make(map[string]string, len(oc.defs))
+	secret := make(map[string]string)
+	keys := make(map[string]string, len(oc.defs))
+	children := childParameters(oc.defs)
+	admin := oc.caller.HasRole(auth.RoleAdmin)
+	var denied []string
+	var revealed []model.VNFDefinition
+	for _, def := range oc.defs {
+		// 只输出子参数的对象参数不需要查看权限
+		if _, ok := children[def.ParameterName]; ok && (def.Type == "object" || values[def.ParameterName] == "") {
+			continue
+		}
+		access := oc.access(def.ParameterName)
+		if !access.CanView || (access.Masked && !admin && kube) {
+			denied = append(denied, def.ParameterName)
+			continue
+		}
+		if access.Masked && !admin {
+			values[def.ParameterName] = MaskedValue
+			continue
+		}
+		if access.Masked {
+			revealed = append(revealed, def)
+		}
+		if !kube {
+			continue
+		}
+		key := configKeyInvalid.ReplaceAllString(def.ParameterName, "_")
+		if other, ok := keys[key]; ok {
+			return nil, fmt.Errorf("%w: 参数 %s 与 %s 转换后的键同为 %s", ErrRenderOption, other, def.ParameterName, key)
+		}
+		keys[key] = def.ParameterName
+		if def.Type == TypeSecret || opts.Format == RenderFormatSecret {
+			secret[key] = values[def.ParameterName]
+		} else {
+			plain[key] = values[def.ParameterName]
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return nil, fmt.Errorf("%w: %s", ErrRenderDenied, strings.Join(denied, ", "))
+	}
+
+	manifest := &Manifest{Name: meta.Name, FileName: meta.Name + "-" + opts.Format + ".yaml", ContentType: formats.ContentType(formats.YAML)}
+	if !kube {
+		doc, err := parameterDocument(oc.defs, values)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.Data, err = formats.Marshal(opts.Format, doc); err != nil {
+			return nil, err
+		}
+		manifest.FileName, manifest.ContentType = meta.Name+"."+opts.Format, formats.ContentType(opts.Format)
+	}
+
+	reason := strings.TrimSpace(opts.Reason)
+	if reason == "" {
+		reason = fmt.Sprintf("渲染配置（%s，%s）", opts.Format, opts.Overlay)
+	}
+	for _, def := range revealed {
+		record := &model.ChangeRecord{
+			VNFID:         vnfID,
+			EntityType:    "definition",
+			EntityID:      def.ID,
+			ParameterName: def.ParameterName,
+			Action:        model.ChangeActionReveal,
+			Reason:        reason,
+		}
+		if err := appendChange(ctx, s.store, record, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if !kube {
+		return manifest, nil
+	}
+	if opts.Format == RenderFormatKustomize {
+		manifest.FileName = "kustomization.yaml"
+		manifest.Objects = append(manifest.Objects, renderKustomization(meta, plain, secret))
+		return manifest, nil
+	}
+	if len(plain) > 0 || len(secret) == 0 {
+		manifest.Objects = append(manifest.Objects, k8sDataObject{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta, Data: plain})
+	}
+	if len(secret) > 0 {
+		data := make(map[string]string, len(secret))
+		for key, v := range secret {
+			data[key] = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		manifest.Objects = append(manifest.Objects, k8sDataObject{APIVersion: "v1", Kind: "Secret", Metadata: meta, Type: "Opaque", Data: data})
+	}
+	return manifest, nil
+}
+
+// parameterDocument 按参数名中的 . 将参数嵌套为文档；对象参数（如 database_config）有子参数时只输出子参数，
+// 其余既有取值又有子参数的参数无法嵌套
+func parameterDocument(defs []model.VNFDefinition, values map[string]string) (map[string]interface{}, error) {
+	types := make(map[string]string, len(defs))
+	children := childParameters(defs)
+	names := make([]string, 0, len(defs))
+	for _, def := range defs {
+		types[def.ParameterName] = def.Type
+		names = append(names, def.ParameterName)
+	}
+	typed := typedValues(values, types)
+	sort.Strings(names)
+	doc := make(map[string]interface{})
+	for _, name := range names {
+		if child, ok := children[name]; ok {
+			if types[name] == "object" || values[name] == "" {
+				continue
+			}
+			return nil, fmt.Errorf("%w: 参数 %s 既有取值又有子参数 %s", formats.ErrUnrepresentable, name, child)
+		}
+		node := doc
+		parts := strings.Split(name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = typed[name]
+	}
+	return doc, nil
+}
+
+// childParameters 按参数名中的 . 找出有子参数的参数，返回 上级名称 → 任一子参数
+func childParameters(defs []model.VNFDefinition) map[string]string {
+	children := make(map[string]string)
+	for _, def := range defs {
+		for i := 0; i < len(def.ParameterName); i++ {
+			if def.ParameterName[i] == '.' {
+				children[def.ParameterName[:i]] = def.ParameterName
+			}
+		}
+	}
+	return children
+}
+
+// renderKustomization 以生成器输出，生成的对象名称带内容哈希后缀，取值变化时引用它的工作负载会滚动更新
+func renderKustomization(meta k8sObjectMeta, plain, secret map[string]string) kustomization {
+	k := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization", Namespace: meta.Namespace}
+	opts := kustomizeGeneratorOpts{Labels: meta.Labels, Annotations: meta.Annotations}
+	if len(plain) > 0 || len(secret) == 0 {
+		k.ConfigMapGenerator = []kustomizeGenerator{{Name: meta.Name, Literals: literals(plain), Options: opts}}
+	}
+	if len(secret) > 0 {
+		k.SecretGenerator = []kustomizeGenerator{{Name: meta.Name, Type: "Opaque", Literals: literals(secret), Options: opts}}
+	}
+	return k
+}
+
+func literals(values map[string]string) []string {
+	out := make([]string, 0, len(values))
+	for key, v := range values {
+		out = append(out, key+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// manifestMeta 校验并生成对象元数据：默认标签 app.kubernetes.io/name、app.kubernetes.io/managed-by，
+// 注解记录来源实例与覆盖层
+func manifestMeta(instance *model.VNFInstance, opts ManifestOptions) (k8sObjectMeta, error) {
+	name := opts.Name
+	if name == "" {
+		name = strings.Trim(nameInvalid.ReplaceAllString(strings.ToLower(instance.Name), "-"), "-")
+		if name == "" {
+			name = "vnf-" + strconv.FormatUint(uint64(instance.ID), 10)
+		}
+		if opts.Overlay != BaseOverlay {
+			name += "-" + strings.Trim(nameInvalid.ReplaceAllString(strings.ToLower(opts.Overlay), "-"), "-")
+		}
+		if len(name) > 253 {
+			name = strings.TrimRight(name[:253], "-.")
+		}
+	}
+	if len(name) > 253 || !dnsSubdomainPattern.MatchString(name) {
+		return k8sObjectMeta{}, fmt.Errorf("%w: name %q 须为小写字母、数字、- 与 . 组成的DNS子域名", ErrRenderOption, name)
+	}
+	if opts.Namespace != "" && (len(opts.Namespace) > 63 || !dnsLabelPattern.MatchString(opts.Namespace)) {
+		return k8sObjectMeta{}, fmt.Errorf("%w: namespace %q 须为小写字母、数字与 - 组成（最长63个字符）", ErrRenderOption, opts.Namespace)
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/managed-by": "vnf-config",
+	}
+	if len(name) > 63 {
+		delete(labels, "app.kubernetes.io/name")
+	}
+	for key, value := range opts.Labels {
+		if !validLabelKey(key) || (value != "" && (len(value) > 63 || !labelNamePattern.MatchString(value))) {
+			return k8sObjectMeta{}, fmt.Errorf("%w: 标签 %s=%s 不符合Kubernetes标签规则", ErrRenderOption, key, value)
+		}
+		labels[key] = value
+	}
+	annotations := map[string]string{"vnf-config/vnf-id": strconv.FormatUint(uint64(instance.ID), 10)}
+	if opts.Overlay != BaseOverlay {
+		annotations["vnf-config/overlay"] = opts.Overlay
+	}
+	return k8sObjectMeta{Name: name, Namespace: opts.Namespace, Labels: labels, Annotations: annotations}, nil
+}
+
+// validLabelKey 标签键为 [前缀/]名称，前缀为DNS子域名，名称最长63个字符
+func validLabelKey(key string) bool {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		if len(prefix) > 253 || !dnsSubdomainPattern.MatchString(prefix) {
+			return false
+		}
+		name = key[i+1:]
+	}
+	return len(name) <= 63 && labelNamePattern.MatchString(name)
+}
+
+// ParseLabels 解析 k1=v1,k2=v2 形式的标签参数
+func ParseLabels(raw []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range raw {
+		for _, pair := range strings.Split(item, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("%w: 标签 %q 须为 key=value", ErrRenderOption, pair)
+			}
+			labels[key] = value
+		}
+	}
+	return labels, nil
+}
+
+// WriteManifest 输出渲染结果，Kubernetes清单为多文档YAML
+func WriteManifest(manifest *Manifest, w io.Writer) error {
+	if manifest.Data != nil {
+		_, err := w.Write(manifest.Data)
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, obj := range manifest.Objects {
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
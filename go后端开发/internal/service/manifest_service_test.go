@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vnf-config/internal/model"
+	"vnf-shared/formats"
+)
+
+// TestParameterDocumentRoundTrip 渲染的参数文档以各格式输出后读回，与渲染前的文档相同
+func TestParameterDocumentRoundTrip(t *testing.T) {
+	params := []struct{ name, typ, value string }{
+		{"server_name", "string", "vnf-server-01"},
+		{"port", "number", "8080"},
+		{"ratio", "number", "0.5"},
+		{"enabled", "boolean", "true"},
+		{"backup_schedule", "string", "0 2 * * *"},
+		{"email", "string", ""},
+		{"version", "string", "8080"},
+		{"flag_text", "string", "false"},
+		{"allowed_ips", "array", `["127.0.0.1","192.168.1.0/24"]`},
+		{"ports", "array", `[80, 443]`},
+		{"labels", "object", `{"team":"core","tier":1}`},
+		{"legacy_list", "array", "[127.0.0.1 192.168.1.0/24]"},
+		{"database_config", "object", ""},
+		{"database_config.host", "string", "localhost"},
+		{"database_config.port", "number", "3306"},
+		{"database_config.password", TypeSecret, "p@ss w0rd#1 $HOME"},
+	}
+	defs := make([]model.VNFDefinition, 0, len(params))
+	values := make(map[string]string, len(params))
+	for _, p := range params {
+		defs = append(defs, model.VNFDefinition{ParameterName: p.name, Type: p.typ})
+		values[p.name] = p.value
+	}
+	doc, err := parameterDocument(defs, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["allowed_ips"].([]interface{}); !ok {
+		t.Fatalf("数组参数应输出为列表，得到 %#v", doc["allowed_ips"])
+	}
+	if _, ok := doc["labels"].(map[string]interface{}); !ok {
+		t.Fatalf("对象参数应输出为映射，得到 %#v", doc["labels"])
+	}
+	if _, ok := doc["database_config"].(map[string]interface{})["host"]; !ok {
+		t.Fatalf("有子参数的对象参数应只输出子参数，得到 %#v", doc["database_config"])
+	}
+	want := canonical(t, doc)
+	for _, format := range []string{formats.JSON, formats.TOML, formats.Env, formats.Properties, formats.YAML} {
+		data, err := formats.Marshal(format, doc)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		back, err := formats.Parse(format, data)
+		if err != nil {
+			t.Fatalf("%s 读回失败: %v\n%s", format, err, data)
+		}
+		if got := canonical(t, back); got != want {
+			t.Errorf("%s 读回的文档不同\n渲染: %s\n读回: %s", format, want, got)
+		}
+	}
+}
+
+// canonical 以JSON比较文档，忽略 int 与 int64 等数字类型的差异
+func canonical(t *testing.T, doc map[string]interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
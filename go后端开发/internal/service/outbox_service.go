@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"vnf-config/internal/infra/metrics"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+)
+
+// outboxMu 保证同一进程内发件箱按顺序派发
+var outboxMu sync.Mutex
+
+// OutboxService 文档存储发件箱服务：关系型事务内记录操作，后台派发到文档存储
+type OutboxService struct {
+	store       repository.Store
+	documents   repository.DocumentRepository
+	maxAttempts int
+	batchSize   int
+	retention   time.Duration
+	lastPrune   time.Time
+}
+
+func NewOutboxService(repos *repository.Repositories) *OutboxService {
+	maxAttempts, _ := strconv.Atoi(os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	// 已成功的记录保留 OUTBOX_RETENTION 后清理，0 表示不清理
+	retention := 7 * 24 * time.Hour
+	if v := os.Getenv("OUTBOX_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			retention = d
+		}
+	}
+	return &OutboxService{
+		store:       repos.Store,
+		documents:   repos.Documents,
+		maxAttempts: maxAttempts,
+		batchSize:   200,
+		retention:   retention,
+	}
+}
+
+// OutboxStatus 发件箱状态
+type OutboxStatus struct {
+	Pending   int64               `json:"pending"`
+	Failed    int64               `json:"failed"`
+	Applied   int64               `json:"applied"`
+	Discarded int64               `json:"discarded"`
+	Items     []model.MongoOutbox `json:"items"`
+}
+
+// EnqueueUpsert 在事务tx中记录一次按filter整体替换（不存在则插入）的操作
+func (s *OutboxService) EnqueueUpsert(ctx context.Context, tx repository.Store, collection string, filter bson.M, doc interface{}, version time.Time) (*model.MongoOutbox, error) {
+	docJSON, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("序列化MongoDB文档失败: %v", err)
+	}
+	return s.enqueue(ctx, tx, collection, model.OutboxOpUpsert, filter, string(docJSON), version)
+}
+
+// EnqueueDelete 在事务tx中记录一次按filter删除的操作
+func (s *OutboxService) EnqueueDelete(ctx context.Context, tx repository.Store, collection string, filter bson.M, version time.Time) (*model.MongoOutbox, error) {
+	return s.enqueue(ctx, tx, collection, model.OutboxOpDelete, filter, "", version)
+}
+
+func (s *OutboxService) enqueue(ctx context.Context, tx repository.Store, collection, op string, filter bson.M, doc string, version time.Time) (*model.MongoOutbox, error) {
+	filterJSON, err := bson.MarshalExtJSON(filter, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("序列化MongoDB过滤条件失败: %v", err)
+	}
+	entry := &model.MongoOutbox{
+		IdempotencyKey: idempotencyKey(collection, op, filterJSON, version),
+		Collection:     collection,
+		OrderingKey:    orderingKey(collection, filter, filterJSON),
+		Operation:      op,
+		Filter:         string(filterJSON),
+		Document:       doc,
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	}
+	// 同一版本的同一操作只记录一次
+	if existing, err := tx.Outbox().FindByIdempotencyKey(ctx, entry.IdempotencyKey); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if err := tx.Outbox().Create(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// idempotencyKey 由集合、操作、过滤条件和数据版本确定
+func idempotencyKey(collection, op string, filterJSON []byte, version time.Time) string {
+	return fmt.Sprintf("%s:%s:%x:%d", collection, op, sha1.Sum(filterJSON), version.UnixNano())
+}
+
+// orderingKey 决定哪些操作必须按顺序执行：同一VNF的所有操作（含实例与定义）共用一个顺序，
+// 以免实例删除先于尚未成功的定义写入执行而留下孤立文档
+func orderingKey(collection string, filter bson.M, filterJSON []byte) string {
+	if vnfID, ok := filter["vnf_id"]; ok {
+		return fmt.Sprintf("vnf:%v", vnfID)
+	}
+	return fmt.Sprintf("%s:%x", collection, sha1.Sum(filterJSON))
+}
+
+// PendingCount 统计某VNF尚未派发的操作数
+func (s *OutboxService) PendingCount(ctx context.Context, vnfID uint) (int64, error) {
+	return s.store.Outbox().CountOutstanding(ctx, vnfOrderingKey(vnfID))
+}
+
+// vnfOrderingKey 某VNF所有操作共用的顺序键
+func vnfOrderingKey(vnfID uint) string {
+	return fmt.Sprintf("vnf:%d", vnfID)
+}
+
+// FailedKeys 返回被已放弃操作阻塞的顺序键
+func (s *OutboxService) FailedKeys(ctx context.Context) (map[string]bool, error) {
+	failedEntries, err := s.store.Outbox().ListFailed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(failedEntries))
+	for i := range failedEntries {
+		keys[dispatchKey(&failedEntries[i])] = true
+	}
+	return keys, nil
+}
+
+// Flush 立即派发待执行操作，并返回指定操作中仍未成功的错误
+func (s *OutboxService) Flush(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, _, err := s.DispatchPending(ctx); err != nil {
+		return err
+	}
+	outstanding, err := s.store.Outbox().ListOutstanding(ctx, ids, 0)
+	if err != nil {
+		return err
+	}
+	if len(outstanding) > 0 {
+		return fmt.Errorf("%d个MongoDB操作待重试: %s", len(outstanding), outstanding[0].LastError)
+	}
+	return nil
+}
+
+// DispatchPending 按记录顺序分批派发待执行操作。
+// 同一顺序键（同一VNF）的操作严格按顺序执行，前一操作未成功时后续操作顺延；
+// 已放弃的操作同样阻塞其顺序键，直到通过 Retry 重新执行成功或通过 Discard 丢弃。
+// 被阻塞的记录在查询时排除，不会占满批次而使其他VNF的操作无法派发。
+func (s *OutboxService) DispatchPending(ctx context.Context) (applied int, failed int, err error) {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	blocked, err := s.FailedKeys(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	var afterID uint
+	for {
+		entries, err := s.store.Outbox().ListPending(ctx, afterID, s.batchSize)
+		if err != nil {
+			return applied, failed, err
+		}
+		for i := range entries {
+			entry := &entries[i]
+			afterID = entry.ID
+			docKey := dispatchKey(entry)
+			if blocked[docKey] {
+				continue
+			}
+			if entry.NextAttemptAt.After(now) {
+				blocked[docKey] = true
+				continue
+			}
+
+			if applyErr := s.apply(ctx, entry); applyErr != nil {
+				blocked[docKey] = true
+				failed++
+				s.markFailed(ctx, entry, applyErr)
+				continue
+			}
+			applied++
+			s.markApplied(ctx, entry)
+		}
+		if len(entries) < s.batchSize || ctx.Err() != nil {
+			return applied, failed, nil
+		}
+	}
+}
+
+// dispatchKey 派发时的顺序键，早期记录没有顺序键时按集合与过滤条件区分
+func dispatchKey(entry *model.MongoOutbox) string {
+	if entry.OrderingKey != "" {
+		return entry.OrderingKey
+	}
+	return entry.Collection + "|" + entry.Filter
+}
+
+// apply 执行单个发件箱操作。整体替换与按条件删除均可安全重复执行。
+func (s *OutboxService) apply(ctx context.Context, entry *model.MongoOutbox) error {
+	var filter bson.M
+	if err := bson.UnmarshalExtJSON([]byte(entry.Filter), true, &filter); err != nil {
+		return fmt.Errorf("解析过滤条件失败: %v", err)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	switch entry.Operation {
+	case model.OutboxOpUpsert:
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(entry.Document), true, &doc); err != nil {
+			return fmt.Errorf("解析文档失败: %v", err)
+		}
+		err := s.documents.Replace(opCtx, entry.Collection, filter, doc)
+		metrics.ObserveStorage(s.documents.Backend(), "replace_"+entry.Collection, start, err)
+		return err
+	case model.OutboxOpDelete:
+		err := s.documents.Delete(opCtx, entry.Collection, filter)
+		metrics.ObserveStorage(s.documents.Backend(), "delete_"+entry.Collection, start, err)
+		return err
+	default:
+		return fmt.Errorf("未知的发件箱操作: %s", entry.Operation)
+	}
+}
+
+func (s *OutboxService) markApplied(ctx context.Context, entry *model.MongoOutbox) {
+	now := time.Now()
+	err := s.store.Outbox().Update(ctx, entry.ID, map[string]interface{}{
+		"status":     model.OutboxStatusApplied,
+		"attempts":   entry.Attempts + 1,
+		"last_error": "",
+		"applied_at": &now,
+	})
+	if err != nil {
+		log.Printf("更新发件箱记录 %d 状态失败: %v", entry.ID, err)
+	}
+	metrics.OutboxDispatched("applied")
+}
+
+func (s *OutboxService) markFailed(ctx context.Context, entry *model.MongoOutbox, applyErr error) {
+	attempts := entry.Attempts + 1
+	status := model.OutboxStatusPending
+	if attempts >= s.maxAttempts {
+		status = model.OutboxStatusFailed
+	}
+	if status == model.OutboxStatusFailed {
+		metrics.OutboxDispatched("failed")
+	} else {
+		metrics.OutboxDispatched("retry")
+	}
+	msg := applyErr.Error()
+	if len(msg) > 1024 {
+		msg = msg[:1024]
+	}
+	err := s.store.Outbox().Update(ctx, entry.ID, map[string]interface{}{
+		"status":          status,
+		"attempts":        attempts,
+		"last_error":      msg,
+		"next_attempt_at": time.Now().Add(outboxBackoff(attempts)),
+	})
+	if err != nil {
+		log.Printf("更新发件箱记录 %d 状态失败: %v", entry.ID, err)
+	}
+	log.Printf("发件箱操作 %d (%s %s) 第%d次执行失败: %v", entry.ID, entry.Operation, entry.Collection, attempts, applyErr)
+}
+
+// outboxBackoff 指数退避，最长5分钟
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Second << uint(attempts)
+	if d <= 0 || d > 5*time.Minute {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// Run 后台周期性派发，直到ctx取消
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			applied, failed, err := s.DispatchPending(ctx)
+			if err != nil {
+				log.Printf("发件箱派发失败: %v", err)
+			} else if applied > 0 || failed > 0 {
+				log.Printf("发件箱派发完成: 成功 %d, 失败 %d", applied, failed)
+			}
+			s.prune(ctx)
+		}
+	}
+}
+
+// prune 每小时清理一次超过保留期的已成功记录
+func (s *OutboxService) prune(ctx context.Context) {
+	if s.retention <= 0 || time.Since(s.lastPrune) < time.Hour {
+		return
+	}
+	s.lastPrune = time.Now()
+	n, err := s.store.Outbox().DeleteApplied(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		log.Printf("清理发件箱记录失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已清理 %d 条已成功的发件箱记录", n)
+	}
+}
+
+// Status 统计发件箱状态并列出未完成的操作
+func (s *OutboxService) Status(ctx context.Context, limit int) (*OutboxStatus, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	status := &OutboxStatus{}
+	counts := []struct {
+		state string
+		dest  *int64
+	}{
+		{model.OutboxStatusPending, &status.Pending},
+		{model.OutboxStatusFailed, &status.Failed},
+		{model.OutboxStatusApplied, &status.Applied},
+		{model.OutboxStatusDiscarded, &status.Discarded},
+	}
+	for _, c := range counts {
+		count, err := s.store.Outbox().CountByStatus(ctx, c.state)
+		if err != nil {
+			return nil, err
+		}
+		*c.dest = count
+	}
+	items, err := s.store.Outbox().ListOutstanding(ctx, nil, limit)
+	if err != nil {
+		return nil, err
+	}
+	status.Items = items
+	return status, nil
+}
+
+// Retry 将已放弃的操作重新置为待执行。同一顺序键的后续操作在其成功后才会继续派发。
+func (s *OutboxService) Retry(ctx context.Context, id uint) error {
+	found, err := s.store.Outbox().ResetFailed(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("未找到已失败的发件箱记录")
+	}
+	return nil
+}
+
+// Discard 丢弃已放弃的操作，同一顺序键的后续操作随即继续派发。
+// 被丢弃的写入不再同步到文档存储，由此产生的差异可通过对账修复。
+func (s *OutboxService) Discard(ctx context.Context, id uint) error {
+	found, err := s.store.Outbox().DiscardFailed(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("未找到已失败的发件箱记录")
+	}
+	log.Printf("发件箱操作 %d 已被 %s 丢弃", id, auth.ActorFrom(ctx))
+	return nil
+}
@@ -0,0 +1,678 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
+)
+
+// BaseOverlay 生效取值与差异比较中表示VNF的基础取值（参数定义的当前值）
+const BaseOverlay = "base"
+
+// maxOverlayDepth 覆盖层继承的最大层数
+const maxOverlayDepth = 16
+
+// 覆盖层相关错误
+var (
+	ErrOverlayName   = errors.New("覆盖层名称只能包含字母、数字、-、_（最长64个字符），且不能为 base 或 diff")
+	ErrOverlayExists = errors.New("同名覆盖层已存在")
+	ErrOverlayInUse  = errors.New("覆盖层被其他覆盖层继承，不能删除")
+	ErrOverlayCycle  = errors.New("覆盖层的继承关系不能成环，且不能超过16层")
+	ErrOverlayLocked = errors.New("覆盖层包含不可更新或须经变更单审批的参数，不能删除")
+)
+
+var overlayNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// OverlayView 覆盖层及其取值，调用方无权查看的参数不输出，屏蔽参数的取值以 MaskedValue 输出
+type OverlayView struct {
+	model.Overlay
+	Parent string            `json:"parent,omitempty"`
+	Values map[string]string `json:"values"`
+}
+
+// EffectiveValue 参数在覆盖层中的生效取值，Source 为取值来源（base 或覆盖层名称）
+type EffectiveValue struct {
+	ParameterName string `json:"parameterName"`
+	Group         string `json:"group"`
+	Type          string `json:"type"`
+	Value         string `json:"value"`
+	Source        string `json:"source"`
+	Masked        bool   `json:"masked,omitempty"`
+}
+
+// OverlayDiffItem 参数在两个覆盖层中生效取值的差异（屏蔽参数按明文比较，取值不输出）
+type OverlayDiffItem struct {
+	ParameterName string `json:"parameterName"`
+	Group         string `json:"group"`
+	FromValue     string `json:"fromValue"`
+	FromSource    string `json:"fromSource"`
+	ToValue       string `json:"toValue"`
+	ToSource      string `json:"toSource"`
+}
+
+// overlaySnapshot 覆盖层在变更历史中的快照（不含取值，取值按参数单独记录）
+type overlaySnapshot struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Parent      string `json:"parent,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// overlayValueSnapshot 覆盖层取值在变更历史中的快照，字段与定义快照一致以便按参数权限屏蔽
+type overlayValueSnapshot struct {
+	Overlay       string `json:"overlay"`
+	ParameterName string `json:"parameterName"`
+	Group         string `json:"group"`
+	Type          string `json:"type"`
+	CurrentValue  string `json:"currentValue"`
+}
+
+// OverlayService 环境覆盖层：按 基础 → 上级覆盖层 → 本层 计算参数的生效取值，并比较覆盖层之间的差异。
+// 覆盖层不修改参数定义的当前值，但会改变生效取值，因此与直接修改一样：
+// 不可更新的参数、以及审批策略要求经变更单修改的分组中的参数不能在覆盖层中设置、修改或移除
+type OverlayService struct {
+	store       repository.Store
+	permissions *PermissionService
+	gitops      *GitOpsService
+}
+
+func NewOverlayService(repos *repository.Repositories) *OverlayService {
+	return &OverlayService{store: repos.Store, permissions: NewPermissionService(repos), gitops: NewGitOpsService(repos)}
+}
+
+// overlayContext 一次请求中计算覆盖层所需的数据
+type overlayContext struct {
+	caller   *auth.Identity
+	policy   *PermissionPolicy
+	defs     []model.VNFDefinition // 明文
+	byName   map[string]int
+	overlays []model.Overlay
+	byID     map[uint]*model.Overlay
+	rules    *approvalRules
+}
+
+// load 读取VNF的定义与覆盖层。权限规则须在事务外读取后传入（嵌入式后端只有一个连接）
+func (s *OverlayService) load(ctx context.Context, store repository.Store, policy *PermissionPolicy, vnfID uint) (*overlayContext, error) {
+	if _, err := store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	defs, err := store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := store.Overlays().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := loadApprovalRules(ctx, store, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	oc := &overlayContext{
+		caller:   auth.FromContext(ctx),
+		policy:   policy,
+		defs:     make([]model.VNFDefinition, len(defs)),
+		byName:   make(map[string]int, len(defs)),
+		overlays: overlays,
+		byID:     make(map[uint]*model.Overlay, len(overlays)),
+		rules:    rules,
+	}
+	for i, def := range defs {
+		if oc.defs[i], err = openDefinition(def); err != nil {
+			return nil, err
+		}
+		oc.byName[def.ParameterName] = i
+	}
+	for i := range overlays {
+		oc.byID[overlays[i].ID] = &overlays[i]
+	}
+	return oc, nil
+}
+
+func (s *OverlayService) loadPolicy(ctx context.Context, vnfID uint) (*overlayContext, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	return s.load(ctx, s.store, policy, vnfID)
+}
+
+func (oc *overlayContext) find(name string) *model.Overlay {
+	for i := range oc.overlays {
+		if oc.overlays[i].Name == name {
+			return &oc.overlays[i]
+		}
+	}
+	return nil
+}
+
+func (oc *overlayContext) access(name string) ParameterAccess {
+	i, ok := oc.byName[name]
+	if !ok {
+		return ParameterAccess{}
+	}
+	access := oc.policy.Access(oc.caller, name, oc.defs[i].Group)
+	if oc.defs[i].Type == TypeSecret {
+		access.Masked = true
+	}
+	return access
+}
+
+// locked 参数 param（须存在）的取值能否在覆盖层中修改：不可更新或所在分组须经变更单审批时返回原因
+func (oc *overlayContext) locked(param string) string {
+	def := oc.defs[oc.byName[param]]
+	if !def.CanBeUpdated {
+		return "参数无法更新"
+	}
+	if oc.rules.enforced(def.Group) {
+		return ErrApprovalRequired.Error()
+	}
+	return ""
+}
+
+// chain 返回从最上级到 overlay 的继承链，成环或超过最大层数时返回 ErrOverlayCycle
+func (oc *overlayContext) chain(overlay *model.Overlay) ([]*model.Overlay, error) {
+	var chain []*model.Overlay
+	for o := overlay; o != nil; {
+		if len(chain) >= maxOverlayDepth {
+			return nil, ErrOverlayCycle
+		}
+		chain = append([]*model.Overlay{o}, chain...)
+		if o.ParentID == nil {
+			break
+		}
+		o = oc.byID[*o.ParentID]
+	}
+	return chain, nil
+}
+
+// effective 计算覆盖层的生效取值（明文）与来源，name 为 base 时为参数定义的当前值
+func (oc *overlayContext) effective(name string) (values, sources map[string]string, err error) {
+	if name == BaseOverlay {
+		return oc.effectiveOf(nil, nil)
+	}
+	overlay := oc.find(name)
+	if overlay == nil {
+		return nil, nil, fmt.Errorf("%w: 覆盖层 %s", repository.ErrNotFound, name)
+	}
+	return oc.effectiveOf(overlay, nil)
+}
+
+// effectiveOf 计算 overlay（nil 表示基础取值）的生效取值与来源；
+// override 不为 nil 时以其替换本层的取值，用于校验修改后的结果
+func (oc *overlayContext) effectiveOf(overlay *model.Overlay, override map[string]string) (values, sources map[string]string, err error) {
+	values = make(map[string]string, len(oc.defs))
+	sources = make(map[string]string, len(oc.defs))
+	for _, def := range oc.defs {
+		values[def.ParameterName], sources[def.ParameterName] = def.CurrentValue, BaseOverlay
+	}
+	if overlay == nil {
+		return values, sources, nil
+	}
+	chain, err := oc.chain(overlay)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, o := range chain {
+		layer := make(map[string]string, len(o.Values))
+		if o == overlay && override != nil {
+			layer = override
+		} else {
+			for _, v := range o.Values {
+				if layer[v.ParameterName], err = secrets.Default().Decrypt(secretOwner(o.VNFID, v.ParameterName), v.Value); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		for param, value := range layer {
+			if _, ok := oc.byName[param]; ok {
+				values[param], sources[param] = value, o.Name
+			}
+		}
+	}
+	return values, sources, nil
+}
+
+func (oc *overlayContext) view(overlay model.Overlay) (*OverlayView, error) {
+	view := &OverlayView{Overlay: overlay, Values: make(map[string]string, len(overlay.Values))}
+	if overlay.ParentID != nil {
+		if parent := oc.byID[*overlay.ParentID]; parent != nil {
+			view.Parent = parent.Name
+		}
+	}
+	for _, v := range overlay.Values {
+		access := oc.access(v.ParameterName)
+		if !access.CanView {
+			continue
+		}
+		if access.Masked {
+			view.Values[v.ParameterName] = maskValue(v.Value)
+			continue
+		}
+		value, err := secrets.Default().Decrypt(secretOwner(overlay.VNFID, v.ParameterName), v.Value)
+		if err != nil {
+			return nil, err
+		}
+		view.Values[v.ParameterName] = value
+	}
+	return view, nil
+}
+
+// List 列出VNF的全部覆盖层
+func (s *OverlayService) List(ctx context.Context, vnfID uint) ([]OverlayView, error) {
+	oc, err := s.loadPolicy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]OverlayView, 0, len(oc.overlays))
+	for _, overlay := range oc.overlays {
+		view, err := oc.view(overlay)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, nil
+}
+
+// Get 查看覆盖层本层的取值
+func (s *OverlayService) Get(ctx context.Context, vnfID uint, name string) (*OverlayView, error) {
+	oc, err := s.loadPolicy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	overlay := oc.find(name)
+	if overlay == nil {
+		return nil, repository.ErrNotFound
+	}
+	return oc.view(*overlay)
+}
+
+// Create 创建覆盖层，取值校验同批量修改，任一参数不通过时返回 *ValidationError
+func (s *OverlayService) Create(ctx context.Context, vnfID uint, req dto.OverlayRequest) (*OverlayView, error) {
+	if !overlayNamePattern.MatchString(req.Name) || req.Name == BaseOverlay || req.Name == "diff" {
+		return nil, ErrOverlayName
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = audit.WithReason(ctx, req.Reason)
+	var view *OverlayView
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		oc, err := s.load(ctx, tx, policy, vnfID)
+		if err != nil {
+			return err
+		}
+		if oc.find(req.Name) != nil {
+			return ErrOverlayExists
+		}
+		overlay := &model.Overlay{VNFID: vnfID, Name: req.Name}
+		view, err = s.save(ctx, tx, oc, overlay, req, true)
+		return err
+	})
+	if err == nil {
+		s.gitops.Publish(ctx, vnfID, model.ChangeActionCreate, []string{"overlays/" + req.Name})
+	}
+	return view, err
+}
+
+// Update 整体替换覆盖层的上级、说明与取值。调用方无权查看的参数保留原值，
+// 屏蔽参数原样回传 MaskedValue 时视为未修改
+func (s *OverlayService) Update(ctx context.Context, vnfID uint, name string, req dto.OverlayRequest) (*OverlayView, error) {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = audit.WithReason(ctx, req.Reason)
+	var view *OverlayView
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		oc, err := s.load(ctx, tx, policy, vnfID)
+		if err != nil {
+			return err
+		}
+		overlay := oc.find(name)
+		if overlay == nil {
+			return repository.ErrNotFound
+		}
+		view, err = s.save(ctx, tx, oc, overlay, req, false)
+		return err
+	})
+	if err == nil {
+		s.gitops.Publish(ctx, vnfID, model.ChangeActionUpdate, []string{"overlays/" + name})
+	}
+	return view, err
+}
+
+// save 校验并保存覆盖层 overlay（新建时尚无ID），记录覆盖层与各参数取值的变更历史
+func (s *OverlayService) save(ctx context.Context, tx repository.Store, oc *overlayContext, overlay *model.Overlay, req dto.OverlayRequest, create bool) (*OverlayView, error) {
+	before := *overlay
+	beforeSnap := oc.snapshot(before)
+
+	// 上级覆盖层与继承链
+	overlay.ParentID = nil
+	if req.Parent != "" && req.Parent != BaseOverlay {
+		parent := oc.find(req.Parent)
+		if parent == nil {
+			return nil, fmt.Errorf("%w: 上级覆盖层 %s", repository.ErrNotFound, req.Parent)
+		}
+		overlay.ParentID = &parent.ID
+		for o := parent; o != nil; {
+			if o.ID == overlay.ID {
+				return nil, ErrOverlayCycle
+			}
+			if o.ParentID == nil {
+				break
+			}
+			o = oc.byID[*o.ParentID]
+		}
+	}
+	overlay.Description = req.Description
+	if _, err := oc.chain(overlay); err != nil {
+		return nil, err
+	}
+
+	// 本层取值：从原有取值出发，按请求整体替换调用方可见的参数
+	old := make(map[string]string, len(before.Values))
+	stored := make(map[string]string, len(before.Values))
+	for _, v := range before.Values {
+		plain, err := secrets.Default().Decrypt(secretOwner(before.VNFID, v.ParameterName), v.Value)
+		if err != nil {
+			return nil, err
+		}
+		old[v.ParameterName], stored[v.ParameterName] = plain, v.Value
+	}
+	layer := make(map[string]string, len(req.Values))
+	for param, value := range old {
+		if !oc.access(param).CanView {
+			layer[param] = value
+		}
+	}
+	names := make([]string, 0, len(req.Values))
+	for param := range req.Values {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+	verr := &ValidationError{}
+	changed := make(map[string]bool)
+	for _, param := range names {
+		value, ok := formatValue(req.Values[param])
+		if !ok {
+			verr.add(param, "取值须为字符串、数字或布尔值")
+			continue
+		}
+		access := oc.access(param)
+		if !access.CanView {
+			verr.add(param, "参数不存在")
+			continue
+		}
+		prev, had := old[param]
+		if access.Masked && value == MaskedValue && had {
+			layer[param] = prev
+			continue
+		}
+		if had && value == prev {
+			layer[param] = value
+			continue
+		}
+		if !access.CanEdit {
+			verr.add(param, ErrPermissionDenied.Error())
+			continue
+		}
+		if msg := oc.locked(param); msg != "" {
+			verr.add(param, msg)
+			continue
+		}
+		if msg := validateValue(oc.defs[oc.byName[param]], value); msg != "" {
+			verr.add(param, msg)
+			continue
+		}
+		layer[param], changed[param] = value, true
+	}
+	for param := range old {
+		if _, kept := layer[param]; kept {
+			continue
+		}
+		// 从本层移除参数同样需要修改权限，且参数可以修改
+		if access := oc.access(param); !access.CanEdit {
+			verr.add(param, ErrPermissionDenied.Error())
+			layer[param] = old[param]
+			continue
+		}
+		if msg := oc.locked(param); msg != "" {
+			verr.add(param, msg)
+			layer[param] = old[param]
+			continue
+		}
+		changed[param] = true
+	}
+	values, _, err := oc.effectiveOf(overlay, layer)
+	if err != nil {
+		return nil, err
+	}
+	verr.checkRequired(oc.defs, values, changed)
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+
+	// 保存：未修改的取值沿用原密文
+	overlay.Values = make([]model.OverlayValue, 0, len(layer))
+	params := make([]string, 0, len(layer))
+	for param := range layer {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		value := layer[param]
+		if !changed[param] {
+			value = stored[param]
+		} else if oc.defs[oc.byName[param]].Type == TypeSecret {
+			if value, err = secrets.Default().Encrypt(secretOwner(overlay.VNFID, param), value); err != nil {
+				return nil, err
+			}
+		}
+		overlay.Values = append(overlay.Values, model.OverlayValue{ParameterName: param, Value: value})
+	}
+	if create {
+		values := overlay.Values
+		overlay.Values = nil
+		if err := tx.Overlays().Create(ctx, overlay); err != nil {
+			return nil, err
+		}
+		overlay.Values = values
+		oc.byID[overlay.ID] = overlay
+	} else if err := tx.Overlays().Update(ctx, overlay); err != nil {
+		return nil, err
+	}
+	if err := tx.Overlays().ReplaceValues(ctx, overlay.ID, overlay.Values); err != nil {
+		return nil, err
+	}
+
+	// 变更历史：覆盖层本身与每个修改的参数各一条
+	record := &model.ChangeRecord{VNFID: overlay.VNFID, EntityType: "overlay", EntityID: overlay.ID, Action: model.ChangeActionUpdate}
+	afterSnap := oc.snapshot(*overlay)
+	switch {
+	case create:
+		record.Action = model.ChangeActionCreate
+		err = appendChange(ctx, tx, record, nil, afterSnap)
+	case *beforeSnap != *afterSnap:
+		err = appendChange(ctx, tx, record, beforeSnap, afterSnap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := make(map[string]string, len(overlay.Values))
+	for _, v := range overlay.Values {
+		now[v.ParameterName] = v.Value
+	}
+	for _, param := range sortedKeys(changed) {
+		var beforeValue, afterValue interface{}
+		if v, ok := stored[param]; ok {
+			beforeValue = oc.valueSnapshot(overlay.Name, param, v)
+		}
+		if v, ok := now[param]; ok {
+			afterValue = oc.valueSnapshot(overlay.Name, param, v)
+		}
+		action := model.ChangeActionUpdate
+		switch {
+		case beforeValue == nil:
+			action = model.ChangeActionCreate
+		case afterValue == nil:
+			action = model.ChangeActionDelete
+		}
+		rec := &model.ChangeRecord{VNFID: overlay.VNFID, EntityType: "overlay_value", EntityID: overlay.ID, ParameterName: param, Action: action}
+		if err := appendChange(ctx, tx, rec, beforeValue, afterValue); err != nil {
+			return nil, err
+		}
+	}
+	return oc.view(*overlay)
+}
+
+func (oc *overlayContext) snapshot(overlay model.Overlay) *overlaySnapshot {
+	snap := &overlaySnapshot{ID: overlay.ID, Name: overlay.Name, Description: overlay.Description}
+	if overlay.ParentID != nil {
+		if parent := oc.byID[*overlay.ParentID]; parent != nil {
+			snap.Parent = parent.Name
+		}
+	}
+	return snap
+}
+
+func (oc *overlayContext) valueSnapshot(overlay, param, value string) *overlayValueSnapshot {
+	def := oc.defs[oc.byName[param]]
+	return &overlayValueSnapshot{Overlay: overlay, ParameterName: param, Group: def.Group, Type: def.Type, CurrentValue: value}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Delete 删除覆盖层；被其他覆盖层继承时返回 ErrOverlayInUse。
+// 本层包含调用方无权修改的参数时返回 ErrPermissionDenied，包含不可更新或须经变更单审批的参数时返回 ErrOverlayLocked
+func (s *OverlayService) Delete(ctx context.Context, vnfID uint, name string) error {
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return err
+	}
+	err = s.store.Transaction(ctx, func(tx repository.Store) error {
+		oc, err := s.load(ctx, tx, policy, vnfID)
+		if err != nil {
+			return err
+		}
+		overlay := oc.find(name)
+		if overlay == nil {
+			return repository.ErrNotFound
+		}
+		for _, o := range oc.overlays {
+			if o.ParentID != nil && *o.ParentID == overlay.ID {
+				return ErrOverlayInUse
+			}
+		}
+		for _, v := range overlay.Values {
+			access := oc.access(v.ParameterName)
+			if !access.CanView {
+				continue
+			}
+			if !access.CanEdit {
+				return fmt.Errorf("%w: %s", ErrPermissionDenied, v.ParameterName)
+			}
+			if msg := oc.locked(v.ParameterName); msg != "" {
+				return fmt.Errorf("%w: %s（%s）", ErrOverlayLocked, v.ParameterName, msg)
+			}
+		}
+		if err := tx.Overlays().Delete(ctx, vnfID, overlay.ID); err != nil {
+			return err
+		}
+		record := &model.ChangeRecord{VNFID: vnfID, EntityType: "overlay", EntityID: overlay.ID, Action: model.ChangeActionDelete}
+		return appendChange(ctx, tx, record, oc.snapshot(*overlay), nil)
+	})
+	if err == nil {
+		s.gitops.Publish(ctx, vnfID, model.ChangeActionDelete, []string{"overlays/" + name})
+	}
+	return err
+}
+
+// Effective 按 基础 → 上级覆盖层 → 本层 计算全部参数的生效取值及来源，name 为 base 时为基础取值
+func (s *OverlayService) Effective(ctx context.Context, vnfID uint, name string) ([]EffectiveValue, error) {
+	oc, err := s.loadPolicy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	values, sources, err := oc.effective(name)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]EffectiveValue, 0, len(oc.defs))
+	for _, def := range oc.defs {
+		access := oc.access(def.ParameterName)
+		if !access.CanView {
+			continue
+		}
+		item := EffectiveValue{
+			ParameterName: def.ParameterName,
+			Group:         def.Group,
+			Type:          def.Type,
+			Value:         values[def.ParameterName],
+			Source:        sources[def.ParameterName],
+			Masked:        access.Masked,
+		}
+		if access.Masked {
+			item.Value = maskValue(item.Value)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ParameterName < items[j].ParameterName })
+	return items, nil
+}
+
+// Diff 比较两个覆盖层（或 base）的生效取值，只返回取值不同的参数
+func (s *OverlayService) Diff(ctx context.Context, vnfID uint, from, to string) ([]OverlayDiffItem, error) {
+	oc, err := s.loadPolicy(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	fromValues, fromSources, err := oc.effective(from)
+	if err != nil {
+		return nil, err
+	}
+	toValues, toSources, err := oc.effective(to)
+	if err != nil {
+		return nil, err
+	}
+	items := []OverlayDiffItem{}
+	for _, def := range oc.defs {
+		name := def.ParameterName
+		access := oc.access(name)
+		if !access.CanView || fromValues[name] == toValues[name] {
+			continue
+		}
+		item := OverlayDiffItem{
+			ParameterName: name,
+			Group:         def.Group,
+			FromValue:     fromValues[name],
+			FromSource:    fromSources[name],
+			ToValue:       toValues[name],
+			ToSource:      toSources[name],
+		}
+		if access.Masked {
+			item.FromValue, item.ToValue = maskValue(item.FromValue), maskValue(item.ToValue)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ParameterName < items[j].ParameterName })
+	return items, nil
+}
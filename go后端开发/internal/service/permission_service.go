@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
+)
+
+// 未配置规则时的默认权限，与路由上的角色要求一致
+const (
+	DefaultViewRole = auth.RoleViewer
+	DefaultEditRole = auth.RoleOperator
+)
+
+// MaskedValue 屏蔽后输出的参数值
+const MaskedValue = "******"
+
+// ErrPermissionDenied 调用方无权修改该参数
+var ErrPermissionDenied = errors.New("无权修改该参数")
+
+// PermissionSpec 描述文件中为参数或分组声明的权限：
+//
+//	permissions:
+//	  view: operator   # 查看所需的最低角色
+//	  edit: admin      # 修改所需的最低角色
+//	  masked: true     # 输出时屏蔽取值
+type PermissionSpec struct {
+	View   string `json:"view,omitempty" yaml:"view"`
+	Edit   string `json:"edit,omitempty" yaml:"edit"`
+	Masked *bool  `json:"masked,omitempty" yaml:"masked"`
+}
+
+// ParameterAccess 参数的生效权限及调用方据此可执行的操作
+type ParameterAccess struct {
+	ViewRole string `json:"viewRole"`
+	EditRole string `json:"editRole"`
+	Masked   bool   `json:"masked"`
+	CanView  bool   `json:"canView"`
+	CanEdit  bool   `json:"canEdit"`
+}
+
+// DefinitionView 按调用方权限输出的参数定义，屏蔽的参数不返回真实取值
+type DefinitionView struct {
+	model.VNFDefinition
+	Access ParameterAccess `json:"access"`
+}
+
+// PermissionPolicy 某VNF的全部权限规则
+type PermissionPolicy struct {
+	parameters map[string]model.ParameterPermission
+	groups     map[string]model.ParameterPermission
+}
+
+// Empty 是否未配置任何规则（全部参数使用默认权限）
+func (p *PermissionPolicy) Empty() bool {
+	return len(p.parameters) == 0 && len(p.groups) == 0
+}
+
+// Access 计算参数对调用方的生效权限：默认值 < 分组规则 < 上级对象的规则 < 参数规则。
+// 对象参数（如 database_config）上的规则同样作用于其子属性（database_config.password）
+func (p *PermissionPolicy) Access(caller *auth.Identity, name, group string) ParameterAccess {
+	access := p.rules(name, group)
+	access.CanView = caller.HasRole(access.ViewRole)
+	access.CanEdit = access.CanView && caller.HasRole(access.EditRole)
+	return access
+}
+
+// Loosens 参数从分组 from 移入 to 后生效规则是否变得宽松：查看或修改所需的角色降低，或不再屏蔽取值
+func (p *PermissionPolicy) Loosens(name, from, to string) bool {
+	before, after := p.rules(name, from), p.rules(name, to)
+	lower := func(was, now string) bool {
+		return was != now && auth.HigherRole(now, was) == was
+	}
+	return lower(before.ViewRole, after.ViewRole) || lower(before.EditRole, after.EditRole) ||
+		(before.Masked && !after.Masked)
+}
+
+// rules 按规则优先级计算参数的生效权限，不含调用方能否查看与修改
+func (p *PermissionPolicy) rules(name, group string) ParameterAccess {
+	access := ParameterAccess{ViewRole: DefaultViewRole, EditRole: DefaultEditRole}
+	apply := func(rule model.ParameterPermission, ok bool) {
+		if !ok {
+			return
+		}
+		if rule.ViewRole != "" {
+			access.ViewRole = rule.ViewRole
+		}
+		if rule.EditRole != "" {
+			access.EditRole = rule.EditRole
+		}
+		if rule.Masked != nil {
+			access.Masked = *rule.Masked
+		}
+	}
+	if group != "" {
+		rule, ok := p.groups[group]
+		apply(rule, ok)
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' {
+			rule, ok := p.parameters[name[:i]]
+			apply(rule, ok)
+		}
+	}
+	rule, ok := p.parameters[name]
+	apply(rule, ok)
+	return access
+}
+
+// Hidden 返回调用方无权查看的参数名，包括仅有参数规则的已删除参数
+func (p *PermissionPolicy) Hidden(caller *auth.Identity, defs []model.VNFDefinition) []string {
+	seen := make(map[string]bool)
+	var names []string
+	hide := func(name, group string) {
+		if !seen[name] && !p.Access(caller, name, group).CanView {
+			names = append(names, name)
+		}
+		seen[name] = true
+	}
+	for _, def := range defs {
+		hide(def.ParameterName, def.Group)
+	}
+	for name := range p.parameters {
+		hide(name, "")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// View 按调用方权限输出定义，机密参数始终屏蔽
+func (p *PermissionPolicy) View(caller *auth.Identity, def model.VNFDefinition) DefinitionView {
+	access := p.Access(caller, def.ParameterName, def.Group)
+	if def.Type == TypeSecret {
+		access.Masked = true
+	}
+	if access.Masked {
+		def.DefaultValue, def.CurrentValue = maskValue(def.DefaultValue), maskValue(def.CurrentValue)
+	}
+	return DefinitionView{VNFDefinition: def, Access: access}
+}
+
+// FilterConfig 返回去掉无权查看的表单项、并屏蔽取值后的配置副本
+func (p *PermissionPolicy) FilterConfig(caller *auth.Identity, config *YAMLConfig) *YAMLConfig {
+	filtered := *config
+	filtered.Fields = make(map[string]FormField, len(config.Fields))
+	for name, field := range config.Fields {
+		access := p.Access(caller, name, field.Group)
+		if !access.CanView {
+			continue
+		}
+		if access.Masked || field.Type == TypeSecret {
+			if field.DefaultValue != nil {
+				field.DefaultValue = MaskedValue
+			}
+		}
+		filtered.Fields[name] = field
+	}
+	return &filtered
+}
+
+// RedactHistory 屏蔽变更记录快照中的参数取值，无权查看的参数清空快照
+func (p *PermissionPolicy) RedactHistory(caller *auth.Identity, records []model.ChangeRecord) {
+	for i := range records {
+		rec := &records[i]
+		if rec.ParameterName == "" {
+			continue
+		}
+		group, typ := snapshotInfo(rec.Before, rec.After)
+		access := p.Access(caller, rec.ParameterName, group)
+		switch {
+		case !access.CanView:
+			rec.Before, rec.After = "", ""
+		case access.Masked || typ == TypeSecret:
+			rec.Before, rec.After = maskSnapshot(rec.Before), maskSnapshot(rec.After)
+		}
+	}
+}
+
+// snapshotInfo 从定义快照中读取参数所属分组与类型，类型以变更前为准（由机密改为普通类型时同样屏蔽）
+func snapshotInfo(before, after string) (group, typ string) {
+	for _, raw := range []string{before, after} {
+		var def struct {
+			Group string `json:"group"`
+			Type  string `json:"type"`
+		}
+		if raw == "" || json.Unmarshal([]byte(raw), &def) != nil {
+			continue
+		}
+		if group == "" {
+			group = def.Group
+		}
+		if typ != TypeSecret {
+			typ = def.Type
+		}
+	}
+	return group, typ
+}
+
+func maskSnapshot(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return ""
+	}
+	for _, key := range []string{"defaultValue", "currentValue"} {
+		if v, ok := m[key].(string); ok {
+			m[key] = maskValue(v)
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// PermissionService 参数权限规则管理与鉴权
+type PermissionService struct {
+	store repository.Store
+}
+
+func NewPermissionService(repos *repository.Repositories) *PermissionService {
+	return &PermissionService{store: repos.Store}
+}
+
+// Policy 加载某VNF的权限规则
+func (s *PermissionService) Policy(ctx context.Context, vnfID uint) (*PermissionPolicy, error) {
+	rules, err := s.store.Permissions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	policy := &PermissionPolicy{
+		parameters: make(map[string]model.ParameterPermission),
+		groups:     make(map[string]model.ParameterPermission),
+	}
+	for _, rule := range rules {
+		if rule.Scope == model.PermissionScopeGroup {
+			policy.groups[rule.Target] = rule
+		} else {
+			policy.parameters[rule.Target] = rule
+		}
+	}
+	return policy, nil
+}
+
+// Access 计算当前调用方对某个参数的权限
+func (s *PermissionService) Access(ctx context.Context, vnfID uint, name, group string) (ParameterAccess, error) {
+	policy, err := s.Policy(ctx, vnfID)
+	if err != nil {
+		return ParameterAccess{}, err
+	}
+	return policy.Access(auth.FromContext(ctx), name, group), nil
+}
+
+func (s *PermissionService) List(ctx context.Context, vnfID uint) ([]model.ParameterPermission, error) {
+	if _, err := s.store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	return s.store.Permissions().ListByVNF(ctx, vnfID)
+}
+
+// Set 通过API设置规则，覆盖同一目标上的已有规则（包括描述文件中声明的规则）
+func (s *PermissionService) Set(ctx context.Context, vnfID uint, req dto.PermissionRequest) (*model.ParameterPermission, error) {
+	if _, err := s.store.Instances().Get(ctx, vnfID); err != nil {
+		return nil, err
+	}
+	rule := &model.ParameterPermission{
+		VNFID:    vnfID,
+		Scope:    req.Scope,
+		Target:   req.Target,
+		ViewRole: req.ViewRole,
+		EditRole: req.EditRole,
+		Masked:   req.Masked,
+		Source:   model.PermissionSourceAPI,
+	}
+	if err := validatePermission(rule); err != nil {
+		return nil, err
+	}
+	if err := s.store.Permissions().Upsert(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *PermissionService) Delete(ctx context.Context, vnfID, id uint) error {
+	return s.store.Permissions().Delete(ctx, vnfID, id)
+}
+
+// ApplyDescriptor 保存描述文件中为参数和分组声明的权限
+func (s *PermissionService) ApplyDescriptor(ctx context.Context, vnfID uint, config *YAMLConfig) error {
+	rules := descriptorPermissions(vnfID, config)
+	if len(rules) == 0 {
+		return nil
+	}
+	return s.store.Transaction(ctx, func(tx repository.Store) error {
+		for i := range rules {
+			if err := tx.Permissions().Upsert(ctx, &rules[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ValidateDescriptorPermissions 校验描述文件中声明的权限，角色无效时拒绝整个描述文件，
+// 避免规则被忽略后参数回落到更宽松的默认权限
+func ValidateDescriptorPermissions(config *YAMLConfig) error {
+	for _, rule := range descriptorPermissions(0, config) {
+		if err := validatePermission(&rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func descriptorPermissions(vnfID uint, config *YAMLConfig) []model.ParameterPermission {
+	var rules []model.ParameterPermission
+	add := func(scope, target string, spec *PermissionSpec) {
+		if spec == nil {
+			return
+		}
+		rules = append(rules, model.ParameterPermission{
+			VNFID:    vnfID,
+			Scope:    scope,
+			Target:   target,
+			ViewRole: spec.View,
+			EditRole: spec.Edit,
+			Masked:   spec.Masked,
+			Source:   model.PermissionSourceDescriptor,
+		})
+	}
+	for group, spec := range config.GroupPermissions {
+		spec := spec
+		add(model.PermissionScopeGroup, group, &spec)
+	}
+	for name, field := range config.Fields {
+		add(model.PermissionScopeParameter, name, field.Permissions)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Scope != rules[j].Scope {
+			return rules[i].Scope < rules[j].Scope
+		}
+		return rules[i].Target < rules[j].Target
+	})
+	return rules
+}
+
+func validatePermission(rule *model.ParameterPermission) error {
+	if rule.Scope != model.PermissionScopeParameter && rule.Scope != model.PermissionScopeGroup {
+		return fmt.Errorf("无效的作用范围: %s（可选 parameter、group）", rule.Scope)
+	}
+	if rule.Target == "" {
+		return errors.New("权限规则缺少目标")
+	}
+	for _, role := range []string{rule.ViewRole, rule.EditRole} {
+		if role != "" && !auth.ValidRole(role) {
+			return fmt.Errorf("%s %s 的角色无效: %s", rule.Scope, rule.Target, role)
+		}
+	}
+	return nil
+}
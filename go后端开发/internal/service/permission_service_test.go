@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+
+	"vnf-config/internal/model"
+	"vnf-shared/auth"
+)
+
+// TestPolicyLoosens 移动分组后查看、修改角色降低或不再屏蔽时视为放宽
+func TestPolicyLoosens(t *testing.T) {
+	masked := true
+	policy := &PermissionPolicy{
+		parameters: map[string]model.ParameterPermission{
+			"db.password": {Target: "db.password", ViewRole: auth.RoleOperator},
+		},
+		groups: map[string]model.ParameterPermission{
+			"secure":  {Target: "secure", Masked: &masked},
+			"strict":  {Target: "strict", ViewRole: auth.RoleAdmin, EditRole: auth.RoleAdmin},
+			"editors": {Target: "editors", EditRole: auth.RoleAdmin},
+		},
+	}
+	cases := []struct {
+		name, from, to string
+		want           bool
+	}{
+		{"port", "secure", "", true},
+		{"port", "", "secure", false},
+		{"port", "strict", "editors", true},
+		{"port", "editors", "", true},
+		{"port", "", "strict", false},
+		{"port", "other", "", false},
+		// 参数规则优先于分组规则，查看角色不随分组改变
+		{"db.password", "editors", "", true},
+		{"db.password", "strict", "editors", false},
+	}
+	for _, c := range cases {
+		if got := policy.Loosens(c.name, c.from, c.to); got != c.want {
+			t.Errorf("Loosens(%s, %q, %q) = %v, want %v", c.name, c.from, c.to, got, c.want)
+		}
+	}
+}
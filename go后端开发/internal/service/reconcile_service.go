@@ -0,0 +1,559 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+)
+
+// 差异类型
+const (
+	DriftMissing   = "missing"   // MySQL中存在、MongoDB中缺失
+	DriftOrphaned  = "orphaned"  // MongoDB中存在、MySQL中没有对应记录
+	DriftDiffering = "differing" // 两边都存在但字段不一致
+)
+
+// 修复时的数据基准
+const (
+	ReconcileSourceMySQL = "mysql"
+	ReconcileSourceMongo = "mongo"
+)
+
+// ReconcileOptions 对账参数
+type ReconcileOptions struct {
+	Source string `json:"source"` // 修复时以哪一侧为准：mysql（默认）或 mongo
+	Repair bool   `json:"repair"` // 为 false 时只生成报告
+	VNFID  uint   `json:"vnfId"`  // 为 0 时检查全部VNF
+}
+
+// FieldDrift 单个字段的差异
+type FieldDrift struct {
+	Field string      `json:"field"`
+	MySQL interface{} `json:"mysql"`
+	Mongo interface{} `json:"mongo"`
+}
+
+// DriftItem 单条差异
+type DriftItem struct {
+	Entity       string       `json:"entity"` // instance 或 definition
+	Kind         string       `json:"kind"`
+	VNFID        uint         `json:"vnfId"`
+	DefinitionID uint         `json:"definitionId,omitempty"`
+	Name         string       `json:"name"`
+	MongoID      string       `json:"mongoId,omitempty"`
+	Fields       []FieldDrift `json:"fields,omitempty"`
+	Repaired     bool         `json:"repaired"`
+	RepairError  string       `json:"repairError,omitempty"`
+
+	mysqlInstance *model.VNFInstance
+	mysqlDef      *model.VNFDefinition
+	mongoInstance *VNFInstanceMongo
+	mongoDef      *VNFDefinitionMongo
+}
+
+// DriftReport 对账报告
+type DriftReport struct {
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	Options    ReconcileOptions `json:"options"`
+	Summary    map[string]int   `json:"summary"`
+	// 仍有待派发发件箱操作的VNF，差异可能只是同步延迟，不做修复
+	SkippedPending []uint `json:"skippedPending"`
+	// 其中发件箱有已放弃操作的VNF，需先重试或丢弃该操作，否则差异不会自行消除
+	BlockedFailed []uint      `json:"blockedFailed"`
+	Items         []DriftItem `json:"items"`
+}
+
+var (
+	lastReportMu sync.Mutex
+	lastReport   *DriftReport
+)
+
+// ReconcileService MySQL与MongoDB全量对账与修复
+type ReconcileService struct {
+	store     repository.Store
+	documents repository.DocumentRepository
+	outbox    *OutboxService
+}
+
+func NewReconcileService(repos *repository.Repositories) *ReconcileService {
+	return &ReconcileService{
+		store:     repos.Store,
+		documents: repos.Documents,
+		outbox:    NewOutboxService(repos),
+	}
+}
+
+// BackfillDefinitionIDs 为早期写入、缺少 definition_id 的定义文档按 vnf_id 与 parameter_name 补齐关联。
+// 已有同一定义的文档时删除旧文档；找不到对应定义的文档保留，由对账报告为孤立文档。
+// 只处理缺少 definition_id 的文档，可重复执行
+func (s *ReconcileService) BackfillDefinitionIDs(ctx context.Context) (updated, removed int, err error) {
+	// 与发件箱派发互斥，避免派发按 definition_id 插入的文档与补齐的旧文档重复
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	findCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var docs []VNFDefinitionMongo
+	if err := s.documents.Find(findCtx, repository.CollectionDefinitions, bson.M{"definition_id": bson.M{"$exists": false}}, &docs); err != nil {
+		return 0, 0, err
+	}
+
+	byVNF := make(map[uint]map[string]model.VNFDefinition)
+	for i := range docs {
+		doc := &docs[i]
+		defs, ok := byVNF[doc.VNFID]
+		if !ok {
+			list, err := s.store.Definitions().ListByVNF(ctx, doc.VNFID)
+			if err != nil {
+				return updated, removed, err
+			}
+			defs = make(map[string]model.VNFDefinition, len(list))
+			for _, def := range list {
+				defs[def.ParameterName] = def
+			}
+			byVNF[doc.VNFID] = defs
+		}
+		def, ok := defs[doc.ParameterName]
+		if !ok {
+			continue
+		}
+
+		opCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		var existing VNFDefinitionMongo
+		err := s.documents.FindOne(opCtx, repository.CollectionDefinitions, definitionFilter(def.VNFID, def.ID), &existing)
+		switch {
+		case err == nil:
+			err = s.documents.Delete(opCtx, repository.CollectionDefinitions, bson.M{"_id": doc.ID})
+			if err == nil {
+				removed++
+			}
+		case errors.Is(err, repository.ErrNotFound):
+			doc.DefinitionID = def.ID
+			err = s.documents.Replace(opCtx, repository.CollectionDefinitions, bson.M{"_id": doc.ID}, doc)
+			if err == nil {
+				updated++
+			}
+		}
+		cancel()
+		if err != nil {
+			return updated, removed, fmt.Errorf("参数 %s（VNF #%d）的定义文档补齐失败: %v", doc.ParameterName, doc.VNFID, err)
+		}
+	}
+	return updated, removed, nil
+}
+
+// LastReport 返回最近一次对账报告
+func (s *ReconcileService) LastReport() *DriftReport {
+	lastReportMu.Lock()
+	defer lastReportMu.Unlock()
+	return lastReport
+}
+
+// Run 比对两库中的实例与定义，生成差异报告，并按需以指定一侧为准修复
+func (s *ReconcileService) Run(ctx context.Context, opts ReconcileOptions) (*DriftReport, error) {
+	if opts.Source == "" {
+		opts.Source = ReconcileSourceMySQL
+	}
+	if opts.Source != ReconcileSourceMySQL && opts.Source != ReconcileSourceMongo {
+		return nil, fmt.Errorf("不支持的数据基准: %s", opts.Source)
+	}
+
+	report := &DriftReport{
+		StartedAt:      time.Now(),
+		Options:        opts,
+		Summary:        map[string]int{DriftMissing: 0, DriftOrphaned: 0, DriftDiffering: 0},
+		SkippedPending: []uint{},
+		BlockedFailed:  []uint{},
+		Items:          []DriftItem{},
+	}
+
+	items, err := s.compare(ctx, opts.VNFID)
+	if err != nil {
+		return nil, err
+	}
+
+	failedKeys, err := s.outbox.FailedKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[uint]bool)
+	for i := range items {
+		item := &items[i]
+		report.Summary[item.Kind]++
+		if _, checked := pending[item.VNFID]; !checked {
+			count, err := s.outbox.PendingCount(ctx, item.VNFID)
+			if err != nil {
+				return nil, err
+			}
+			pending[item.VNFID] = count > 0
+			if count > 0 {
+				report.SkippedPending = append(report.SkippedPending, item.VNFID)
+				if failedKeys[vnfOrderingKey(item.VNFID)] {
+					report.BlockedFailed = append(report.BlockedFailed, item.VNFID)
+				}
+			}
+		}
+	}
+
+	if opts.Repair {
+		s.repair(ctx, items, opts.Source, pending)
+	}
+	report.Items = items
+	report.FinishedAt = time.Now()
+
+	lastReportMu.Lock()
+	lastReport = report
+	lastReportMu.Unlock()
+	return report, nil
+}
+
+// compare 双向逐字段比对
+func (s *ReconcileService) compare(ctx context.Context, vnfID uint) ([]DriftItem, error) {
+	instances, err := s.store.Instances().ListAll(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, vnfID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	if vnfID != 0 {
+		filter["vnf_id"] = vnfID
+	}
+	findCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var mongoInstances []VNFInstanceMongo
+	if err := s.documents.Find(findCtx, repository.CollectionInstances, filter, &mongoInstances); err != nil {
+		return nil, err
+	}
+	var mongoDefs []VNFDefinitionMongo
+	if err := s.documents.Find(findCtx, repository.CollectionDefinitions, filter, &mongoDefs); err != nil {
+		return nil, err
+	}
+
+	var items []DriftItem
+
+	// 实例：以 vnf_id 关联
+	instDocs := make(map[uint]*VNFInstanceMongo)
+	for i := range mongoInstances {
+		doc := &mongoInstances[i]
+		if _, dup := instDocs[doc.VNFID]; dup {
+			items = append(items, DriftItem{Entity: "instance", Kind: DriftOrphaned, VNFID: doc.VNFID, Name: doc.Name, MongoID: doc.ID.Hex(), mongoInstance: doc})
+			continue
+		}
+		instDocs[doc.VNFID] = doc
+	}
+	for i := range instances {
+		inst := &instances[i]
+		doc, ok := instDocs[inst.ID]
+		if !ok {
+			items = append(items, DriftItem{Entity: "instance", Kind: DriftMissing, VNFID: inst.ID, Name: inst.Name, mysqlInstance: inst})
+			continue
+		}
+		delete(instDocs, inst.ID)
+		if fields := instanceFieldDrifts(inst, doc); len(fields) > 0 {
+			items = append(items, DriftItem{Entity: "instance", Kind: DriftDiffering, VNFID: inst.ID, Name: inst.Name, MongoID: doc.ID.Hex(), Fields: fields, mysqlInstance: inst, mongoInstance: doc})
+		}
+	}
+	for _, doc := range instDocs {
+		items = append(items, DriftItem{Entity: "instance", Kind: DriftOrphaned, VNFID: doc.VNFID, Name: doc.Name, MongoID: doc.ID.Hex(), mongoInstance: doc})
+	}
+
+	// 定义：以 definition_id 关联
+	defDocs := make(map[uint]*VNFDefinitionMongo)
+	for i := range mongoDefs {
+		doc := &mongoDefs[i]
+		if _, dup := defDocs[doc.DefinitionID]; dup || doc.DefinitionID == 0 {
+			items = append(items, DriftItem{Entity: "definition", Kind: DriftOrphaned, VNFID: doc.VNFID, DefinitionID: doc.DefinitionID, Name: doc.ParameterName, MongoID: doc.ID.Hex(), mongoDef: doc})
+			continue
+		}
+		defDocs[doc.DefinitionID] = doc
+	}
+	for i := range defs {
+		def := &defs[i]
+		doc, ok := defDocs[def.ID]
+		if !ok {
+			items = append(items, DriftItem{Entity: "definition", Kind: DriftMissing, VNFID: def.VNFID, DefinitionID: def.ID, Name: def.ParameterName, mysqlDef: def})
+			continue
+		}
+		delete(defDocs, def.ID)
+		if fields := definitionFieldDrifts(*def, *doc); len(fields) > 0 {
+			items = append(items, DriftItem{Entity: "definition", Kind: DriftDiffering, VNFID: def.VNFID, DefinitionID: def.ID, Name: def.ParameterName, MongoID: doc.ID.Hex(), Fields: fields, mysqlDef: def, mongoDef: doc})
+		}
+	}
+	for _, doc := range defDocs {
+		items = append(items, DriftItem{Entity: "definition", Kind: DriftOrphaned, VNFID: doc.VNFID, DefinitionID: doc.DefinitionID, Name: doc.ParameterName, MongoID: doc.ID.Hex(), mongoDef: doc})
+	}
+	return items, nil
+}
+
+// instanceFieldDrifts 比对实例字段；yaml_config 只能由上传生成，仅检查是否存在
+func instanceFieldDrifts(inst *model.VNFInstance, doc *VNFInstanceMongo) []FieldDrift {
+	var fields []FieldDrift
+	if inst.Name != doc.Name {
+		fields = append(fields, FieldDrift{Field: "name", MySQL: inst.Name, Mongo: doc.Name})
+	}
+	if doc.YAMLConfig == nil {
+		fields = append(fields, FieldDrift{Field: "yamlConfig", MySQL: "present", Mongo: nil})
+	}
+	return fields
+}
+
+// definitionFieldDrifts 逐字段比对定义，报告中不输出机密参数的取值
+func definitionFieldDrifts(def model.VNFDefinition, doc VNFDefinitionMongo) []FieldDrift {
+	var fields []FieldDrift
+	secret := def.Type == TypeSecret || doc.Type == TypeSecret
+	compare := func(name string, a, b interface{}) {
+		if fmt.Sprint(a) != fmt.Sprint(b) {
+			if secret && (name == "defaultValue" || name == "currentValue") {
+				a, b = maskValue(fmt.Sprint(a)), maskValue(mongoString(b))
+			}
+			fields = append(fields, FieldDrift{Field: name, MySQL: a, Mongo: b})
+		}
+	}
+	compare("parameterName", def.ParameterName, doc.ParameterName)
+	compare("defaultValue", def.DefaultValue, doc.DefaultValue)
+	compare("descriptionTxt", def.DescriptionText, doc.DescriptionText)
+	compare("type", def.Type, doc.Type)
+	compare("group", def.Group, doc.Group)
+	compare("canBeUpdated", def.CanBeUpdated, doc.CanBeUpdated)
+	compare("hidenCondition", def.HiddenCondition, doc.HiddenCondition)
+	compare("optional", boolString(def.Optional), boolString(doc.Optional))
+	compare("constraints", def.Constraints, doc.Constraints)
+	compare("currentValue", def.CurrentValue, doc.CurrentValue)
+	compare("modified", def.Modified, doc.Modified)
+	return fields
+}
+
+// repair 逐VNF修复；有待派发操作的VNF跳过
+func (s *ReconcileService) repair(ctx context.Context, items []DriftItem, source string, pending map[uint]bool) {
+	var entryIDs []uint
+	for i := range items {
+		item := &items[i]
+		if pending[item.VNFID] {
+			continue
+		}
+		err := s.store.Transaction(ctx, func(tx repository.Store) error {
+			var ids []uint
+			var err error
+			if source == ReconcileSourceMySQL {
+				ids, err = s.repairFromMySQL(ctx, tx, item)
+			} else {
+				ids, err = s.repairFromMongo(ctx, tx, item)
+			}
+			entryIDs = append(entryIDs, ids...)
+			return err
+		})
+		if err != nil {
+			item.RepairError = err.Error()
+			continue
+		}
+		item.Repaired = true
+	}
+	if err := s.outbox.Flush(ctx, entryIDs); err != nil {
+		log.Printf("对账修复的MongoDB操作待重试: %v", err)
+	}
+}
+
+// repairFromMySQL 以MySQL为准：补写缺失文档、删除孤立文档、覆盖不一致文档
+func (s *ReconcileService) repairFromMySQL(ctx context.Context, tx repository.Store, item *DriftItem) ([]uint, error) {
+	now := time.Now()
+	var entry *model.MongoOutbox
+	var err error
+	switch {
+	case item.Kind == DriftOrphaned && item.Entity == "instance":
+		entry, err = s.outbox.EnqueueDelete(ctx, tx, repository.CollectionInstances, bson.M{"vnf_id": item.VNFID, "_id": item.mongoInstance.ID}, now)
+	case item.Kind == DriftOrphaned && item.Entity == "definition":
+		entry, err = s.outbox.EnqueueDelete(ctx, tx, repository.CollectionDefinitions, bson.M{"vnf_id": item.VNFID, "_id": item.mongoDef.ID}, now)
+	case item.Entity == "instance":
+		doc, buildErr := s.instanceDocFromMySQL(ctx, tx, item.mysqlInstance, item.mongoInstance)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		entry, err = s.outbox.EnqueueUpsert(ctx, tx, repository.CollectionInstances, bson.M{"vnf_id": item.VNFID}, doc, now)
+	default:
+		entry, err = s.outbox.EnqueueUpsert(ctx, tx, repository.CollectionDefinitions, definitionFilter(item.VNFID, item.DefinitionID), toDefinitionMongo(*item.mysqlDef), now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []uint{entry.ID}, nil
+}
+
+// instanceDocFromMySQL 由MySQL实例构造MongoDB文档；已有文档时保留其YAML配置，
+// 否则由MySQL中的定义重建表单项
+func (s *ReconcileService) instanceDocFromMySQL(ctx context.Context, tx repository.Store, inst *model.VNFInstance, existing *VNFInstanceMongo) (*VNFInstanceMongo, error) {
+	doc := &VNFInstanceMongo{
+		VNFID:     inst.ID,
+		Name:      inst.Name,
+		CreatedAt: inst.CreatedAt,
+		UpdatedAt: inst.UpdatedAt,
+	}
+	if existing != nil && existing.YAMLConfig != nil {
+		doc.YAMLConfig = existing.YAMLConfig
+		doc.FormFields = existing.FormFields
+		doc.Metadata = existing.Metadata
+		return doc, nil
+	}
+	defs, err := tx.Definitions().ListByVNF(ctx, inst.ID)
+	if err != nil {
+		return nil, err
+	}
+	config := yamlConfigFromDefinitions(defs)
+	doc.YAMLConfig = config
+	doc.FormFields = config.Fields
+	doc.Metadata = config.Metadata
+	return doc, nil
+}
+
+// yamlConfigFromDefinitions 由参数定义重建YAML配置的表单项部分
+func yamlConfigFromDefinitions(defs []model.VNFDefinition) *YAMLConfig {
+	config := &YAMLConfig{
+		Fields:   make(map[string]FormField),
+		Groups:   make(map[string]string),
+		Metadata: map[string]interface{}{"rebuiltFrom": "mysql"},
+	}
+	for i, def := range defs {
+		var defaultValue interface{} = def.DefaultValue
+		if def.Type == TypeSecret {
+			// 机密取值只以密文保存在参数定义中
+			defaultValue = nil
+		}
+		config.Fields[def.ParameterName] = FormField{
+			Name:            def.ParameterName,
+			Type:            def.Type,
+			DefaultValue:    defaultValue,
+			Description:     def.DescriptionText,
+			Required:        def.Optional != nil && !*def.Optional,
+			Group:           def.Group,
+			HiddenCondition: def.HiddenCondition,
+			Validation:      make(map[string]interface{}),
+			Order:           i,
+			Metadata:        make(map[string]interface{}),
+		}
+	}
+	return config
+}
+
+// repairFromMongo 以MongoDB为准：删除MongoDB中缺失的MySQL记录、按文档补建或覆盖MySQL记录
+func (s *ReconcileService) repairFromMongo(ctx context.Context, tx repository.Store, item *DriftItem) ([]uint, error) {
+	// 以MongoDB为准修改MySQL时同样记录变更历史
+	action := model.ChangeActionUpdate
+	if item.Kind == DriftOrphaned {
+		action = model.ChangeActionCreate
+	}
+	switch {
+	case item.Kind == DriftMissing && item.Entity == "instance":
+		if err := tx.Definitions().DeleteByVNF(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		if err := tx.Permissions().DeleteByVNF(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		if err := tx.ChangeSets().DeleteByVNF(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		if err := tx.ApprovalPolicies().DeleteByVNF(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		if err := tx.Overlays().DeleteByVNF(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		if err := tx.Instances().Delete(ctx, item.VNFID); err != nil {
+			return nil, err
+		}
+		return nil, recordInstanceChange(ctx, tx, model.ChangeActionDelete, item.mysqlInstance, nil)
+	case item.Kind == DriftMissing && item.Entity == "definition":
+		if err := tx.Definitions().Delete(ctx, item.VNFID, item.DefinitionID); err != nil {
+			return nil, err
+		}
+		return nil, recordDefinitionChange(ctx, tx, model.ChangeActionDelete, item.mysqlDef, nil)
+	case item.Entity == "instance":
+		doc := item.mongoInstance
+		inst := model.VNFInstance{ID: doc.VNFID, Name: doc.Name, CreatedAt: doc.CreatedAt, UpdatedAt: doc.UpdatedAt}
+		if doc.VNFID == 0 {
+			return nil, errors.New("MongoDB实例文档缺少vnf_id，无法恢复")
+		}
+		if err := tx.Instances().Save(ctx, &inst); err != nil {
+			return nil, err
+		}
+		return nil, recordInstanceChange(ctx, tx, action, item.mysqlInstance, &inst)
+	default:
+		doc := item.mongoDef
+		def := definitionFromMongo(*doc)
+		if err := tx.Definitions().Save(ctx, &def); err != nil {
+			return nil, err
+		}
+		if err := recordDefinitionChange(ctx, tx, action, item.mysqlDef, &def); err != nil {
+			return nil, err
+		}
+		if doc.DefinitionID == def.ID {
+			return nil, nil
+		}
+		// 文档缺少定义ID时MySQL分配了新ID，需回写到MongoDB文档
+		entry, err := s.outbox.EnqueueUpsert(ctx, tx, repository.CollectionDefinitions, bson.M{"vnf_id": doc.VNFID, "_id": doc.ID}, toDefinitionMongo(def), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return []uint{entry.ID}, nil
+	}
+}
+
+// definitionFromMongo 由MongoDB文档构造MySQL定义
+func definitionFromMongo(doc VNFDefinitionMongo) model.VNFDefinition {
+	return model.VNFDefinition{
+		ID:              doc.DefinitionID,
+		VNFID:           doc.VNFID,
+		ParameterName:   doc.ParameterName,
+		DefaultValue:    mongoString(doc.DefaultValue),
+		DescriptionText: doc.DescriptionText,
+		Type:            doc.Type,
+		Group:           doc.Group,
+		CanBeUpdated:    doc.CanBeUpdated,
+		HiddenCondition: doc.HiddenCondition,
+		Optional:        doc.Optional,
+		Constraints:     mongoString(doc.Constraints),
+		CurrentValue:    mongoString(doc.CurrentValue),
+		Modified:        doc.Modified,
+		CreatedAt:       doc.CreatedAt,
+		UpdatedAt:       doc.UpdatedAt,
+	}
+}
+
+func mongoString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// RunScheduled 按固定间隔执行对账，直到ctx取消
+func (s *ReconcileService) RunScheduled(ctx context.Context, interval time.Duration, opts ReconcileOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Run(ctx, opts)
+			if err != nil {
+				log.Printf("定时对账失败: %v", err)
+				continue
+			}
+			log.Printf("定时对账完成: 缺失 %d, 孤立 %d, 不一致 %d",
+				report.Summary[DriftMissing], report.Summary[DriftOrphaned], report.Summary[DriftDiffering])
+		}
+	}
+}
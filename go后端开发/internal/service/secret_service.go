@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/gitops"
+	"vnf-shared/secrets"
+)
+
+// TypeSecret 机密参数类型：取值加密保存，在所有接口与变更历史中屏蔽
+const TypeSecret = "secret"
+
+// ErrReasonRequired 查看机密参数明文必须说明原因
+var ErrReasonRequired = errors.New("查看明文需要填写原因")
+
+// secretOwner 机密取值的所属对象，作为密文的附加认证数据：密文只能在同一实例的同名参数下解密，
+// 定义、覆盖层、变更单与GitOps仓库中该参数的取值共用
+func secretOwner(vnfID uint, parameterName string) string {
+	return fmt.Sprintf("vnf:%d/%s", vnfID, parameterName)
+}
+
+// sealDefinition 加密 secret 类型定义的默认值与当前值（调用前为明文），须已设置 VNFID
+func sealDefinition(def *model.VNFDefinition) error {
+	if def.Type != TypeSecret {
+		return nil
+	}
+	owner := secretOwner(def.VNFID, def.ParameterName)
+	var err error
+	if def.DefaultValue, err = secrets.Default().Encrypt(owner, def.DefaultValue); err != nil {
+		return err
+	}
+	def.CurrentValue, err = secrets.Default().Encrypt(owner, def.CurrentValue)
+	return err
+}
+
+// openDefinition 返回解密后的定义副本，不是密文的取值原样保留
+func openDefinition(def model.VNFDefinition) (model.VNFDefinition, error) {
+	owner := secretOwner(def.VNFID, def.ParameterName)
+	var err error
+	if def.DefaultValue, err = secrets.Default().Decrypt(owner, def.DefaultValue); err != nil {
+		return def, err
+	}
+	def.CurrentValue, err = secrets.Default().Decrypt(owner, def.CurrentValue)
+	return def, err
+}
+
+// resealDefinition 加密修改后的机密定义 item（明文），取值与修改前明文 plain 相同时沿用 current 中的原密文，
+// 避免未修改的取值在每次保存时产生新的密文
+func resealDefinition(item *model.VNFDefinition, current, plain model.VNFDefinition) error {
+	if item.Type != TypeSecret {
+		return nil
+	}
+	reuse := current.Type == TypeSecret
+	for _, v := range []struct {
+		dest           *string
+		stored, before string
+	}{
+		{&item.DefaultValue, current.DefaultValue, plain.DefaultValue},
+		{&item.CurrentValue, current.CurrentValue, plain.CurrentValue},
+	} {
+		if reuse && *v.dest == v.before && secrets.IsEncrypted(v.stored) {
+			*v.dest = v.stored
+			continue
+		}
+		sealed, err := secrets.Default().Encrypt(secretOwner(item.VNFID, item.ParameterName), *v.dest)
+		if err != nil {
+			return err
+		}
+		*v.dest = sealed
+	}
+	return nil
+}
+
+// maskSecretDefinitions 返回屏蔽机密参数取值后的定义副本
+func maskSecretDefinitions(defs []model.VNFDefinition) []model.VNFDefinition {
+	out := make([]model.VNFDefinition, len(defs))
+	for i, def := range defs {
+		if def.Type == TypeSecret {
+			def.DefaultValue, def.CurrentValue = maskValue(def.DefaultValue), maskValue(def.CurrentValue)
+		}
+		out[i] = def
+	}
+	return out
+}
+
+// maskValue 非空取值屏蔽为 MaskedValue，空值保持为空以便区分“未设置”
+func maskValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	return MaskedValue
+}
+
+// withoutSecretDefaults 返回去掉机密表单项默认值的配置副本，机密取值只以密文保存在参数定义中
+func withoutSecretDefaults(config *YAMLConfig) *YAMLConfig {
+	stripped := *config
+	stripped.Fields = make(map[string]FormField, len(config.Fields))
+	for name, field := range config.Fields {
+		if field.Type == TypeSecret {
+			field.DefaultValue = nil
+		}
+		stripped.Fields[name] = field
+	}
+	return &stripped
+}
+
+// requireSecretKey 描述文件为机密参数提供了默认值但未配置密钥时拒绝上传
+func requireSecretKey(config *YAMLConfig) error {
+	if secrets.Default().Configured() {
+		return nil
+	}
+	for _, field := range config.Fields {
+		if field.Type == TypeSecret && field.DefaultValue != nil {
+			return secrets.ErrNoKey
+		}
+	}
+	return nil
+}
+
+// SecretService 机密参数的明文查看与主密钥轮换
+type SecretService struct {
+	store       repository.Store
+	documents   repository.DocumentRepository
+	dualStorage *DualStorageService
+	permissions *PermissionService
+	gitops      *GitOpsService
+}
+
+func NewSecretService(repos *repository.Repositories) *SecretService {
+	return &SecretService{
+		store:       repos.Store,
+		documents:   repos.Documents,
+		dualStorage: NewDualStorageService(repos),
+		permissions: NewPermissionService(repos),
+		gitops:      NewGitOpsService(repos),
+	}
+}
+
+// RevealedSecret 参数的明文取值
+type RevealedSecret struct {
+	DefinitionID  uint   `json:"definitionId"`
+	ParameterName string `json:"parameterName"`
+	DefaultValue  string `json:"defaultValue"`
+	CurrentValue  string `json:"currentValue"`
+}
+
+// Reveal 返回参数的明文取值，并在变更历史中记录查看人与原因
+func (s *SecretService) Reveal(ctx context.Context, vnfID, defID uint, reason string) (*RevealedSecret, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	def, err := s.store.Definitions().Get(ctx, vnfID, defID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.permissions.Access(ctx, vnfID, def.ParameterName, def.Group)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanView {
+		return nil, repository.ErrNotFound
+	}
+	plain, err := openDefinition(*def)
+	if err != nil {
+		return nil, err
+	}
+	record := &model.ChangeRecord{
+		VNFID:         def.VNFID,
+		EntityType:    "definition",
+		EntityID:      def.ID,
+		ParameterName: def.ParameterName,
+		Action:        model.ChangeActionReveal,
+		Reason:        reason,
+	}
+	if err := appendChange(ctx, s.store, record, nil, nil); err != nil {
+		return nil, err
+	}
+	return &RevealedSecret{
+		DefinitionID:  def.ID,
+		ParameterName: def.ParameterName,
+		DefaultValue:  plain.DefaultValue,
+		CurrentValue:  plain.CurrentValue,
+	}, nil
+}
+
+// 密文统计中关系型存储以外的位置
+const (
+	SealedDefinitions = "definitions" // 参数定义的默认值与当前值
+	SealedDocuments   = "documents"   // 文档存储中的定义文档
+	SealedGitOps      = "gitops"      // GitOps仓库当前提交中的渲染文件
+)
+
+// sealedScanBatch 分批读取含密文字段的条数
+const sealedScanBatch = 500
+
+// SecretStatus 密钥与密文的分布情况
+type SecretStatus struct {
+	Configured bool     `json:"configured"`
+	PrimaryKey string   `json:"primaryKey,omitempty"`
+	Keys       []string `json:"keys"`
+	// ValuesByKey 各主密钥加密的密文个数（全部位置合计）
+	ValuesByKey map[string]int `json:"valuesByKey"`
+	// Locations 各位置按主密钥统计的密文个数：definitions、overlays、changeSets、history、outbox、documents 与 gitops
+	Locations map[string]map[string]int `json:"locations"`
+	// Errors 统计失败的位置及原因，这些位置中的密文未计入
+	Errors map[string]string `json:"errors,omitempty"`
+	// Removable 不是主密钥且在全部位置中都不再使用的密钥，可从密钥文件中移除；有位置统计失败时为空。
+	// GitOps仓库历史提交中的密文不统计，拉取轮换前的外部提交仍需要旧密钥
+	Removable []string `json:"removable"`
+	// Plaintext 尚未加密的机密参数取值个数
+	Plaintext int `json:"plaintext"`
+}
+
+// Status 统计参数定义、覆盖层、变更单、变更历史、发件箱、文档存储与GitOps仓库中的密文所使用的主密钥
+func (s *SecretService) Status(ctx context.Context) (*SecretStatus, error) {
+	defs, err := s.store.Definitions().ListByVNF(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	keyring := secrets.Default()
+	status := &SecretStatus{
+		Configured:  keyring.Configured(),
+		PrimaryKey:  keyring.Primary(),
+		Keys:        keyring.KeyIDs(),
+		ValuesByKey: make(map[string]int),
+		Locations:   make(map[string]map[string]int),
+		Errors:      make(map[string]string),
+		Removable:   []string{},
+	}
+	count := func(location, value string) {
+		for _, token := range secrets.Find(value) {
+			if status.Locations[location] == nil {
+				status.Locations[location] = make(map[string]int)
+			}
+			status.Locations[location][secrets.KeyID(token)]++
+			status.ValuesByKey[secrets.KeyID(token)]++
+		}
+	}
+
+	for _, def := range defs {
+		for _, v := range []string{def.DefaultValue, def.CurrentValue} {
+			count(SealedDefinitions, v)
+			if def.Type == TypeSecret && v != "" && !secrets.IsEncrypted(v) {
+				status.Plaintext++
+			}
+		}
+	}
+	for _, location := range repository.SealedLocations {
+		err := s.scanSealed(ctx, location, func(field repository.SealedField) error {
+			count(location, field.Value)
+			return nil
+		})
+		if err != nil {
+			status.Errors[location] = err.Error()
+		}
+	}
+	if s.documents != nil {
+		var docs []VNFDefinitionMongo
+		if err := s.documents.Find(ctx, repository.CollectionDefinitions, bson.M{}, &docs); err != nil {
+			status.Errors[SealedDocuments] = err.Error()
+		}
+		for _, doc := range docs {
+			for _, v := range []interface{}{doc.DefaultValue, doc.CurrentValue} {
+				if text, ok := v.(string); ok {
+					count(SealedDocuments, text)
+				}
+			}
+		}
+	}
+	if gitops.Default() != nil {
+		counts, err := s.gitops.Ciphertexts(ctx)
+		if err != nil {
+			status.Errors[SealedGitOps] = err.Error()
+		}
+		for id, n := range counts {
+			if status.Locations[SealedGitOps] == nil {
+				status.Locations[SealedGitOps] = make(map[string]int)
+			}
+			status.Locations[SealedGitOps][id] += n
+			status.ValuesByKey[id] += n
+		}
+	}
+
+	if len(status.Errors) == 0 {
+		for _, id := range status.Keys {
+			if id != status.PrimaryKey && status.ValuesByKey[id] == 0 {
+				status.Removable = append(status.Removable, id)
+			}
+		}
+	}
+	return status, nil
+}
+
+// scanSealed 分批读取 location 中含密文的字段
+func (s *SecretService) scanSealed(ctx context.Context, location string, fn func(field repository.SealedField) error) error {
+	var after uint
+	for {
+		fields, err := s.store.Ciphertexts().Scan(ctx, location, secrets.Prefix, after, sealedScanBatch)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		for _, field := range fields {
+			if err := fn(field); err != nil {
+				return err
+			}
+			after = field.ID
+		}
+	}
+}
+
+// RotationReport 主密钥轮换结果
+type RotationReport struct {
+	PrimaryKey string `json:"primaryKey"`
+	// Rewrapped 各位置改用主密钥加密数据密钥的个数：definitions 为定义数，其余为密文数
+	Rewrapped map[string]int `json:"rewrapped"`
+	Encrypted int            `json:"encrypted"` // 原为明文、本次加密的定义数
+	// GitOpsCommit 以新密文重新渲染GitOps仓库的提交，没有改动时为空
+	GitOpsCommit string        `json:"gitopsCommit,omitempty"`
+	Failed       []string      `json:"failed,omitempty"`
+	Status       *SecretStatus `json:"status"`
+}
+
+// Rotate 将使用旧主密钥的密文改用当前主密钥加密数据密钥，并加密遗留的明文机密取值：
+// 参数定义经双存储写入（文档存储中的定义文档同步更新），覆盖层、变更单、变更历史与发件箱中的密文原地改写，
+// 最后重新渲染GitOps仓库。结果中的 Status.Removable 列出此后可以移除的旧密钥
+func (s *SecretService) Rotate(ctx context.Context) (*RotationReport, error) {
+	keyring := secrets.Default()
+	if !keyring.Configured() {
+		return nil, secrets.ErrNoKey
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	report := &RotationReport{PrimaryKey: keyring.Primary(), Rewrapped: make(map[string]int)}
+	ctx = audit.WithReason(ctx, "主密钥轮换")
+	for _, def := range defs {
+		before, item := def, def
+		owner := secretOwner(def.VNFID, def.ParameterName)
+		rewrapped, encrypted := false, false
+		var failure error
+		for _, v := range []*string{&item.DefaultValue, &item.CurrentValue} {
+			switch {
+			case secrets.IsEncrypted(*v):
+				value, changed, err := keyring.Rewrap(*v)
+				if err != nil {
+					failure = err
+					continue
+				}
+				*v, rewrapped = value, rewrapped || changed
+			case item.Type == TypeSecret && *v != "":
+				value, err := keyring.Encrypt(owner, *v)
+				if err != nil {
+					failure = err
+					continue
+				}
+				*v, encrypted = value, true
+			}
+		}
+		if failure != nil {
+			report.Failed = append(report.Failed, def.ParameterName+": "+failure.Error())
+			continue
+		}
+		if !rewrapped && !encrypted {
+			continue
+		}
+		if res := s.dualStorage.SaveVNFDefinition(ctx, &before, &item); !res.MySQLSuccess {
+			report.Failed = append(report.Failed, def.ParameterName+": "+res.MySQLError.Error())
+			continue
+		}
+		if rewrapped {
+			report.Rewrapped[SealedDefinitions]++
+		}
+		if encrypted {
+			report.Encrypted++
+		}
+	}
+
+	// 定义之后处理：上面的修改在变更历史与发件箱中留下的旧密文一并改写
+	for _, location := range repository.SealedLocations {
+		err := s.scanSealed(ctx, location, func(field repository.SealedField) error {
+			value, n, err := keyring.RewrapText(field.Value)
+			if err != nil {
+				report.Failed = append(report.Failed, fmt.Sprintf("%s #%d %s: %v", location, field.ID, field.Column, err))
+				return nil
+			}
+			if n == 0 {
+				return nil
+			}
+			// 读取后被并发修改的字段已由新的写入使用主密钥加密
+			if _, err := s.store.Ciphertexts().Replace(ctx, location, field, value); err != nil {
+				return err
+			}
+			report.Rewrapped[location] += n
+			return nil
+		})
+		if err != nil {
+			report.Failed = append(report.Failed, location+": "+err.Error())
+		}
+	}
+
+	if gitops.Default() != nil {
+		hash, err := s.gitops.RenderAll(ctx, "主密钥轮换: 以主密钥 "+keyring.Primary()+" 重新加密机密取值")
+		if err != nil {
+			report.Failed = append(report.Failed, SealedGitOps+": "+err.Error())
+		}
+		report.GitOpsCommit = hash
+	}
+
+	if report.Status, err = s.Status(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"archive/zip"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,19 +16,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v3"
 
-	"vnf-config/internal/infra/db"
+	"vnf-config/internal/infra/metrics"
 	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
+	"vnf-shared/auth"
 )
 
 type UploadService struct {
 	yamlParser    *YAMLParserService
 	dualStorage   *DualStorageService
+	permissions   *PermissionService
 }
 
-func NewUploadService() *UploadService { 
+func NewUploadService(repos *repository.Repositories) *UploadService {
 	return &UploadService{
 		yamlParser:  NewYAMLParserService(),
-		dualStorage: NewDualStorageService(),
+		dualStorage: NewDualStorageService(repos),
+		permissions: NewPermissionService(repos),
 	}
 }
 
@@ -34,14 +40,38 @@ func NewUploadService() *UploadService {
 type UploadResult struct {
 	VNFInstance  *model.VNFInstance
 	Definitions  []model.VNFDefinition
-	FormFields   map[string]interface{}
+	FormFields   map[string]FormField
 	YAMLConfig   *YAMLConfig
 	StorageResult *StorageResult
 	Errors       []string
 }
 
+// GetVNFConfig 获取VNF的完整配置，文档存储不可用时由结构化数据重建。
+// 输出前按调用方权限去掉无权查看的表单项，并屏蔽受限参数与机密参数的取值
+func (s *UploadService) GetVNFConfig(ctx context.Context, vnfID uint) (*VNFInstanceMongo, string, error) {
+	instance, source, err := s.dualStorage.GetVNFConfig(vnfID)
+	if err != nil {
+		return nil, "", err
+	}
+	policy, err := s.permissions.Policy(ctx, vnfID)
+	if err != nil {
+		return nil, "", err
+	}
+	config, ok := instance.YAMLConfig.(*YAMLConfig)
+	if !ok {
+		if policy.Empty() {
+			return instance, source, nil
+		}
+		return nil, "", errors.New("配置文档格式无法识别，不能按参数权限输出")
+	}
+	config = policy.FilterConfig(auth.FromContext(ctx), config)
+	instance.YAMLConfig, instance.FormFields = config, config.Fields
+	return instance, source, nil
+}
+
 func (s *UploadService) HandleZipUpload(c *gin.Context, fileHeader *multipart.FileHeader) (*UploadResult, error) {
 	result := &UploadResult{}
+	metrics.ObserveUpload(fileHeader.Size)
 
 	// 保存ZIP文件
 	uploadDir := defaultString(os.Getenv("UPLOAD_DIR"), "./data/uploads")
@@ -72,20 +102,32 @@ func (s *UploadService) HandleZipUpload(c *gin.Context, fileHeader *multipart.Fi
 	}
 	result.YAMLConfig = yamlConfig
 
+	// 权限声明无效时拒绝上传，避免参数回落到更宽松的默认权限
+	if err := ValidateDescriptorPermissions(yamlConfig); err != nil {
+		return nil, err
+	}
+	if err := requireSecretKey(yamlConfig); err != nil {
+		return nil, err
+	}
+
 	// 验证表单项
 	validationErrors := s.yamlParser.ValidateFormFields(yamlConfig)
 	if len(validationErrors) > 0 {
 		result.Errors = validationErrors
 	}
 
+	// 机密参数的默认值只以密文保存在参数定义中，配置文档与响应中不包含
+	storedConfig := withoutSecretDefaults(yamlConfig)
+	result.YAMLConfig = storedConfig
+
 	// 提取表单项
-	result.FormFields = yamlConfig.Fields
+	result.FormFields = storedConfig.Fields
 
 	// 创建VNF实例
 	instance := &model.VNFInstance{Name: strings.TrimSuffix(fileHeader.Filename, ".zip")}
 	
 	// 使用双数据库存储
-	storageResult := s.dualStorage.StoreVNFInstance(instance, yamlConfig)
+	storageResult := s.dualStorage.StoreVNFInstance(c, instance, storedConfig)
 	result.StorageResult = storageResult
 
 	if !storageResult.MySQLSuccess {
@@ -101,15 +143,36 @@ func (s *UploadService) HandleZipUpload(c *gin.Context, fileHeader *multipart.Fi
 
 	// 生成VNF定义
 	definitions := s.generateVNFDefinitions(yamlConfig, result.VNFInstance.ID)
-	result.Definitions = definitions
+	for i := range definitions {
+		if err := sealDefinition(&definitions[i]); err != nil {
+			return nil, fmt.Errorf("参数 %s 加密失败: %v", definitions[i].ParameterName, err)
+		}
+	}
 
 	// 存储VNF定义到双数据库
-	defStorageResult := s.dualStorage.StoreVNFDefinitions(definitions)
+	defStorageResult := s.dualStorage.StoreVNFDefinitions(c, definitions)
 	if !defStorageResult.MySQLSuccess {
 		result.Errors = append(result.Errors, fmt.Sprintf("VNF定义MySQL存储失败: %v", defStorageResult.MySQLError))
 	}
-	if !defStorageResult.MongoSuccess {
-		result.Errors = append(result.Errors, fmt.Sprintf("VNF定义MongoDB存储失败: %v", defStorageResult.MongoError))
+
+	result.Definitions = maskSecretDefinitions(definitions)
+
+	// 保存描述文件中声明的参数权限
+	if err := s.permissions.ApplyDescriptor(c, result.VNFInstance.ID, yamlConfig); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("参数权限保存失败: %v", err))
+	}
+	if defStorageResult.MongoPending {
+		// MongoDB写入已记录在发件箱中，后台重试直至一致，不再视为数据分歧
+		storageResult.MongoPending = true
+		storageResult.MongoSuccess = false
+		if storageResult.MongoError == nil {
+			storageResult.MongoError = defStorageResult.MongoError
+		}
+	}
+
+	// 保留原始安装包，导出归档时一同输出
+	if err := savePackage(result.VNFInstance.ID, filePath); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("原始安装包保存失败: %v", err))
 	}
 
 	// 清理临时文件
@@ -172,6 +235,11 @@ func (s *UploadService) generateVNFDefinitions(yamlConfig *YAMLConfig, vnfID uin
 				defaultValue = v
 			case int, float64, bool:
 				defaultValue = fmt.Sprintf("%v", v)
+			case []interface{}, map[string]interface{}:
+				// 数组与对象以JSON保存，渲染与GitOps导出时按类型还原
+				if b, err := json.Marshal(v); err == nil {
+					defaultValue = string(b)
+				}
 			default:
 				defaultValue = fmt.Sprintf("%v", v)
 			}
@@ -200,6 +268,7 @@ func (s *UploadService) generateVNFDefinitions(yamlConfig *YAMLConfig, vnfID uin
 			DefaultValue:    defaultValue,
 			DescriptionText: field.Description,
 			Type:            field.Type,
+			Group:           field.Group,
 			CanBeUpdated:    true, // 默认可更新
 			HiddenCondition: field.HiddenCondition,
 			Optional:        optional,
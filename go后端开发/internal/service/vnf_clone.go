@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"vnf-config/internal/dto"
+	"vnf-config/internal/infra/audit"
+	"vnf-config/internal/model"
+	"vnf-shared/auth"
+	"vnf-shared/secrets"
+)
+
+// CloneResult 克隆得到的实例及其参数定义（屏蔽规则同定义列表）
+type CloneResult struct {
+	Instance     *model.VNFInstance `json:"instance"`
+	Definitions  []DefinitionView   `json:"definitions"`
+	MongoPending bool               `json:"mongoPending"`
+}
+
+// Clone 以新名称复制VNF实例、全部参数定义、参数权限规则、审批策略、环境覆盖层与配置文档，并在同一事务中应用 overrides。
+// 覆盖值按源实例的权限规则检查修改权限，并与直接批量修改一样整组校验，任一参数不通过时返回 *ValidationError。
+// 待审批的变更单与变更历史不复制
+func (s *VNFService) Clone(ctx context.Context, id uint, req dto.VNFCloneRequest) (*CloneResult, error) {
+	source, err := s.store.Instances().Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	doc, _, err := s.dualStorage.GetVNFConfig(id)
+	if err != nil {
+		return nil, err
+	}
+	config, ok := doc.YAMLConfig.(*YAMLConfig)
+	if !ok {
+		return nil, errors.New("源实例的配置文档无法解析")
+	}
+	defs, err := s.store.Definitions().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.store.Permissions().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := s.store.ApprovalPolicies().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	overlays, err := s.store.Overlays().ListByVNF(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.permissions.Policy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	caller := auth.FromContext(ctx)
+
+	// 复制明文定义，覆盖值在明文上校验；机密取值由 StoreVNFClone 按新实例重新加密
+	clones := make([]model.VNFDefinition, len(defs))
+	byName := make(map[string]int, len(defs))
+	values := make(map[string]string, len(defs))
+	plain := make([]model.VNFDefinition, len(defs))
+	for i, def := range defs {
+		if plain[i], err = openDefinition(def); err != nil {
+			return nil, err
+		}
+		byName[def.ParameterName] = i
+		values[def.ParameterName] = plain[i].CurrentValue
+	}
+	names := make([]string, 0, len(req.Overrides))
+	for name := range req.Overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	verr := &ValidationError{}
+	changed := make(map[string]bool)
+	for _, name := range names {
+		value, ok := formatValue(req.Overrides[name])
+		if !ok {
+			verr.add(name, "取值须为字符串、数字或布尔值")
+			continue
+		}
+		i, exists := byName[name]
+		if !exists || !policy.Access(caller, name, defs[i].Group).CanView {
+			verr.add(name, "参数不存在")
+			continue
+		}
+		if !policy.Access(caller, name, defs[i].Group).CanEdit {
+			verr.add(name, ErrPermissionDenied.Error())
+			continue
+		}
+		if msg := validateValue(defs[i], value); msg != "" {
+			verr.add(name, msg)
+			continue
+		}
+		values[name], changed[name] = value, true
+	}
+	verr.checkRequired(defs, values, changed)
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	secret := make(map[string]bool)
+	for i, def := range defs {
+		item := plain[i]
+		if changed[def.ParameterName] {
+			item.CurrentValue = values[def.ParameterName]
+		}
+		secret[def.ParameterName] = def.Type == TypeSecret
+		item.Modified = values[def.ParameterName] != plain[i].DefaultValue
+		item.ID, item.VNFID = 0, 0
+		clones[i] = item
+	}
+	for i := range rules {
+		rules[i].ID, rules[i].VNFID = 0, 0
+	}
+	for i := range policies {
+		policies[i].ID, policies[i].VNFID = 0, 0
+	}
+	for _, o := range overlays {
+		for i, v := range o.Values {
+			if !secret[v.ParameterName] {
+				continue
+			}
+			if o.Values[i].Value, err = secrets.Default().Decrypt(secretOwner(id, v.ParameterName), v.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if req.Reason == "" && audit.Reason(ctx) == "" {
+		req.Reason = fmt.Sprintf("克隆自 VNF #%d（%s）", source.ID, source.Name)
+	}
+	ctx = audit.WithReason(ctx, req.Reason)
+	instance := &model.VNFInstance{Name: req.Name}
+	res := s.dualStorage.StoreVNFClone(ctx, instance, config, clones, rules, policies, overlays)
+	if !res.MySQLSuccess {
+		return nil, res.MySQLError
+	}
+
+	if path := packagePath(id); fileExists(path) {
+		if err := savePackage(instance.ID, path); err != nil {
+			log.Printf("复制VNF #%d 的原始安装包失败: %v", id, err)
+		}
+	}
+
+	clonePolicy, err := s.permissions.Policy(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := &CloneResult{Instance: instance, Definitions: make([]DefinitionView, 0, len(clones)), MongoPending: res.MongoPending}
+	for _, def := range clones {
+		result.Definitions = append(result.Definitions, clonePolicy.View(caller, def))
+	}
+	return result, nil
+}
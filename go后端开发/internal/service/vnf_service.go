@@ -2,37 +2,47 @@ package service
 
 import (
 	"context"
+	"io"
 
-	"vnf-config/internal/infra/db"
 	"vnf-config/internal/model"
+	"vnf-config/internal/repository"
 )
 
-type VNFService struct{}
+type VNFService struct {
+	store       repository.Store
+	dualStorage *DualStorageService
+	audit       *AuditService
+	permissions *PermissionService
+}
 
-func NewVNFService() *VNFService { return &VNFService{} }
+func NewVNFService(repos *repository.Repositories) *VNFService {
+	return &VNFService{store: repos.Store, dualStorage: NewDualStorageService(repos), audit: NewAuditService(repos), permissions: NewPermissionService(repos)}
+}
 
 func (s *VNFService) List(ctx context.Context, page, pageSize int, keyword string) ([]model.VNFInstance, int64, error) {
-	if page <= 0 { page = 1 }
-	if pageSize <= 0 || pageSize > 100 { pageSize = 10 }
-	var items []model.VNFInstance
-	q := db.MySQLDB.Model(&model.VNFInstance{})
-	if keyword != "" {
-		q = q.Where("name LIKE ?", "%"+keyword+"%")
-	}
-	var total int64
-	if err := q.Count(&total).Error; err != nil { return nil, 0, err }
-	if err := q.Order("id desc").Limit(pageSize).Offset((page-1)*pageSize).Find(&items).Error; err != nil { return nil, 0, err }
-	return items, total, nil
+	return s.store.Instances().List(ctx, page, pageSize, keyword)
 }
 
 func (s *VNFService) Get(ctx context.Context, id uint) (*model.VNFInstance, error) {
-	var item model.VNFInstance
-	if err := db.MySQLDB.First(&item, id).Error; err != nil { return nil, err }
-	return &item, nil
+	return s.store.Instances().Get(ctx, id)
 }
 
+// Delete 删除实例及其定义，并级联删除MongoDB中的文档与保留的原始安装包
 func (s *VNFService) Delete(ctx context.Context, id uint) error {
-	return db.MySQLDB.Delete(&model.VNFInstance{}, id).Error
+	if res := s.dualStorage.DeleteVNFInstance(ctx, id); !res.MySQLSuccess { return res.MySQLError }
+	removePackage(id)
+	return nil
 }
 
 
+
+// History 分页查询VNF实例及其参数定义的变更历史。
+// 不返回调用方无权查看的参数，屏蔽参数与机密参数的快照中不含取值
+func (s *VNFService) History(ctx context.Context, filter repository.HistoryFilter) ([]model.ChangeRecord, int64, error) {
+	return s.audit.List(ctx, filter)
+}
+
+// ExportHistory 按 format（csv、json）导出VNF的全部变更历史，过滤与屏蔽规则同 History
+func (s *VNFService) ExportHistory(ctx context.Context, filter repository.HistoryFilter, format string, w io.Writer) error {
+	return s.audit.Export(ctx, filter, format, w)
+}
@@ -3,10 +3,12 @@ package service
 import (
 	"fmt"
 	"os"
-	"reflect"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"vnf-config/internal/infra/metrics"
 )
 
 // YAMLParserService YAML解析服务
@@ -29,6 +31,7 @@ type FormField struct {
 	Options         []interface{}          `json:"options,omitempty"`
 	Group           string                 `json:"group,omitempty"`
 	Order           int                    `json:"order"`
+	Permissions     *PermissionSpec        `json:"permissions,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -36,13 +39,18 @@ type FormField struct {
 type YAMLConfig struct {
 	Fields    map[string]FormField `json:"fields"`
 	Groups    map[string]string    `json:"groups"`
+	// GroupPermissions 分组上声明的权限，作用于组内全部参数
+	GroupPermissions map[string]PermissionSpec `json:"groupPermissions,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata"`
 	Version   string               `json:"version"`
 	Schema    string               `json:"schema"`
 }
 
 // ParseYAMLFile 解析YAML文件并提取表单项
-func (s *YAMLParserService) ParseYAMLFile(filePath string) (*YAMLConfig, error) {
+func (s *YAMLParserService) ParseYAMLFile(filePath string) (config *YAMLConfig, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveParse(start, err) }()
+
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("读取YAML文件失败: %v", err)
@@ -53,7 +61,7 @@ func (s *YAMLParserService) ParseYAMLFile(filePath string) (*YAMLConfig, error)
 		return nil, fmt.Errorf("YAML解析失败: %v", err)
 	}
 
-	config := &YAMLConfig{
+	config = &YAMLConfig{
 		Fields:   make(map[string]FormField),
 		Groups:   make(map[string]string),
 		Metadata: make(map[string]interface{}),
@@ -81,6 +89,10 @@ func (s *YAMLParserService) parseNode(path string, node interface{}, config *YAM
 			if field.Name != "" {
 				config.Fields[field.Name] = field
 			}
+			// 对象的 properties 逐项解析为 "对象名.属性名" 表单项
+			if properties, ok := v["properties"].(map[string]interface{}); ok {
+				s.parseProperties(path, field.Group, properties, config, order)
+			}
 		} else {
 			// 递归解析子节点
 			for key, value := range v {
@@ -105,39 +117,54 @@ func (s *YAMLParserService) parseNode(path string, node interface{}, config *YAM
 	}
 }
 
-// isSpecialConfigNode 检查是否是特殊配置节点
+// parseProperties 解析对象表单项的子属性，未声明分组的子属性沿用对象的分组
+func (s *YAMLParserService) parseProperties(path, group string, properties map[string]interface{}, config *YAMLConfig, order int) {
+	for key, value := range properties {
+		childPath := s.buildPath(path, key)
+		s.parseNode(childPath, value, config, order+1)
+		if group == "" {
+			continue
+		}
+		for name, field := range config.Fields {
+			if field.Group == "" && (name == childPath || strings.HasPrefix(name, childPath+".")) {
+				field.Group = group
+				config.Fields[name] = field
+			}
+		}
+	}
+}
+
+// isSpecialConfigNode 检查是否是特殊配置节点。只匹配顶层键名本身，
+// 避免 database_config 之类的参数因名称包含关键字被当作声明节点丢弃
 func (s *YAMLParserService) isSpecialConfigNode(path string, node map[string]interface{}) bool {
 	specialKeys := []string{"metadata", "groups", "schema", "version", "config", "settings"}
 	for _, key := range specialKeys {
-		if strings.Contains(strings.ToLower(path), key) {
+		if strings.EqualFold(path, key) {
 			return true
 		}
 	}
 	return false
 }
 
-// parseSpecialConfig 解析特殊配置节点
+// parseSpecialConfig 解析特殊配置节点，path 为节点的键名
 func (s *YAMLParserService) parseSpecialConfig(path string, node map[string]interface{}, config *YAMLConfig) {
-	for key, value := range node {
-		switch key {
-		case "metadata":
-			if metadata, ok := value.(map[string]interface{}); ok {
-				config.Metadata = metadata
-			}
-		case "groups":
-			if groups, ok := value.(map[string]interface{}); ok {
-				for groupKey, groupValue := range groups {
-					if groupName, ok := groupValue.(string); ok {
-						config.Groups[groupKey] = groupName
+	switch strings.ToLower(path) {
+	case "metadata":
+		config.Metadata = node
+	case "groups":
+		for groupKey, groupValue := range node {
+			switch g := groupValue.(type) {
+			case string:
+				config.Groups[groupKey] = g
+			case map[string]interface{}:
+				// 分组可写为 {name: 显示名称, permissions: {...}}
+				name, _ := g["name"].(string)
+				config.Groups[groupKey] = defaultString(name, groupKey)
+				if spec := parsePermissionSpec(g["permissions"]); spec != nil {
+					if config.GroupPermissions == nil {
+						config.GroupPermissions = make(map[string]PermissionSpec)
 					}
-				}
-			}
-		case "schema", "version":
-			if strValue, ok := value.(string); ok {
-				if key == "schema" {
-					config.Schema = strValue
-				} else if key == "version" {
-					config.Version = strValue
+					config.GroupPermissions[groupKey] = *spec
 				}
 			}
 		}
@@ -149,6 +176,7 @@ func (s *YAMLParserService) hasFormFieldProperties(node map[string]interface{})
 	formFieldKeys := []string{
 		"type", "default", "description", "required", "hidden", "validation",
 		"options", "group", "order", "constraints", "can_be_update", "optional",
+		"permissions",
 	}
 	
 	for _, key := range formFieldKeys {
@@ -217,6 +245,10 @@ func (s *YAMLParserService) extractFormField(path string, node map[string]interf
 			if intValue, ok := value.(int); ok {
 				field.Order = intValue
 			}
+		case "permissions", "access":
+			field.Permissions = parsePermissionSpec(value)
+		case "properties":
+			// 子属性作为独立表单项解析，见 parseProperties
 		default:
 			// 其他属性作为元数据
 			field.Metadata[key] = value
@@ -231,6 +263,21 @@ func (s *YAMLParserService) extractFormField(path string, node map[string]interf
 	return field
 }
 
+// parsePermissionSpec 解析 permissions 节点，格式见 PermissionSpec
+func parsePermissionSpec(value interface{}) *PermissionSpec {
+	node, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	spec := &PermissionSpec{}
+	spec.View, _ = node["view"].(string)
+	spec.Edit, _ = node["edit"].(string)
+	if masked, ok := node["masked"].(bool); ok {
+		spec.Masked = &masked
+	}
+	return spec
+}
+
 // createSimpleField 创建简单字段
 func (s *YAMLParserService) createSimpleField(path string, value interface{}, order int) FormField {
 	return FormField{
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// 角色，权限依次递增：viewer 只读，operator 可修改配置，admin 保留给删除、管理等操作
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var roleLevel = map[string]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// ValidRole 是否为已知角色
+func ValidRole(role string) bool {
+	_, ok := roleLevel[role]
+	return ok
+}
+
+// HigherRole 返回两个角色中权限较高的一个，未知角色视为最低
+func HigherRole(a, b string) string {
+	if roleLevel[b] > roleLevel[a] {
+		return b
+	}
+	return a
+}
+
+// 认证方式
+const (
+	MethodToken     = "token"
+	MethodJWT       = "jwt"
+	MethodOIDC      = "oidc"
+	MethodAnonymous = "anonymous" // 已关闭认证（AUTH_DISABLED=true）
+	MethodGit       = "git"       // GitOps拉取的外部提交，作者未经验证
+)
+
+// ActorSystem 没有调用方的后台任务（定时对账等）记录的操作人
+const ActorSystem = "system"
+
+// Identity 已认证的调用方
+type Identity struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	Method  string   `json:"method"`
+}
+
+// HasRole 调用方是否具备 role 或更高的角色
+func (id *Identity) HasRole(role string) bool {
+	if id == nil {
+		return false
+	}
+	need := roleLevel[role]
+	for _, r := range id.Roles {
+		if roleLevel[r] >= need {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoCredentials 请求未携带该认证方式可识别的凭据，由下一个认证方式继续尝试
+var ErrNoCredentials = errors.New("未提供认证凭据")
+
+// Authenticator 从请求中识别调用方；凭据存在但无效时返回 ErrNoCredentials 以外的错误
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// chain 依次尝试多个认证方式，第一个识别出凭据的方式决定结果
+type chain []Authenticator
+
+func (c chain) Authenticate(r *http.Request) (*Identity, error) {
+	var lastErr error = ErrNoCredentials
+	for _, a := range c {
+		id, err := a.Authenticate(r)
+		if err == nil {
+			return id, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// anonymous 关闭认证时的调用方，拥有全部权限
+type anonymous struct{}
+
+func (anonymous) Authenticate(r *http.Request) (*Identity, error) {
+	return &Identity{Subject: "anonymous", Roles: []string{RoleAdmin}, Method: MethodAnonymous}, nil
+}
+
+// bearerToken 读取 Authorization: Bearer 头
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+type ctxKey struct{}
+
+// WithIdentity 将调用方放入上下文
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext 取出上下文中的调用方，不存在时返回 nil
+func FromContext(ctx context.Context) *Identity {
+	if ctx == nil {
+		return nil
+	}
+	id, _ := ctx.Value(ctxKey{}).(*Identity)
+	return id
+}
+
+// ActorFrom 变更记录中的操作人：调用方标识，后台任务为 "system"
+func ActorFrom(ctx context.Context) string {
+	if id := FromContext(ctx); id != nil {
+		return id.Subject
+	}
+	return ActorSystem
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Config 认证配置
+type Config struct {
+	// Providers 启用的认证方式：token、jwt、oidc，按顺序尝试
+	Providers []string
+	// Disabled 显式关闭认证（AUTH_DISABLED=true），所有请求以管理员身份放行，仅用于本地开发
+	Disabled     bool
+	TokensFile   string
+	JWKSFile     string
+	JWT          JWTConfig
+	OIDCIssuer   string
+	OIDCAudience string
+}
+
+// LoadConfig 从环境变量读取认证配置
+func LoadConfig() Config {
+	var providers []string
+	for _, p := range strings.Split(os.Getenv("AUTH_PROVIDERS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			providers = append(providers, p)
+		}
+	}
+	return Config{
+		Providers:  providers,
+		Disabled:   os.Getenv("AUTH_DISABLED") == "true",
+		TokensFile: os.Getenv("AUTH_TOKENS_FILE"),
+		JWKSFile:   os.Getenv("AUTH_JWKS_FILE"),
+		JWT: JWTConfig{
+			Issuer:       os.Getenv("AUTH_JWT_ISSUER"),
+			Audience:     os.Getenv("AUTH_JWT_AUDIENCE"),
+			RolesClaim:   os.Getenv("AUTH_ROLES_CLAIM"),
+			SubjectClaim: os.Getenv("AUTH_SUBJECT_CLAIM"),
+		},
+		OIDCIssuer:   os.Getenv("OIDC_ISSUER"),
+		OIDCAudience: os.Getenv("OIDC_AUDIENCE"),
+	}
+}
+
+// New 按配置构造认证器。未配置任何认证方式时拒绝启动，除非显式关闭认证，此时所有请求都以管理员身份放行
+func New(config Config) (Authenticator, error) {
+	if config.Disabled {
+		if len(config.Providers) > 0 {
+			return nil, errors.New("AUTH_DISABLED=true 时不能同时设置 AUTH_PROVIDERS")
+		}
+		log.Println("警告：已关闭认证（AUTH_DISABLED=true），所有请求均拥有管理员权限")
+		return anonymous{}, nil
+	}
+	if len(config.Providers) == 0 {
+		return nil, errors.New("未配置认证方式：请设置 AUTH_PROVIDERS，或设置 AUTH_DISABLED=true 显式关闭认证")
+	}
+
+	var c chain
+	for _, p := range config.Providers {
+		switch p {
+		case MethodToken:
+			if config.TokensFile == "" {
+				return nil, errors.New("启用token认证需要设置 AUTH_TOKENS_FILE")
+			}
+			a, err := LoadTokens(config.TokensFile)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, a)
+		case MethodJWT:
+			if config.JWKSFile == "" {
+				return nil, errors.New("启用jwt认证需要设置 AUTH_JWKS_FILE")
+			}
+			keys, err := newFileKeySet(config.JWKSFile)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, newJWTAuthenticator(MethodJWT, keys, config.JWT))
+		case MethodOIDC:
+			if config.OIDCIssuer == "" {
+				return nil, errors.New("启用oidc认证需要设置 OIDC_ISSUER")
+			}
+			// 同一签发方为其他客户端签发的令牌同样能通过验签，必须限定受众
+			if config.OIDCAudience == "" {
+				return nil, errors.New("启用oidc认证需要设置 OIDC_AUDIENCE")
+			}
+			oidc := config.JWT
+			oidc.Issuer, oidc.Audience = config.OIDCIssuer, config.OIDCAudience
+			c = append(c, newJWTAuthenticator(MethodOIDC, newOIDCKeySet(config.OIDCIssuer), oidc))
+		default:
+			return nil, fmt.Errorf("未知的认证方式: %s", p)
+		}
+	}
+	log.Printf("已启用认证: %s", strings.Join(config.Providers, ","))
+	return c, nil
+}
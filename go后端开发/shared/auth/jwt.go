@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig JWT校验配置
+type JWTConfig struct {
+	Issuer       string
+	Audience     string
+	RolesClaim   string // 角色声明，支持以点分隔的嵌套路径，如 realm_access.roles
+	SubjectClaim string
+}
+
+type jwtAuthenticator struct {
+	method string
+	keys   *keySet
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+func newJWTAuthenticator(method string, keys *keySet, config JWTConfig) *jwtAuthenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.SubjectClaim == "" {
+		config.SubjectClaim = "sub"
+	}
+	return &jwtAuthenticator{method: method, keys: keys, config: config, parser: jwt.NewParser(opts...)}
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	raw := bearerToken(r)
+	if raw == "" || strings.Count(raw, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(r.Context(), kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JWT无效: %v", err)
+	}
+
+	subject, _ := lookupClaim(claims, a.config.SubjectClaim).(string)
+	if subject == "" {
+		return nil, fmt.Errorf("JWT缺少 %s 声明", a.config.SubjectClaim)
+	}
+	return &Identity{Subject: subject, Roles: rolesFromClaim(lookupClaim(claims, a.config.RolesClaim)), Method: a.method}, nil
+}
+
+// lookupClaim 按点分隔路径读取嵌套声明
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var cur interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// rolesFromClaim 角色声明可以是字符串数组或以空格/逗号分隔的字符串，未知角色被忽略
+func rolesFromClaim(v interface{}) []string {
+	var names []string
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	case string:
+		names = strings.FieldsFunc(x, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+	roles := []string{}
+	for _, name := range names {
+		if ValidRole(name) {
+			roles = append(roles, name)
+		}
+	}
+	return roles
+}
+
+// keySet JWKS公钥集合。遇到未知 kid 时重新加载（密钥轮换），两次加载至少间隔 minRefresh
+type keySet struct {
+	mu         sync.Mutex
+	fetch      func(ctx context.Context) ([]byte, error)
+	keys       map[string]interface{}
+	loadedAt   time.Time
+	minRefresh time.Duration
+}
+
+// newFileKeySet 从本地JWKS文件加载公钥
+func newFileKeySet(path string) (*keySet, error) {
+	ks := &keySet{
+		fetch:      func(context.Context) ([]byte, error) { return os.ReadFile(path) },
+		minRefresh: 10 * time.Second,
+	}
+	if err := ks.reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *keySet) key(ctx context.Context, kid string) (interface{}, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if k, ok := ks.find(kid); ok {
+		return k, nil
+	}
+	if time.Since(ks.loadedAt) < ks.minRefresh {
+		return nil, fmt.Errorf("未知的密钥ID: %q", kid)
+	}
+	if err := ks.reloadLocked(ctx); err != nil {
+		return nil, err
+	}
+	if k, ok := ks.find(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("未知的密钥ID: %q", kid)
+}
+
+// find 令牌未指定 kid 且只有一个密钥时使用该密钥
+func (ks *keySet) find(kid string) (interface{}, bool) {
+	if k, ok := ks.keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+func (ks *keySet) reload(ctx context.Context) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.reloadLocked(ctx)
+}
+
+func (ks *keySet) reloadLocked(ctx context.Context) error {
+	ks.loadedAt = time.Now()
+	data, err := ks.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("加载JWKS失败: %v", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	ks.keys = keys
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS 解析RSA与EC公钥，忽略用于加密（use=enc）及不支持的密钥
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析JWKS失败: %v", err)
+	}
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		var pub interface{}
+		var err error
+		switch k.Kty {
+		case "RSA":
+			pub, err = rsaKey(k)
+		case "EC":
+			pub, err = ecKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JWKS密钥 %q 无效: %v", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS中没有可用的签名公钥")
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func ecKey(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("公钥不在曲线上")
+	}
+	return pub, nil
+}
+
+// newOIDCKeySet 通过 issuer 的发现文档获取 jwks_uri 并加载公钥。
+// 发现或加载失败不影响启动，在首次校验令牌时重试。
+func newOIDCKeySet(issuer string) *keySet {
+	issuer = strings.TrimSuffix(issuer, "/")
+	client := &http.Client{Timeout: 10 * time.Second}
+	var jwksURI string
+	ks := &keySet{minRefresh: 30 * time.Second}
+	ks.fetch = func(ctx context.Context) ([]byte, error) {
+		if jwksURI == "" {
+			var doc struct {
+				Issuer  string `json:"issuer"`
+				JWKSURI string `json:"jwks_uri"`
+			}
+			if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+				return nil, fmt.Errorf("OIDC发现失败: %v", err)
+			}
+			if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+				return nil, fmt.Errorf("OIDC发现文档的issuer不匹配: %s", doc.Issuer)
+			}
+			if doc.JWKSURI == "" {
+				return nil, errors.New("OIDC发现文档缺少jwks_uri")
+			}
+			jwksURI = doc.JWKSURI
+		}
+		return get(ctx, client, jwksURI)
+	}
+	if err := ks.reload(context.Background()); err != nil {
+		ks.loadedAt = time.Time{}
+		log.Printf("OIDC公钥暂不可用，将在首次认证时重试: %v", err)
+	}
+	return ks
+}
+
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s 返回 %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	data, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testJWKS 生成RSA密钥并写入只含其公钥的JWKS文件
+func testJWKS(t *testing.T, kid string) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+func bearerRequest(token string) *http.Request {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	key, path := testJWKS(t, "k1")
+	keys, err := newFileKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newJWTAuthenticator(MethodJWT, keys, JWTConfig{Issuer: "https://idp", Audience: "vnf", RolesClaim: "realm_access.roles"})
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "alice", "iss": "https://idp", "aud": "vnf",
+			"exp":          time.Now().Add(time.Hour).Unix(),
+			"realm_access": map[string]interface{}{"roles": []string{"operator", "unknown"}},
+		}
+	}
+
+	id, err := a.Authenticate(bearerRequest(sign(t, jwt.SigningMethodRS256, key, "k1", valid())))
+	if err != nil {
+		t.Fatalf("有效令牌被拒绝: %v", err)
+	}
+	if id.Subject != "alice" || id.Method != MethodJWT || !reflect.DeepEqual(id.Roles, []string{"operator"}) {
+		t.Fatalf("调用方不正确: %+v", id)
+	}
+
+	claims := func(edit func(jwt.MapClaims)) jwt.MapClaims {
+		c := valid()
+		edit(c)
+		return c
+	}
+	rejected := map[string]string{
+		"expired":       sign(t, jwt.SigningMethodRS256, key, "k1", claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+		"no exp":        sign(t, jwt.SigningMethodRS256, key, "k1", claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+		"wrong aud":     sign(t, jwt.SigningMethodRS256, key, "k1", claims(func(c jwt.MapClaims) { c["aud"] = "other" })),
+		"wrong iss":     sign(t, jwt.SigningMethodRS256, key, "k1", claims(func(c jwt.MapClaims) { c["iss"] = "https://evil" })),
+		"no sub":        sign(t, jwt.SigningMethodRS256, key, "k1", claims(func(c jwt.MapClaims) { delete(c, "sub") })),
+		"unknown kid":   sign(t, jwt.SigningMethodRS256, key, "k2", valid()),
+		"alg none":      sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "k1", valid()),
+		"alg HS256":     sign(t, jwt.SigningMethodHS256, key.PublicKey.N.Bytes(), "k1", valid()),
+		"tampered body": sign(t, jwt.SigningMethodRS256, key, "k1", valid()) + "x",
+	}
+	for name, token := range rejected {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.Authenticate(bearerRequest(token)); err == nil || errors.Is(err, ErrNoCredentials) {
+				t.Fatalf("应拒绝令牌，得到 %v", err)
+			}
+		})
+	}
+
+	// 不是JWT的令牌交给下一个认证方式
+	if _, err := a.Authenticate(bearerRequest("static-token")); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("非JWT令牌应返回 ErrNoCredentials，得到 %v", err)
+	}
+}
+
+func TestTokenAuthenticate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.yaml")
+	// sha256("s3cret")
+	data := "tokens:\n  - name: ci\n    sha256: 1ec1c26b50d5d3c58d9583181af8076655fe00756bf7285940ba3670f99fcba0\n    roles: [operator]\n  - name: dev\n    token: plain\n    roles: [viewer]\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadTokens(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := a.Authenticate(bearerRequest("plain"))
+	if err != nil || id.Subject != "dev" || !id.HasRole(RoleViewer) || id.HasRole(RoleOperator) {
+		t.Fatalf("明文令牌识别错误: %+v, %v", id, err)
+	}
+	if id, err := a.Authenticate(bearerRequest("s3cret")); err != nil || id.Subject != "ci" || !id.HasRole(RoleOperator) {
+		t.Fatalf("哈希令牌识别错误: %+v, %v", id, err)
+	}
+	if _, err := a.Authenticate(bearerRequest("wrong")); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("未知令牌应返回 ErrNoCredentials，得到 %v", err)
+	}
+}
+
+func TestNewRequiresProvider(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Fatal("未配置认证方式时应拒绝启动")
+	}
+	if _, err := New(Config{Disabled: true, Providers: []string{MethodToken}}); err == nil {
+		t.Fatal("AUTH_DISABLED 与 AUTH_PROVIDERS 同时设置时应拒绝启动")
+	}
+	if _, err := New(Config{Providers: []string{MethodOIDC}, OIDCIssuer: "https://idp.example.com"}); err == nil {
+		t.Fatal("启用oidc认证但未设置 OIDC_AUDIENCE 时应拒绝启动")
+	}
+	a, err := New(Config{Disabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := a.Authenticate(bearerRequest(""))
+	if err != nil || id.Method != MethodAnonymous || !id.HasRole(RoleAdmin) {
+		t.Fatalf("关闭认证时应以管理员身份放行: %+v, %v", id, err)
+	}
+}
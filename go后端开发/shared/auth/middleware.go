@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware 认证请求并把调用方放入请求上下文，认证失败返回401，realm 为 WWW-Authenticate 中的服务名
+func Middleware(a Authenticator, realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := a.Authenticate(c.Request)
+		if err != nil {
+			msg := err.Error()
+			if errors.Is(err, ErrNoCredentials) {
+				msg = "需要有效的认证凭据"
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		c.Request = c.Request.WithContext(WithIdentity(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// Require 要求调用方至少具备 role，否则返回403
+func Require(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !FromContext(c.Request.Context()).HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "需要 " + role + " 或更高权限"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Current 返回当前调用方，供 GET /auth/me 使用
+func Current(c *gin.Context) {
+	c.JSON(http.StatusOK, FromContext(c.Request.Context()))
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TokenEntry 静态API令牌，明文 token 与 sha256 二选一，推荐只保存哈希
+type TokenEntry struct {
+	Name   string   `yaml:"name"`
+	Token  string   `yaml:"token"`
+	SHA256 string   `yaml:"sha256"`
+	Roles  []string `yaml:"roles"`
+}
+
+type tokenAuthenticator struct {
+	entries []tokenDigest
+}
+
+type tokenDigest struct {
+	name   string
+	digest []byte
+	roles  []string
+}
+
+// LoadTokens 读取令牌文件：
+//
+//	tokens:
+//	  - name: ci
+//	    sha256: 9f86d08...   # echo -n <token> | sha256sum
+//	    roles: [operator]
+func LoadTokens(path string) (Authenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌文件失败: %v", err)
+	}
+	var file struct {
+		Tokens []TokenEntry `yaml:"tokens"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析令牌文件失败: %v", err)
+	}
+
+	a := &tokenAuthenticator{}
+	for i, e := range file.Tokens {
+		if e.Name == "" {
+			return nil, fmt.Errorf("第%d个令牌缺少name", i+1)
+		}
+		for _, r := range e.Roles {
+			if !ValidRole(r) {
+				return nil, fmt.Errorf("令牌 %s 的角色无效: %s", e.Name, r)
+			}
+		}
+		var digest []byte
+		switch {
+		case e.SHA256 != "":
+			if digest, err = hex.DecodeString(strings.TrimSpace(e.SHA256)); err != nil || len(digest) != sha256.Size {
+				return nil, fmt.Errorf("令牌 %s 的sha256无效", e.Name)
+			}
+		case e.Token != "":
+			sum := sha256.Sum256([]byte(e.Token))
+			digest = sum[:]
+		default:
+			return nil, fmt.Errorf("令牌 %s 缺少token或sha256", e.Name)
+		}
+		a.entries = append(a.entries, tokenDigest{name: e.Name, digest: digest, roles: e.Roles})
+	}
+	return a, nil
+}
+
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	sum := sha256.Sum256([]byte(token))
+	for _, e := range a.entries {
+		if subtle.ConstantTimeCompare(sum[:], e.digest) == 1 {
+			return &Identity{Subject: e.name, Roles: e.roles, Method: MethodToken}, nil
+		}
+	}
+	// 不是已知的静态令牌，交给JWT认证方式继续识别
+	return nil, ErrNoCredentials
+}
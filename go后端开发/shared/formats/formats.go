@@ -0,0 +1,525 @@
+// Package formats 在YAML文档与 JSON、TOML、.env、properties 等格式之间转换。
+//
+// .env 与 properties 为键值格式，文档按以下可逆规则扁平化：
+//   - 每个标量一行，键为从根到该标量的各级键名以 . 连接（即字段路径，如 server.cors.enabled）；
+//     .env 中以 __ 代替 .（SERVER 与 server 区分大小写）
+//   - 元素全为标量的数组作为一项，取值为YAML流式数组，如 ["*", 8080]（.env 中不含空格）
+//   - 取值文本按YAML标量读回即得原值：数字、布尔值原样输出，null 为空，字符串在会被读成其他类型
+//     （如 "8080"、"true"、空串）或含特殊字符时加双引号（JSON转义）；.env 中含 $ 或 ` 的字符串使用单引号
+//   - 空对象、元素含对象或数组的数组、含 . 或为空的键名（.env 另要求键名由字母、数字和单个 _ 组成）无法表示，返回 *Error
+//
+// Parse 按同样的规则读回；其他工具生成的键值文件中无法按YAML读取的取值作为字符串
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的格式
+const (
+	JSON       = "json"
+	TOML       = "toml"
+	Env        = "env"
+	Properties = "properties"
+	YAML       = "yaml"
+)
+
+var (
+	// ErrFormat 不支持的格式
+	ErrFormat = errors.New("format 须为 json、toml、env、properties 或 yaml")
+	// ErrUnrepresentable 文档结构无法以指定格式表示
+	ErrUnrepresentable = errors.New("文档结构无法以该格式表示")
+)
+
+// Error 文档中无法以 Format 表示的位置，Path 为字段路径（根节点为空）
+type Error struct {
+	Format string
+	Path   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "根节点"
+	}
+	return fmt.Sprintf("%s: %s 无法以 %s 表示，%s", ErrUnrepresentable, path, e.Format, e.Reason)
+}
+
+func (e *Error) Unwrap() error {
+	return ErrUnrepresentable
+}
+
+var (
+	contentTypes = map[string]string{
+		JSON:       "application/json; charset=utf-8",
+		TOML:       "application/toml; charset=utf-8",
+		Env:        "text/plain; charset=utf-8",
+		Properties: "text/x-java-properties; charset=utf-8",
+		YAML:       "application/yaml; charset=utf-8",
+	}
+	envSegment   = regexp.MustCompile(`^[A-Za-z0-9]+(_[A-Za-z0-9]+)*$`)
+	envPlain     = regexp.MustCompile(`^[A-Za-z0-9_./:,@%+=-]+$`)
+	propsInvalid = regexp.MustCompile(`[\s=:#!\\]`)
+)
+
+// Valid 是否为支持的格式
+func Valid(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// ContentType 格式对应的响应类型
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Marshal 按格式输出文档。doc 为YAML解码得到的值（map、[]interface{} 与标量），YAML 格式还可为 *yaml.Node 以保留键顺序与注释
+func Marshal(format string, doc interface{}) ([]byte, error) {
+	switch format {
+	case YAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case JSON:
+		v, err := normalize(format, "", doc)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case TOML:
+		v, err := normalize(format, "", doc)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := v.(map[string]interface{}); !ok {
+			return nil, &Error{Format: format, Reason: "根节点须为对象"}
+		}
+		out, err := toml.Marshal(v)
+		if err != nil {
+			return nil, &Error{Format: format, Reason: err.Error()}
+		}
+		return out, nil
+	case Env, Properties:
+		entries, err := Flatten(format, doc)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, e := range entries {
+			buf.WriteString(e.Key + "=" + e.Value + "\n")
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, ErrFormat
+}
+
+// normalize 统一map的键为字符串，并检查无法表示的取值：TOML 没有 null，各格式都不输出 NaN 与无穷大
+func normalize(format, path string, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			var err error
+			if m[k], err = normalize(format, join(path, k), item); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			key := fmt.Sprint(k)
+			var err error
+			if m[key], err = normalize(format, join(path, key), item); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, item := range x {
+			var err error
+			if list[i], err = normalize(format, join(path, "[]"), item); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case nil:
+		if format == TOML {
+			return nil, &Error{Format: format, Path: path, Reason: "TOML 没有 null"}
+		}
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, &Error{Format: format, Path: path, Reason: "不支持 NaN 与无穷大"}
+		}
+	}
+	return v, nil
+}
+
+func join(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+// Entry 扁平化后的一项：Path 为字段路径，Key 为输出的键，Value 为取值文本
+type Entry struct {
+	Path  string
+	Key   string
+	Value string
+}
+
+// Flatten 按包注释中的规则将文档扁平化为键值（format 为 Env 或 Properties），按路径排序
+func Flatten(format string, doc interface{}) ([]Entry, error) {
+	v, err := normalize(format, "", doc)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, &Error{Format: format, Reason: "根节点须为对象"}
+	}
+	var entries []Entry
+	if err := flatten(format, "", v, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func flatten(format, path string, v interface{}, entries *[]Entry) error {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) == 0 && path != "" {
+			return &Error{Format: format, Path: path, Reason: "空对象没有可输出的键"}
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "" || strings.Contains(k, ".") {
+				return &Error{Format: format, Path: join(path, k), Reason: "键名为空或含 ."}
+			}
+			if err := flatten(format, join(path, k), x[k], entries); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	key, err := Key(format, path)
+	if err != nil {
+		return err
+	}
+	value, err := Value(format, path, v)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, Entry{Path: path, Key: key, Value: value})
+	return nil
+}
+
+// Key 字段路径在键值格式中的键：.env 以 __ 连接各级键名，properties 即字段路径
+func Key(format, path string) (string, error) {
+	segments := strings.Split(path, ".")
+	switch format {
+	case Env:
+		for i, s := range segments {
+			if !envSegment.MatchString(s) || (i == 0 && s[0] >= '0' && s[0] <= '9') {
+				return "", &Error{Format: format, Path: path, Reason: "环境变量名只能由字母、数字和单个 _ 组成且不以数字开头"}
+			}
+		}
+		return strings.Join(segments, "__"), nil
+	case Properties:
+		if propsInvalid.MatchString(path) {
+			return "", &Error{Format: format, Path: path, Reason: "键名不能含空白或 = : # ! \\"}
+		}
+		return path, nil
+	}
+	return "", ErrFormat
+}
+
+// Value 标量或标量数组的取值文本，按YAML读回即得原值
+func Value(format, path string, v interface{}) (string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return scalar(format, path, v, false)
+	}
+	items := make([]string, len(list))
+	for i, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return "", &Error{Format: format, Path: path, Reason: "数组元素须为标量"}
+		}
+		text, err := scalar(format, path, item, true)
+		if err != nil {
+			return "", err
+		}
+		if item == nil {
+			text = "null"
+		}
+		items[i] = text
+	}
+	if format == Env {
+		// dotenv 不识别YAML数组，只能不加引号原样输出，此时空白、# 与变量引用会被改写
+		text := "[" + strings.Join(items, ",") + "]"
+		if strings.ContainsAny(text, " \t#$`") {
+			return "", &Error{Format: format, Path: path, Reason: "数组元素含空白、#、$ 或 ` 时无法在 .env 中原样表示"}
+		}
+		return text, nil
+	}
+	return "[" + strings.Join(items, ", ") + "]", nil
+}
+
+// scalar 输出单个标量；inArray 时字符串总是加引号
+func scalar(format, path string, v interface{}, inArray bool) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case uint64:
+		return strconv.FormatUint(x, 10), nil
+	case float64:
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case string:
+		if !inArray && plain(format, x) {
+			return x, nil
+		}
+		if format == Env && strings.ContainsAny(x, "$`") {
+			return envQuote(format, path, x)
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(x); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+	return scalar(format, path, fmt.Sprint(v), inArray)
+}
+
+// plain 字符串能否不加引号输出：按YAML读回仍为同一字符串且不含注释、引号、转义等特殊字符
+func plain(format, s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, "#\"'\\") {
+		return false
+	}
+	if format == Env && !envPlain.MatchString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return false
+	}
+	back, ok := v.(string)
+	return ok && back == s
+}
+
+// envQuote 以单引号输出，dotenv 与 shell 都不会展开其中的 $ 与 `
+func envQuote(format, path, s string) (string, error) {
+	if strings.ContainsAny(s, "'\n\r") {
+		return "", &Error{Format: format, Path: path, Reason: "同时含 $ 或 ` 与单引号或换行的取值无法在 .env 中原样表示"}
+	}
+	return "'" + s + "'", nil
+}
+
+// Parse 读取文档，返回的文档以对象为根，数字统一为 int64 或 float64；.env 与 properties 按扁平化规则还原嵌套结构
+func Parse(format string, data []byte) (map[string]interface{}, error) {
+	var doc interface{}
+	switch format {
+	case JSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %v", err)
+		}
+	case TOML:
+		var m map[string]interface{}
+		if err := toml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("解析TOML失败: %v", err)
+		}
+		doc = m
+	case YAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("解析YAML失败: %v", err)
+		}
+	case Env, Properties:
+		return parseFlat(format, data)
+	default:
+		return nil, ErrFormat
+	}
+	m, ok := plainValue(doc).(map[string]interface{})
+	if !ok {
+		return nil, &Error{Format: format, Reason: "根节点须为对象"}
+	}
+	return m, nil
+}
+
+// plainValue 统一解码结果中的类型：map 的键为字符串，整数为 int64，JSON数字按是否为整数转换，日期时间为字符串
+func plainValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			x[k] = plainValue(item)
+		}
+		return x
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			m[fmt.Sprint(k)] = plainValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range x {
+			x[i] = plainValue(item)
+		}
+		return x
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	case int:
+		return int64(x)
+	case uint64:
+		if x <= math.MaxInt64 {
+			return int64(x)
+		}
+		return float64(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		return fmt.Sprint(x)
+	}
+	return v
+}
+
+// parseFlat 读取键值文件：忽略空行与注释（# 开头，properties 另有 !），.env 允许 export 前缀；
+// properties 的键与取值以第一个 = 或 : 分隔
+func parseFlat(format string, data []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || line[0] == '#' || (format == Properties && line[0] == '!') {
+			continue
+		}
+		sep := strings.IndexByte(line, '=')
+		if format == Env {
+			line = strings.TrimPrefix(line, "export ")
+			sep = strings.IndexByte(line, '=')
+		} else if i := strings.IndexByte(line, ':'); i >= 0 && (sep < 0 || i < sep) {
+			sep = i
+		}
+		if sep <= 0 {
+			return nil, fmt.Errorf("第 %d 行不是 key=value", n+1)
+		}
+		key, text := strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+		path := key
+		if format == Env {
+			path = strings.ReplaceAll(key, "__", ".")
+		}
+		if err := setPath(format, doc, path, ParseValue(text)); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// ParseValue 将键值格式中的取值文本按YAML读回：空为 null，标量与标量数组按YAML类型，其余按原文作为字符串
+func ParseValue(text string) interface{} {
+	if text == "" {
+		return nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+		return text
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return text
+	case []interface{}:
+		for _, item := range x {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return text
+			}
+		}
+	case nil:
+		if strings.HasPrefix(text, "#") {
+			return text
+		}
+	}
+	return plainValue(v)
+}
+
+// setPath 按字段路径写入嵌套文档，同一路径既是取值又是上级时返回 *Error
+func setPath(format string, doc map[string]interface{}, path string, value interface{}) error {
+	parts := strings.Split(path, ".")
+	node := doc
+	for i, part := range parts {
+		if part == "" {
+			return &Error{Format: format, Path: path, Reason: "键名为空"}
+		}
+		if i == len(parts)-1 {
+			if _, exists := node[part]; exists {
+				return &Error{Format: format, Path: path, Reason: "键重复或同时是其他键的上级"}
+			}
+			node[part] = value
+			return nil
+		}
+		child, ok := node[part].(map[string]interface{})
+		if !ok {
+			if _, exists := node[part]; exists {
+				return &Error{Format: format, Path: path, Reason: strings.Join(parts[:i+1], ".") + " 同时有取值"}
+			}
+			child = make(map[string]interface{})
+			node[part] = child
+		}
+		node = child
+	}
+	return nil
+}
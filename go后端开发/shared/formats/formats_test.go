@@ -0,0 +1,128 @@
+package formats
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// sampleDoc 覆盖各类可表示取值的文档，数字已统一为 int64 与 float64（即 Parse 的输出形式）
+func sampleDoc() map[string]interface{} {
+	return map[string]interface{}{
+		"server": map[string]interface{}{
+			"name":    "vnf-server-01",
+			"port":    int64(8080),
+			"ratio":   0.75,
+			"offset":  int64(-3),
+			"enabled": true,
+			"cors": map[string]interface{}{
+				"origins": []interface{}{"*", "https://a.example.com"},
+				"ports":   []interface{}{int64(80), int64(443)},
+			},
+		},
+		"database_config": map[string]interface{}{
+			"host":     "localhost",
+			"password": "p@ss w0rd#1 $HOME `id`",
+			"port":     int64(3306),
+		},
+		"quoted": map[string]interface{}{
+			"number":  "8080",
+			"bool":    "true",
+			"null":    "null",
+			"empty":   "",
+			"spaces":  "  padded  ",
+			"comment": "# not a comment",
+			"quote":   `say "hi"`,
+			"colon":   "a: b",
+			"unicode": "数据库配置",
+			"newline": "line1\nline2",
+			"cron":    "0 2 * * *",
+		},
+		"log_level": "info",
+		"flags":     []interface{}{true, false},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{JSON, TOML, Env, Properties, YAML} {
+		t.Run(format, func(t *testing.T) {
+			doc := sampleDoc()
+			data, err := Marshal(format, doc)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			back, err := Parse(format, data)
+			if err != nil {
+				t.Fatalf("Parse: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(back, sampleDoc()) {
+				t.Fatalf("读回的文档与原文档不同\n输出:\n%s\n读回: %#v", data, back)
+			}
+		})
+	}
+}
+
+func TestRoundTripNull(t *testing.T) {
+	doc := map[string]interface{}{"a": map[string]interface{}{"b": nil}, "c": "x"}
+	for _, format := range []string{JSON, Env, Properties, YAML} {
+		data, err := Marshal(format, doc)
+		if err != nil {
+			t.Fatalf("%s Marshal: %v", format, err)
+		}
+		back, err := Parse(format, data)
+		if err != nil {
+			t.Fatalf("%s Parse: %v", format, err)
+		}
+		if !reflect.DeepEqual(back, doc) {
+			t.Fatalf("%s 读回 %#v", format, back)
+		}
+	}
+	if _, err := Marshal(TOML, doc); !errors.Is(err, ErrUnrepresentable) {
+		t.Fatalf("TOML 不能表示 null，得到 %v", err)
+	}
+}
+
+func TestUnrepresentable(t *testing.T) {
+	cases := []struct {
+		name    string
+		formats []string
+		doc     map[string]interface{}
+	}{
+		{"空对象", []string{Env, Properties}, map[string]interface{}{"a": map[string]interface{}{}}},
+		{"对象数组", []string{Env, Properties}, map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": int64(1)}}}},
+		{"嵌套数组", []string{Env, Properties}, map[string]interface{}{"a": []interface{}{[]interface{}{int64(1)}}}},
+		{"键含点", []string{Env, Properties}, map[string]interface{}{"a.b": int64(1)}},
+		{"env 键名", []string{Env}, map[string]interface{}{"a-b": int64(1)}},
+		{"NaN", []string{JSON, TOML, Env, Properties}, map[string]interface{}{"a": math.NaN()}},
+	}
+	for _, c := range cases {
+		for _, format := range c.formats {
+			if _, err := Marshal(format, c.doc); !errors.Is(err, ErrUnrepresentable) {
+				t.Errorf("%s 以 %s 输出应返回 ErrUnrepresentable，得到 %v", c.name, format, err)
+			}
+		}
+	}
+}
+
+func TestParseFlat(t *testing.T) {
+	data := "# comment\nexport SERVER__PORT=8080\nSERVER__NAME=web\nHOSTS=[\"a\",\"b\"]\nRAW=not [valid\n"
+	doc, err := Parse(Env, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"SERVER": map[string]interface{}{"PORT": int64(8080), "NAME": "web"},
+		"HOSTS":  []interface{}{"a", "b"},
+		"RAW":    "not [valid",
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("读回 %#v", doc)
+	}
+	if _, err := Parse(Properties, []byte("a=1\na.b=2\n")); !errors.Is(err, ErrUnrepresentable) {
+		t.Fatalf("同一路径既是取值又是上级时应返回 ErrUnrepresentable，得到 %v", err)
+	}
+	if _, err := Parse(Properties, []byte("novalue\n")); err == nil {
+		t.Fatal("不是 key=value 的行应报错")
+	}
+}
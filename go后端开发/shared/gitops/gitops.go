@@ -0,0 +1,341 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configKey 仓库配置中记录最近一次拉取位置的键
+const configKey = "vnf-config.pulled"
+
+// ErrDisabled 未配置 GITOPS_REPO_PATH
+var ErrDisabled = errors.New("未启用GitOps同步（GITOPS_REPO_PATH）")
+
+// Config GitOps同步配置
+type Config struct {
+	Path        string // 本地仓库路径，为空时不启用
+	Branch      string
+	Remote      string // 远端名称，设置时提交后推送，拉取前先从远端变基
+	Committer   Author
+	EmailDomain string // 操作人标识不是邮箱时使用的邮箱域名
+}
+
+// LoadConfig 从环境变量读取配置：GITOPS_REPO_PATH、GITOPS_BRANCH（默认 main）、GITOPS_REMOTE、
+// GITOPS_COMMITTER_NAME、GITOPS_COMMITTER_EMAIL 与 GITOPS_EMAIL_DOMAIN
+func LoadConfig() Config {
+	return Config{
+		Path:   os.Getenv("GITOPS_REPO_PATH"),
+		Branch: envDefault("GITOPS_BRANCH", "main"),
+		Remote: os.Getenv("GITOPS_REMOTE"),
+		Committer: Author{
+			Name:  envDefault("GITOPS_COMMITTER_NAME", "vnf-config"),
+			Email: envDefault("GITOPS_COMMITTER_EMAIL", "vnf-config@localhost"),
+		},
+		EmailDomain: envDefault("GITOPS_EMAIL_DOMAIN", "vnf-config.local"),
+	}
+}
+
+func envDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// Author 提交的作者或提交者
+type Author struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Commit 仓库中的一次提交，Files 为该提交修改的文件
+type Commit struct {
+	Hash      string    `json:"hash"`
+	Author    Author    `json:"author"`
+	Committer Author    `json:"committer"`
+	Time      time.Time `json:"time"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body,omitempty"`
+	Files     []string  `json:"files"`
+}
+
+// Repo 通过 git 命令行操作的本地仓库，提交与拉取串行执行
+type Repo struct {
+	cfg Config
+	mu  sync.Mutex
+}
+
+// Open 打开配置的仓库，目录不是git仓库的根目录时在 Branch 上初始化；Path 为空时返回 nil。
+// 目录位于其他仓库（如应用自身的检出目录）之内时同样初始化，提交不会写入外层仓库
+func Open(cfg Config) (*Repo, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("未找到git命令: %v", err)
+	}
+	if err := os.MkdirAll(cfg.Path, 0755); err != nil {
+		return nil, err
+	}
+	root, err := canonicalPath(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Repo{cfg: cfg}
+	ctx := context.Background()
+	top, err := r.git(ctx, nil, "rev-parse", "--show-toplevel")
+	if err == nil {
+		top, err = canonicalPath(top)
+	}
+	if err != nil || top != root {
+		if _, err := r.git(ctx, nil, "init", "-q", "-b", cfg.Branch); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Remote != "" {
+		if _, err := r.git(ctx, nil, "remote", "get-url", cfg.Remote); err != nil {
+			return nil, fmt.Errorf("远端 %s 不存在: %v", cfg.Remote, err)
+		}
+	}
+	return r, nil
+}
+
+// canonicalPath 绝对路径并解析符号链接，用于比较仓库根目录
+func canonicalPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+var (
+	defaultMu   sync.RWMutex
+	defaultRepo *Repo
+)
+
+// Init 按环境变量打开进程级仓库，启动时调用
+func Init() error {
+	r, err := Open(LoadConfig())
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		log.Printf("GitOps同步已启用: %s（分支 %s）", r.cfg.Path, r.cfg.Branch)
+	}
+	SetDefault(r)
+	return nil
+}
+
+// SetDefault 替换进程级仓库，nil 表示不启用
+func SetDefault(r *Repo) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRepo = r
+}
+
+// Default 进程级仓库，未启用时为 nil
+func Default() *Repo {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRepo
+}
+
+// Path 本地仓库路径
+func (r *Repo) Path() string {
+	return r.cfg.Path
+}
+
+// Committer 本服务提交时使用的提交者，拉取时据此跳过本服务生成的提交
+func (r *Repo) Committer() Author {
+	return r.cfg.Committer
+}
+
+// AuthorFor 由操作人标识构造提交作者，标识不是邮箱时使用配置的邮箱域名；
+// 拉取外部提交时记录的 git:<邮箱> 还原为原作者邮箱
+func (r *Repo) AuthorFor(subject string) Author {
+	subject = strings.TrimPrefix(subject, "git:")
+	if strings.Contains(subject, "@") {
+		return Author{Name: subject, Email: subject}
+	}
+	return Author{Name: subject, Email: subject + "@" + r.cfg.EmailDomain}
+}
+
+// Commit 在仓库锁内调用 write 写入工作区（root 为仓库根目录）并取得提交说明，暂存 scope 下的全部改动并以 author 提交；
+// scope 下没有改动时不提交，返回空的 hash。配置了远端时提交后推送，推送失败时返回已提交的 hash 与错误
+func (r *Repo) Commit(ctx context.Context, scope string, author Author, write func(root string) (message string, err error)) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message, err := write(r.cfg.Path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(r.cfg.Path, scope)); os.IsNotExist(err) {
+		// 工作区与索引中都没有 scope 时无需提交（如从未渲染过的实例被删除）
+		if tracked, _ := r.git(ctx, nil, "ls-files", "--", scope); tracked == "" {
+			return "", nil
+		}
+	}
+	if _, err := r.git(ctx, nil, "add", "-A", "--", scope); err != nil {
+		return "", err
+	}
+	if _, err := r.git(ctx, nil, "diff", "--cached", "--quiet", "--", scope); err == nil {
+		return "", nil
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + author.Name, "GIT_AUTHOR_EMAIL=" + author.Email,
+		"GIT_COMMITTER_NAME=" + r.cfg.Committer.Name, "GIT_COMMITTER_EMAIL=" + r.cfg.Committer.Email,
+	}
+	if _, err := r.gitInput(ctx, env, message, "commit", "-q", "-F", "-", "--", scope); err != nil {
+		return "", err
+	}
+	hash, err := r.git(ctx, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if r.cfg.Remote != "" {
+		if _, err := r.git(ctx, nil, "push", "-q", r.cfg.Remote, "HEAD:"+r.cfg.Branch); err != nil {
+			return hash, fmt.Errorf("推送失败: %v", err)
+		}
+	}
+	return hash, nil
+}
+
+// Update 配置了远端时从远端拉取并将本地提交变基到其上，失败时放弃变基
+func (r *Repo) Update(ctx context.Context) error {
+	if r.cfg.Remote == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.git(ctx, nil, "pull", "-q", "--rebase", r.cfg.Remote, r.cfg.Branch); err != nil {
+		_, _ = r.git(ctx, nil, "rebase", "--abort")
+		return fmt.Errorf("从远端拉取失败: %v", err)
+	}
+	return nil
+}
+
+// Head 当前提交，仓库还没有提交时为空
+func (r *Repo) Head(ctx context.Context) (string, error) {
+	out, err := r.git(ctx, nil, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return "", nil
+	}
+	return out, nil
+}
+
+// Commits 返回 since（不含）到 HEAD 之间修改了 paths 的提交，按时间从旧到新；since 为空时返回全部
+func (r *Repo) Commits(ctx context.Context, since string, paths ...string) ([]Commit, error) {
+	rng := "HEAD"
+	if since != "" {
+		rng = since + "..HEAD"
+	}
+	out, err := r.git(ctx, nil, append([]string{"rev-list", "--reverse", rng, "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, hash := range strings.Fields(out) {
+		c, err := r.commit(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, *c)
+	}
+	return commits, nil
+}
+
+func (r *Repo) commit(ctx context.Context, hash string) (*Commit, error) {
+	out, err := r.git(ctx, nil, "show", "-s", "--format=%H%x00%an%x00%ae%x00%cn%x00%ce%x00%aI%x00%s%x00%b", hash)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(out, "\x00", 8)
+	if len(parts) < 8 {
+		return nil, fmt.Errorf("无法解析提交 %s", hash)
+	}
+	c := &Commit{
+		Hash:      parts[0],
+		Author:    Author{Name: parts[1], Email: parts[2]},
+		Committer: Author{Name: parts[3], Email: parts[4]},
+		Subject:   parts[6],
+		Body:      strings.TrimSpace(parts[7]),
+	}
+	c.Time, _ = time.Parse(time.RFC3339, parts[5])
+	files, err := r.git(ctx, nil, "diff-tree", "--no-commit-id", "--name-only", "-r", "--root", hash)
+	if err != nil {
+		return nil, err
+	}
+	c.Files = strings.Fields(files)
+	return c, nil
+}
+
+// Show 读取 rev 中的文件内容，文件不存在时 ok 为 false
+func (r *Repo) Show(ctx context.Context, rev, path string) (data []byte, ok bool, err error) {
+	if _, err := r.git(ctx, nil, "cat-file", "-e", rev+":"+path); err != nil {
+		return nil, false, nil
+	}
+	out, err := r.gitRaw(ctx, nil, "", "show", rev+":"+path)
+	return out, err == nil, err
+}
+
+// Files 列出 rev 中 dir 下的全部文件（含子目录），仓库还没有提交时返回空
+func (r *Repo) Files(ctx context.Context, rev, dir string) ([]string, error) {
+	if _, err := r.git(ctx, nil, "rev-parse", "--verify", "-q", rev); err != nil {
+		return nil, nil
+	}
+	out, err := r.git(ctx, nil, "ls-tree", "-r", "-z", "--name-only", rev, "--", dir)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(out, "\x00"), "\x00"), nil
+}
+
+// Pulled 最近一次拉取处理到的提交，记录在仓库配置中
+func (r *Repo) Pulled(ctx context.Context) string {
+	out, _ := r.git(ctx, nil, "config", "--get", configKey)
+	return out
+}
+
+// SetPulled 记录拉取处理到的提交
+func (r *Repo) SetPulled(ctx context.Context, hash string) error {
+	_, err := r.git(ctx, nil, "config", configKey, hash)
+	return err
+}
+
+func (r *Repo) git(ctx context.Context, env []string, args ...string) (string, error) {
+	return r.gitInput(ctx, env, "", args...)
+}
+
+func (r *Repo) gitInput(ctx context.Context, env []string, input string, args ...string) (string, error) {
+	out, err := r.gitRaw(ctx, env, input, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitRaw 执行git命令，失败时错误中包含标准错误输出
+func (r *Repo) gitRaw(ctx context.Context, env []string, input string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = filepath.Clean(r.cfg.Path)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C"), env...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,124 @@
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func openTemp(t *testing.T, path string) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("未找到git命令")
+	}
+	r, err := Open(Config{
+		Path:        path,
+		Branch:      "main",
+		Committer:   Author{Name: "vnf-config", Email: "vnf-config@localhost"},
+		EmailDomain: "vnf-config.local",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func writeFile(name, content string) func(root string) (string, error) {
+	return func(root string) (string, error) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			return "", err
+		}
+		return "更新 " + name, os.WriteFile(filepath.Join(root, name), []byte(content), 0644)
+	}
+}
+
+// TestCommitAndHistory 提交、读取历史与文件内容，没有改动时不提交
+func TestCommitAndHistory(t *testing.T) {
+	ctx := context.Background()
+	r := openTemp(t, t.TempDir())
+	if head, _ := r.Head(ctx); head != "" {
+		t.Fatalf("新仓库不应有提交: %s", head)
+	}
+
+	author := r.AuthorFor("alice")
+	first, err := r.Commit(ctx, "vnfs", author, writeFile("vnfs/a.yaml", "a: 1\n"))
+	if err != nil || first == "" {
+		t.Fatalf("首次提交失败: %q, %v", first, err)
+	}
+	second, err := r.Commit(ctx, "vnfs", author, writeFile("vnfs/b/c.yaml", "c: 2\n"))
+	if err != nil || second == "" {
+		t.Fatalf("第二次提交失败: %q, %v", second, err)
+	}
+	if hash, err := r.Commit(ctx, "vnfs", author, writeFile("vnfs/a.yaml", "a: 1\n")); err != nil || hash != "" {
+		t.Fatalf("没有改动时不应提交: %q, %v", hash, err)
+	}
+	if hash, err := r.Commit(ctx, "missing", author, func(string) (string, error) { return "无", nil }); err != nil || hash != "" {
+		t.Fatalf("不存在的范围不应提交: %q, %v", hash, err)
+	}
+
+	commits, err := r.Commits(ctx, "", "vnfs")
+	if err != nil || len(commits) != 2 {
+		t.Fatalf("应有2个提交: %+v, %v", commits, err)
+	}
+	c := commits[0]
+	if c.Hash != first || c.Subject != "更新 vnfs/a.yaml" || c.Author.Email != "alice@vnf-config.local" ||
+		c.Committer.Name != "vnf-config" || len(c.Files) != 1 || c.Files[0] != "vnfs/a.yaml" {
+		t.Fatalf("提交信息不正确: %+v", c)
+	}
+	if since, err := r.Commits(ctx, first, "vnfs"); err != nil || len(since) != 1 || since[0].Hash != second {
+		t.Fatalf("应只返回 first 之后的提交: %+v, %v", since, err)
+	}
+	if _, err := r.Commits(ctx, "0000000000000000000000000000000000000000"); err == nil {
+		t.Fatal("起点不存在时应返回错误")
+	}
+
+	data, ok, err := r.Show(ctx, first, "vnfs/a.yaml")
+	if err != nil || !ok || string(data) != "a: 1\n" {
+		t.Fatalf("读取文件失败: %q, %v, %v", data, ok, err)
+	}
+	if _, ok, err := r.Show(ctx, first, "vnfs/b/c.yaml"); ok || err != nil {
+		t.Fatalf("first 中不存在的文件 ok 应为 false: %v", err)
+	}
+	files, err := r.Files(ctx, "HEAD", "vnfs")
+	if err != nil || len(files) != 2 || files[0] != "vnfs/a.yaml" || files[1] != "vnfs/b/c.yaml" {
+		t.Fatalf("文件列表不正确: %v, %v", files, err)
+	}
+
+	if err := r.SetPulled(ctx, second); err != nil || r.Pulled(ctx) != second {
+		t.Fatalf("拉取位置记录失败: %v", err)
+	}
+}
+
+// TestOpenInsideAnotherRepo 路径位于其他仓库之内时初始化独立仓库，提交不写入外层仓库
+func TestOpenInsideAnotherRepo(t *testing.T) {
+	ctx := context.Background()
+	outerPath := t.TempDir()
+	outer := openTemp(t, outerPath)
+	if _, err := outer.Commit(ctx, "app.txt", Author{Name: "dev", Email: "dev@example.com"}, writeFile("app.txt", "app\n")); err != nil {
+		t.Fatal(err)
+	}
+	outerHead, _ := outer.Head(ctx)
+
+	innerPath := filepath.Join(outerPath, "deploy", "gitops")
+	inner := openTemp(t, innerPath)
+	if _, err := os.Stat(filepath.Join(innerPath, ".git")); err != nil {
+		t.Fatalf("应在配置的路径初始化仓库: %v", err)
+	}
+	if _, err := inner.Commit(ctx, "vnfs", inner.AuthorFor("alice"), writeFile("vnfs/a.yaml", "a: 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := outer.Head(ctx); head != outerHead {
+		t.Fatalf("外层仓库不应有新的提交: %s", head)
+	}
+	if commits, err := inner.Commits(ctx, ""); err != nil || len(commits) != 1 {
+		t.Fatalf("内层仓库应有1个提交: %+v, %v", commits, err)
+	}
+
+	// 再次打开已初始化的仓库不重新初始化
+	again := openTemp(t, innerPath)
+	if head, _ := again.Head(ctx); head == "" {
+		t.Fatal("再次打开后应保留已有提交")
+	}
+}
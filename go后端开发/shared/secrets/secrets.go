@@ -0,0 +1,344 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 密文格式：enc:v2:<主密钥ID>:<base64(被主密钥加密的数据密钥)>:<base64(随机数+密文)>。
+// 每个值使用独立的随机数据密钥加密（信封加密），轮换主密钥时只需重新加密数据密钥。
+// 数据以所属对象 owner（如 vnf:1/db_password）作为附加认证数据，密文移到其他参数或实例后无法解密
+const prefix = "enc:v2:"
+
+// Prefix 密文的前缀，用于在存储中查找含密文的记录
+const Prefix = prefix
+
+var (
+	// ErrNoKey 未配置主密钥，无法写入机密值
+	ErrNoKey = errors.New("未配置加密密钥（SECRET_KEYS_FILE 或 SECRET_KEY）")
+	// ErrNoOwner 加解密时未指定密文的所属对象
+	ErrNoOwner = errors.New("未指定密文的所属对象")
+)
+
+// Keyring 主密钥集合：新值使用 primary 加密，其余密钥仅用于解密轮换前写入的值
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring 构造密钥集合，密钥长度须为16、24或32字节
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("无效的密钥ID: %q", id)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("密钥 %s 长度应为16、24或32字节，实际 %d", id, len(key))
+		}
+	}
+	if len(keys) > 0 {
+		if _, ok := keys[primary]; !ok {
+			return nil, fmt.Errorf("主密钥 %q 不在密钥列表中", primary)
+		}
+	}
+	return &Keyring{primary: primary, keys: keys}, nil
+}
+
+// Load 读取密钥配置：SECRET_KEYS_FILE 指向的密钥文件优先，其次是 SECRET_KEY（base64）与 SECRET_KEY_ID。
+// 密钥文件格式：
+//
+//	primary: 2026-10
+//	keys:
+//	  2026-09: <base64>   # 轮换前的旧密钥，仅用于解密
+//	  2026-10: <base64>   # openssl rand -base64 32
+func Load() (*Keyring, error) {
+	if path := os.Getenv("SECRET_KEYS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		var file struct {
+			Primary string            `yaml:"primary"`
+			Keys    map[string]string `yaml:"keys"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析密钥文件失败: %v", err)
+		}
+		if len(file.Keys) == 0 {
+			return nil, errors.New("密钥文件中没有密钥")
+		}
+		keys := make(map[string][]byte, len(file.Keys))
+		for id, encoded := range file.Keys {
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				return nil, fmt.Errorf("密钥 %s 不是有效的base64: %v", id, err)
+			}
+			keys[id] = key
+		}
+		return NewKeyring(file.Primary, keys)
+	}
+	if encoded := os.Getenv("SECRET_KEY"); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("SECRET_KEY 不是有效的base64: %v", err)
+		}
+		id := os.Getenv("SECRET_KEY_ID")
+		if id == "" {
+			id = "default"
+		}
+		return NewKeyring(id, map[string][]byte{id: key})
+	}
+	return &Keyring{}, nil
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring = &Keyring{}
+)
+
+// Init 从环境变量加载进程级密钥集合，启动时调用
+func Init() error {
+	k, err := Load()
+	if err != nil {
+		return err
+	}
+	if !k.Configured() {
+		log.Println("警告：未配置加密密钥，无法保存 secret 类型参数的取值")
+	} else {
+		log.Printf("已加载加密密钥 %d 个，主密钥 %s", len(k.keys), k.primary)
+	}
+	SetDefault(k)
+	return nil
+}
+
+// SetDefault 替换进程级密钥集合
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
+
+// Default 进程级密钥集合，未调用 Init 时为空集合
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// Configured 是否配置了主密钥
+func (k *Keyring) Configured() bool {
+	return len(k.keys) > 0
+}
+
+// Primary 当前主密钥ID
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// KeyIDs 全部密钥ID
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// IsEncrypted 值是否为本包生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID 返回密文使用的主密钥ID，明文返回空串
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(value[len(prefix):], ":")
+	return id
+}
+
+// Encrypt 使用主密钥加密并绑定所属对象 owner，空串原样返回
+func (k *Keyring) Encrypt(owner, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if owner == "" {
+		return "", ErrNoOwner
+	}
+	if !k.Configured() {
+		return "", ErrNoKey
+	}
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", err
+	}
+	sealed, err := seal(dek, []byte(plaintext), []byte(owner))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dek, nil)
+	if err != nil {
+		return "", err
+	}
+	return format(k.primary, wrapped, sealed), nil
+}
+
+// Decrypt 解密属于 owner 的密文，密文属于其他对象时失败；不是密文的值（启用加密前写入的数据）原样返回
+func (k *Keyring) Decrypt(owner, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if owner == "" {
+		return "", ErrNoOwner
+	}
+	id, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dek, sealed, []byte(owner))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 用当前主密钥重新加密数据密钥（数据密钥与数据密文不变）；
+// 已使用主密钥或不是密文时 changed 为 false
+func (k *Keyring) Rewrap(value string) (rewrapped string, changed bool, err error) {
+	if !IsEncrypted(value) || KeyID(value) == k.primary {
+		return value, false, nil
+	}
+	if !k.Configured() {
+		return "", false, ErrNoKey
+	}
+	id, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	if wrapped, err = seal(k.keys[k.primary], dek, nil); err != nil {
+		return "", false, err
+	}
+	return format(k.primary, wrapped, sealed), true, nil
+}
+
+// tokenPattern 匹配文本（如JSON快照、YAML文件）中的密文，密钥ID不含冒号与空白
+var tokenPattern = regexp.MustCompile(regexp.QuoteMeta(prefix) + `[^:\s"'\\]+:[A-Za-z0-9+/]+:[A-Za-z0-9+/]+`)
+
+// Find 返回文本中出现的全部密文
+func Find(text string) []string {
+	if !strings.Contains(text, prefix) {
+		return nil
+	}
+	return tokenPattern.FindAllString(text, -1)
+}
+
+// RewrapText 对文本中出现的每个密文执行 Rewrap，返回替换后的文本与改写的密文个数
+func (k *Keyring) RewrapText(text string) (string, int, error) {
+	changed := 0
+	var failure error
+	out := tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		value, ok, err := k.Rewrap(token)
+		if err != nil {
+			if failure == nil {
+				failure = err
+			}
+			return token
+		}
+		if ok {
+			changed++
+		}
+		return value
+	})
+	if failure != nil {
+		return text, 0, failure
+	}
+	return out, changed, nil
+}
+
+func (k *Keyring) unwrap(id string, wrapped []byte) ([]byte, error) {
+	kek, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("缺少密钥 %s，无法解密", id)
+	}
+	dek, err := open(kek, wrapped, nil)
+	if err != nil {
+		return nil, fmt.Errorf("数据密钥解密失败: %v", err)
+	}
+	return dek, nil
+}
+
+func format(id string, wrapped, sealed []byte) string {
+	return prefix + id + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+func parse(value string) (id string, wrapped, sealed []byte, err error) {
+	parts := strings.Split(value[len(prefix):], ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("密文格式无效")
+	}
+	if wrapped, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, errors.New("密文格式无效")
+	}
+	if sealed, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, errors.New("密文格式无效")
+	}
+	return parts[0], wrapped, sealed, nil
+}
+
+// seal AES-GCM加密，输出 随机数+密文；aad 为附加认证数据，解密时须相同
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文过短")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
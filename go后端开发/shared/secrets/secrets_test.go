@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte(id), 32)[:32]
+	}
+	k, err := NewKeyring(primary, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	const owner = "vnf:1/db_password"
+
+	sealed, err := k.Encrypt(owner, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || KeyID(sealed) != "k1" || strings.Contains(sealed, "s3cret") {
+		t.Fatalf("密文格式不正确: %s", sealed)
+	}
+	if plain, err := k.Decrypt(owner, sealed); err != nil || plain != "s3cret" {
+		t.Fatalf("解密结果 %q, %v", plain, err)
+	}
+
+	// 密文移到其他参数或实例后无法解密
+	for _, other := range []string{"vnf:1/api_key", "vnf:2/db_password"} {
+		if _, err := k.Decrypt(other, sealed); err == nil {
+			t.Fatalf("属于 %s 的密文不应能以 %s 解密", owner, other)
+		}
+	}
+
+	// 篡改密文
+	i := strings.LastIndex(sealed, ":") + 20
+	flip := byte('A')
+	if sealed[i] == 'A' {
+		flip = 'B'
+	}
+	tampered := sealed[:i] + string(flip) + sealed[i+1:]
+	if _, err := k.Decrypt(owner, tampered); err == nil {
+		t.Fatal("被篡改的密文应解密失败")
+	}
+	if _, err := k.Decrypt(owner, prefix+"k1:bad"); err == nil {
+		t.Fatal("格式无效的密文应解密失败")
+	}
+
+	// 空串与明文原样通过
+	if v, err := k.Encrypt(owner, ""); err != nil || v != "" {
+		t.Fatalf("空串应原样返回: %q, %v", v, err)
+	}
+	if v, err := k.Decrypt(owner, "plain"); err != nil || v != "plain" {
+		t.Fatalf("明文应原样返回: %q, %v", v, err)
+	}
+}
+
+func TestEncryptErrors(t *testing.T) {
+	if _, err := (&Keyring{}).Encrypt("vnf:1/p", "x"); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("未配置密钥应返回 ErrNoKey，得到 %v", err)
+	}
+	k := testKeyring(t, "k1", "k1")
+	if _, err := k.Encrypt("", "x"); !errors.Is(err, ErrNoOwner) {
+		t.Fatalf("未指定所属对象应返回 ErrNoOwner，得到 %v", err)
+	}
+	sealed, err := k.Encrypt("vnf:1/p", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Decrypt("", sealed); !errors.Is(err, ErrNoOwner) {
+		t.Fatalf("未指定所属对象应返回 ErrNoOwner，得到 %v", err)
+	}
+	if _, err := testKeyring(t, "k2", "k2").Decrypt("vnf:1/p", sealed); err == nil {
+		t.Fatal("缺少密钥时应解密失败")
+	}
+	if _, err := NewKeyring("k1", map[string][]byte{"k1": []byte("short")}); err == nil {
+		t.Fatal("长度无效的密钥应被拒绝")
+	}
+	if _, err := NewKeyring("k3", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}); err == nil {
+		t.Fatal("主密钥不在列表中时应被拒绝")
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	const owner = "vnf:1/db_password"
+	old := testKeyring(t, "k1", "k1")
+	sealed, err := old.Encrypt(owner, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 已使用主密钥的值不变
+	if v, changed, err := old.Rewrap(sealed); err != nil || changed || v != sealed {
+		t.Fatalf("已使用主密钥的值不应改变: changed=%v, %v", changed, err)
+	}
+
+	// 轮换到新主密钥后，旧值仍可解密，Rewrap 后改用新主密钥
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	if plain, err := rotated.Decrypt(owner, sealed); err != nil || plain != "s3cret" {
+		t.Fatalf("轮换后旧值解密结果 %q, %v", plain, err)
+	}
+	v, changed, err := rotated.Rewrap(sealed)
+	if err != nil || !changed || KeyID(v) != "k2" {
+		t.Fatalf("Rewrap 结果 %s, changed=%v, %v", v, changed, err)
+	}
+	if plain, err := testKeyring(t, "k2", "k2").Decrypt(owner, v); err != nil || plain != "s3cret" {
+		t.Fatalf("移除旧密钥后解密结果 %q, %v", plain, err)
+	}
+	if _, err := rotated.Decrypt("vnf:2/db_password", v); err == nil {
+		t.Fatal("Rewrap 后的密文仍应绑定所属对象")
+	}
+
+	// 明文不变
+	if v, changed, err := rotated.Rewrap("plain"); err != nil || changed || v != "plain" {
+		t.Fatalf("明文不应改变: %q, changed=%v, %v", v, changed, err)
+	}
+}
+
+func TestRewrapText(t *testing.T) {
+	old := testKeyring(t, "k1", "k1")
+	a, _ := old.Encrypt("vnf:1/a", "alpha")
+	b, _ := old.Encrypt("vnf:1/b", "beta")
+	text := `{"currentValue":"` + a + `","defaultValue":"` + b + `","other":"enc:v2:"}`
+	if found := Find(text); len(found) != 2 || found[0] != a || found[1] != b {
+		t.Fatalf("Find 结果 %v", found)
+	}
+	if found := Find("plain text"); found != nil {
+		t.Fatalf("明文中不应找到密文: %v", found)
+	}
+
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	out, changed, err := rotated.RewrapText(text)
+	if err != nil || changed != 2 {
+		t.Fatalf("RewrapText changed=%d, %v", changed, err)
+	}
+	found := Find(out)
+	if len(found) != 2 || KeyID(found[0]) != "k2" || KeyID(found[1]) != "k2" {
+		t.Fatalf("改写后的密文 %v", found)
+	}
+	if plain, err := testKeyring(t, "k2", "k2").Decrypt("vnf:1/b", found[1]); err != nil || plain != "beta" {
+		t.Fatalf("改写后解密结果 %q, %v", plain, err)
+	}
+	if again, n, err := rotated.RewrapText(out); err != nil || n != 0 || again != out {
+		t.Fatalf("已使用主密钥的文本不应改变: n=%d, %v", n, err)
+	}
+	if _, _, err := testKeyring(t, "k3", "k3").RewrapText(text); err == nil {
+		t.Fatal("缺少密钥时应返回错误")
+	}
+}
@@ -0,0 +1,199 @@
+// Package textdiff 按行比较两段文本，输出 diff -u 格式的统一差异
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context 统一差异中每段修改前后保留的上下文行数
+const Context = 3
+
+// edit 编辑脚本中的一行：' ' 两边相同，'-' 只在 a 中，'+' 只在 b 中；a、b 为行号（从0开始）
+type edit struct {
+	op   byte
+	a, b int
+}
+
+// Unified 返回 a 到 b 的统一差异（与 diff -u 相同的格式），fromName、toName 为文件头中的名称；
+// 内容相同时返回空字符串
+func Unified(fromName, toName string, a, b []byte) string {
+	x, y := lines(a), lines(b)
+	edits := script(x, y)
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// 找到下一处修改，连同前后上下文组成一段；两处修改间隔不超过 2*Context 行时合并
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits); i++ {
+			if edits[i].op != ' ' {
+				last = i
+			} else if i-last > 2*Context {
+				break
+			}
+		}
+		from, to := max(first-Context, start), min(last+Context+1, len(edits))
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, x, y, edits[from:to])
+		start = to
+	}
+	return out.String()
+}
+
+// writeHunk 输出一段差异：@@ -起始,行数 +起始,行数 @@ 及各行
+func writeHunk(out *strings.Builder, x, y []string, hunk []edit) {
+	aStart, bStart, aCount, bCount := -1, -1, 0, 0
+	for _, e := range hunk {
+		if e.op != '+' {
+			if aStart < 0 {
+				aStart = e.a
+			}
+			aCount++
+		}
+		if e.op != '-' {
+			if bStart < 0 {
+				bStart = e.b
+			}
+			bCount++
+		}
+	}
+	// 空范围的起始行为其前一行（与 diff -u 一致），hunk 中首行的 a、b 即插入或删除的位置
+	if aStart < 0 {
+		aStart = hunk[0].a
+	} else {
+		aStart++
+	}
+	if bStart < 0 {
+		bStart = hunk[0].b
+	} else {
+		bStart++
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, e := range hunk {
+		line := ""
+		switch e.op {
+		case '+':
+			line = y[e.b]
+		default:
+			line = x[e.a]
+		}
+		out.WriteByte(e.op)
+		out.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// lines 按行拆分，每行保留结尾的换行符（最后一行可能没有）
+func lines(b []byte) []string {
+	s := string(b)
+	var out []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			out = append(out, s)
+			break
+		}
+		out, s = append(out, s[:i+1]), s[i+1:]
+	}
+	return out
+}
+
+// script 计算 x 到 y 的最短编辑脚本：先去掉相同的首尾行，中间部分用 Myers 算法
+func script(x, y []string) []edit {
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	edits := make([]edit, 0, len(x)+len(y)-pre-suf)
+	for i := 0; i < pre; i++ {
+		edits = append(edits, edit{' ', i, i})
+	}
+	edits = append(edits, myers(x[pre:len(x)-suf], y[pre:len(y)-suf], pre)...)
+	for i := suf; i > 0; i-- {
+		edits = append(edits, edit{' ', len(x) - i, len(y) - i})
+	}
+	return edits
+}
+
+// myers 返回 x 到 y 的编辑脚本，行号加上 offset。trace 保存每一步之前的 V 数组（只保留 [-d, d]），用于回溯
+func myers(x, y []string, offset int) []edit {
+	n, m := len(x), len(y)
+	limit := n + m
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[limit-d:limit+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[limit+k-1] < v[limit+k+1]) {
+				i = v[limit+k+1]
+			} else {
+				i = v[limit+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i, j = i+1, j+1
+			}
+			v[limit+k] = i
+			if i >= n && j >= m {
+				return backtrack(trace, n, m, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack 从终点沿 trace 倒推出编辑脚本
+func backtrack(trace [][]int, i, j, offset int) []edit {
+	var edits []edit
+	for d := len(trace) - 1; d > 0; d-- {
+		prev, k := trace[d], i-j
+		var pk int
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		pi := prev[pk+d]
+		pj := pi - pk
+		for i > pi && j > pj {
+			i, j = i-1, j-1
+			edits = append(edits, edit{' ', i + offset, j + offset})
+		}
+		if pk == k+1 {
+			edits = append(edits, edit{'+', i + offset, j - 1 + offset})
+		} else {
+			edits = append(edits, edit{'-', i - 1 + offset, j + offset})
+		}
+		i, j = pi, pj
+	}
+	for i > 0 && j > 0 {
+		i, j = i-1, j-1
+		edits = append(edits, edit{' ', i + offset, j + offset})
+	}
+	for l, r := 0, len(edits)-1; l < r; l, r = l+1, r-1 {
+		edits[l], edits[r] = edits[r], edits[l]
+	}
+	return edits
+}
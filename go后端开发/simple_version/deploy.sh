@@ -59,7 +59,7 @@ build_image() {
     local tag=${1:-"latest"}
 
     log_info "Building Docker image: ${image_name}:${tag}"
-    docker build -t "${image_name}:${tag}" .
+    docker build -t "${image_name}:${tag}" -f Dockerfile ..
 
     log_info "Pushing image to registry (if needed)"
     # 如果你使用私有registry，需要取消注释下面的行
@@ -96,6 +96,16 @@ deploy_to_k8s() {
             -n yaml-config-manager --dry-run=client -o yaml | kubectl apply -f -
     fi
 
+    # 创建认证令牌Secret（服务未配置认证时拒绝启动）
+    if [ ! -f "tokens.yaml" ]; then
+        log_error "tokens.yaml not found. Create it (see README, 认证与权限) before deploying."
+        exit 1
+    fi
+    log_info "Creating auth Secret..."
+    kubectl create secret generic yaml-config-auth \
+        --from-file=tokens.yaml \
+        -n yaml-config-manager --dry-run=client -o yaml | kubectl apply -f -
+
     # 部署应用
     log_info "Deploying application..."
     kubectl apply -f k8s/k8s-app-deployment.yaml
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,7 +25,14 @@ import (
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 
+	"simple-version/metrics"
 	"simple-version/mongo"
+	"simple-version/watch"
+	"vnf-shared/auth"
+	"vnf-shared/formats"
+	"vnf-shared/gitops"
+	"vnf-shared/secrets"
+	"vnf-shared/textdiff"
 )
 
 // YAMLData 存储解析后的YAML数据
@@ -33,65 +47,281 @@ type Field struct {
 	Value       interface{} `json:"value"`
 	Type        string      `json:"type"`
 	Description string      `json:"description"`
+	Secret      bool        `json:"secret,omitempty"`
 }
 
-// parseYAMLFile 解析YAML文件（保留文件中原始键顺序）
-func parseYAMLFile(filePath string) (*YAMLData, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+// maskedValue 机密字段在接口输出与未配置密钥时的快照中的取值
+const maskedValue = "******"
+
+// secretKeys 名称以这些词结尾的键视为机密字段（如 db_password、access_token）
+var secretKeys = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "private_key", "credentials"}
+
+// isSecretKey 键名是否表示机密取值
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range secretKeys {
+		if strings.HasSuffix(key, k) { return true }
 	}
+	return false
+}
 
-	// 原始内容（用于 /yaml/raw）
-	var rawData interface{}
-	if err := yaml.Unmarshal(data, &rawData); err != nil {
-		return nil, err
+// mappingType 返回对象节点中 type 键的取值（描述文件中的参数类型）
+func mappingType(node *yaml.Node) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "type" { return node.Content[i+1].Value }
 	}
+	return ""
+}
+
+// parseYAMLFile 解析YAML文件（保留文件中原始键顺序），经 yamlCache 缓存，返回的数据为共享的只读副本
+func parseYAMLFile(filePath string) (*YAMLData, error) {
+	doc, err := parsed.load(filePath)
+	if err != nil { return nil, err }
+	return doc.data, doc.err
+}
+
+// parseYAMLBytes 解析YAML内容，提取字段（GitOps拉取时解析仓库中的历史版本）
+func parseYAMLBytes(data []byte) (*YAMLData, error) {
+	yamlData, _, err := parseYAMLDocument(data)
+	return yamlData, err
+}
 
-	// 使用 yaml.Node 保留顺序
+// parseYAMLDocument 只解析一次：先得到 yaml.Node（保留顺序，用于提取字段与原位修改），原始内容（用于 /yaml/raw）由节点解码
+func parseYAMLDocument(data []byte) (*YAMLData, *yaml.Node, error) {
 	var root yaml.Node
 	if err := yaml.Unmarshal(data, &root); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	var rawData interface{}
 	var fields []Field
 	if len(root.Content) > 0 {
-		fields = extractFieldsNode("", root.Content[0])
+		if err := root.Decode(&rawData); err != nil {
+			return nil, nil, err
+		}
+		fields = extractFieldsNode("", root.Content[0], false)
 	}
 
 	return &YAMLData{
 		Content: rawData,
 		Fields:  fields,
-	}, nil
+	}, &root, nil
+}
+
+// racyWindow 修改时间距读取不足该时长的缓存项，即使修改时间与大小未变也重新读取比较摘要（同一时间刻度内的再次写入）
+const racyWindow = 2 * time.Second
+
+// parsedYAML 文件的一次解析结果。data 与 root 由所有请求共享、只读，需要修改节点时使用 cloneNode 的副本；
+// err 为解析错误（无法解析的内容同样缓存）
+type parsedYAML struct {
+	content  []byte
+	revision string
+	data     *YAMLData
+	root     *yaml.Node
+	err      error
+	modTime  time.Time
+	size     int64
+	readAt   time.Time
 }
 
-// extractFieldsNode 使用 yaml.Node 递归提取字段，按文件顺序遍历
-func extractFieldsNode(path string, node *yaml.Node) []Field {
+// yamlCache 按文件缓存解析结果：修改时间与大小未变时直接使用；否则读取文件，摘要相同时沿用原结果，不同时重新解析。
+// snapshots 记录各文件最近一次保存快照的内容摘要，内容未变时不重复写入MongoDB
+type yamlCache struct {
+	mu        sync.Mutex
+	entries   map[string]*parsedYAML
+	snapshots map[string]string
+}
+
+var parsed = &yamlCache{entries: map[string]*parsedYAML{}, snapshots: map[string]string{}}
+
+// load 返回文件的解析结果，文件不存在或无法读取时返回错误
+func (c *yamlCache) load(filePath string) (*parsedYAML, error) {
+	info, err := os.Stat(filePath)
+	if err != nil { return nil, err }
+	c.mu.Lock()
+	cached := c.entries[filePath]
+	c.mu.Unlock()
+	if cached != nil && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() && cached.readAt.Sub(cached.modTime) >= racyWindow {
+		metrics.ObserveParseCache("hit")
+		return cached, nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil { return nil, err }
+	doc := &parsedYAML{content: content, revision: fileRevision(content), modTime: info.ModTime(), size: info.Size(), readAt: time.Now()}
+	if cached != nil && cached.revision == doc.revision {
+		doc.data, doc.root, doc.err = cached.data, cached.root, cached.err
+		metrics.ObserveParseCache("unchanged")
+	} else {
+		metrics.ObserveParseCache("miss")
+		start := time.Now()
+		doc.data, doc.root, doc.err = parseYAMLDocument(content)
+		metrics.ObserveParse(start, doc.err)
+	}
+	c.mu.Lock()
+	c.entries[filePath] = doc
+	c.mu.Unlock()
+	return doc, nil
+}
+
+// snapshotDue 文件内容与上次保存的快照不同时登记并返回 true
+func (c *yamlCache) snapshotDue(filePath, revision string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshots[filePath] == revision { return false }
+	c.snapshots[filePath] = revision
+	return true
+}
+
+// snapshotFailed 快照写入失败时撤销登记，下次读取时重试
+func (c *yamlCache) snapshotFailed(filePath, revision string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshots[filePath] == revision { delete(c.snapshots, filePath) }
+}
+
+// cloneNode 深拷贝节点树（别名仍指向副本中的锚点），用于在缓存的节点上修改或屏蔽取值
+func cloneNode(n *yaml.Node) *yaml.Node {
+	copies := make(map[*yaml.Node]*yaml.Node)
+	var clone func(n *yaml.Node) *yaml.Node
+	clone = func(n *yaml.Node) *yaml.Node {
+		if n == nil { return nil }
+		if c, ok := copies[n]; ok { return c }
+		c := *n
+		copies[n] = &c
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content { c.Content[i] = clone(child) }
+		c.Alias = clone(n.Alias)
+		return &c
+	}
+	return clone(n)
+}
+
+// corsConfig 读取 CORS_ALLOWED_ORIGINS（逗号分隔，"*" 表示任意来源），未设置时只允许同源访问
+func corsConfig() (cors.Config, bool) {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" { origins = append(origins, o) }
+	}
+	if len(origins) == 0 { return cors.Config{}, false }
+	cfg := cors.DefaultConfig()
+	cfg.AddAllowHeaders("Authorization")
+	if len(origins) == 1 && origins[0] == "*" {
+		cfg.AllowAllOrigins = true
+	} else {
+		cfg.AllowOrigins = origins
+	}
+	return cfg, true
+}
+
+// snapshotWrite 处理异步快照写入结果：失败的快照不重试，记录日志并计数
+func snapshotWrite(collection string, err error) {
+	if err == nil { return }
+	metrics.SnapshotDropped(collection)
+	log.Printf("快照写入 %s 失败，已丢弃: %v", collection, err)
+}
+
+// extractFieldsNode 使用 yaml.Node 递归提取字段，按文件顺序遍历。
+// secret 表示节点取值为机密：机密键名下的标量，或机密参数描述（键名为机密词或 type: secret）中的 default/value
+func extractFieldsNode(path string, node *yaml.Node, secret bool) []Field {
 	var fields []Field
 
 	switch node.Kind {
 	case yaml.MappingNode:
+		described := secret || mappingType(node) == "secret"
 		for i := 0; i+1 < len(node.Content); i += 2 {
 			keyNode := node.Content[i]
 			valNode := node.Content[i+1]
 			newPath := buildPath(path, keyNode.Value)
-			fields = append(fields, extractFieldsNode(newPath, valNode)...)
+			childSecret := isSecretKey(keyNode.Value) || (described && (keyNode.Value == "default" || keyNode.Value == "value"))
+			fields = append(fields, extractFieldsNode(newPath, valNode, childSecret)...)
 		}
 	case yaml.SequenceNode:
 		for _, item := range node.Content {
 			newPath := buildPath(path, "[]")
-			fields = append(fields, extractFieldsNode(newPath, item)...)
+			fields = append(fields, extractFieldsNode(newPath, item, secret)...)
 		}
 	case yaml.ScalarNode:
 		if path != "" {
 			val := valueFromNode(node)
-			fields = append(fields, Field{Path: path, Value: val, Type: getType(val), Description: ""})
+			fields = append(fields, Field{Path: path, Value: val, Type: getType(val), Description: "", Secret: secret})
 		}
 	}
 
 	return fields
 }
 
+// secretPaths 返回机密字段路径集合
+func (d *YAMLData) secretPaths() map[string]bool {
+	paths := make(map[string]bool)
+	for _, f := range d.Fields {
+		if f.Secret { paths[f.Path] = true }
+	}
+	return paths
+}
+
+// mapSecrets 返回将机密字段取值替换为 conceal(字段路径, 原值) 后的副本，原数据不变
+func (d *YAMLData) mapSecrets(conceal func(string, interface{}) interface{}) *YAMLData {
+	paths := d.secretPaths()
+	out := &YAMLData{Content: d.Content, Fields: make([]Field, len(d.Fields))}
+	for i, f := range d.Fields {
+		if f.Secret { f.Value = conceal(f.Path, f.Value) }
+		out.Fields[i] = f
+	}
+	if len(paths) > 0 { out.Content = mapContent("", d.Content, paths, conceal) }
+	return out
+}
+
+// mapContent 按字段路径替换原始内容中的机密取值
+func mapContent(path string, v interface{}, paths map[string]bool, conceal func(string, interface{}) interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x { m[k] = mapContent(buildPath(path, k), item, paths, conceal) }
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, item := range x { list[i] = mapContent(buildPath(path, "[]"), item, paths, conceal) }
+		return list
+	default:
+		if paths[path] { return conceal(path, v) }
+		return v
+	}
+}
+
+// maskSecret 接口输出时屏蔽机密取值，空值保持为空
+func maskSecret(v interface{}) interface{} {
+	if v == nil || toString(v) == "" { return v }
+	return maskedValue
+}
+
+// maskField 按 conceal 的签名屏蔽机密取值
+func maskField(_ string, v interface{}) interface{} { return maskSecret(v) }
+
+// secretOwner 密文绑定的所属字段（文件名与字段路径），密文移到其他字段或文件后无法解密
+func secretOwner(filePath, path string) string { return "yaml:" + filepath.Base(filePath) + "/" + path }
+
+// sealSecret 快照中的机密取值：配置了密钥时按所属字段加密保存，否则屏蔽
+func sealSecret(filePath, path string, v interface{}) interface{} {
+	if v == nil || toString(v) == "" { return v }
+	if sealed, err := secrets.Default().Encrypt(secretOwner(filePath, path), toString(v)); err == nil { return sealed }
+	return maskedValue
+}
+
+// sealFields 按 conceal 的签名加密文件 filePath 中的机密取值
+func sealFields(filePath string) func(string, interface{}) interface{} {
+	return func(path string, v interface{}) interface{} { return sealSecret(filePath, path, v) }
+}
+
+// snapshotFields 将字段转换为快照文档，机密取值已由 sealSecret 处理
+func snapshotFields(data *YAMLData) []map[string]interface{} {
+	var docs []map[string]interface{}
+	for _, f := range data.Fields {
+		docs = append(docs, map[string]interface{}{"path": f.Path, "value": f.Value, "type": f.Type, "secret": f.Secret})
+	}
+	return docs
+}
+
 // valueFromNode 将标量节点解码为对应Go类型
 func valueFromNode(n *yaml.Node) interface{} {
 	var v interface{}
@@ -129,6 +359,87 @@ func getType(value interface{}) string {
 	}
 }
 
+// fieldFilter GET /yaml 的筛选条件，给出的条件须同时满足。取值按屏蔽后的文本匹配，机密字段不能按明文检索
+type fieldFilter struct {
+	q, path, pathRe, value, valueRe *regexp.Regexp
+	types                           map[string]bool
+	changed                         map[string]bool // 非 nil 时只保留 changedSince 之后经接口修改过的字段
+	since                           time.Time
+}
+
+// parseFieldFilter 读取筛选参数：q（路径或取值包含）、path（包含；含 * ? 时为通配，* 不跨越 .，** 可跨越）、
+// pathRegex、value（包含）、valueRegex、type（逗号分隔）与 changedSince（RFC3339 时间或 24h 这样的时长）
+func parseFieldFilter(c *gin.Context) (*fieldFilter, error) {
+	f := &fieldFilter{}
+	contains := func(s string) *regexp.Regexp { return regexp.MustCompile("(?i)" + regexp.QuoteMeta(s)) }
+	if q := c.Query("q"); q != "" { f.q = contains(q) }
+	if p := c.Query("path"); p != "" {
+		f.path = contains(p)
+		if strings.ContainsAny(p, "*?") { f.path = globPattern(p) }
+	}
+	if v := c.Query("value"); v != "" { f.value = contains(v) }
+	for _, r := range []struct{ name string; dest **regexp.Regexp }{{"pathRegex", &f.pathRe}, {"valueRegex", &f.valueRe}} {
+		expr := c.Query(r.name)
+		if expr == "" { continue }
+		re, err := regexp.Compile(expr)
+		if err != nil { return nil, errors.New(r.name + " 不是有效的正则表达式: " + err.Error()) }
+		*r.dest = re
+	}
+	if t := c.Query("type"); t != "" {
+		f.types = make(map[string]bool)
+		for _, name := range strings.Split(t, ",") { f.types[strings.TrimSpace(name)] = true }
+	}
+	if since := c.Query("changedSince"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			f.since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			f.since = t
+		} else {
+			return nil, errors.New("changedSince 应为RFC3339时间（如 2024-05-01T00:00:00Z）或时长（如 24h）")
+		}
+	}
+	return f, nil
+}
+
+// globPattern 将路径通配转换为完整匹配的正则：** 匹配任意字符，* 与 ? 不跨越 .
+func globPattern(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^.]*")
+		case glob[i] == '?':
+			b.WriteString("[^.]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// match 字段是否满足全部筛选条件
+func (f *fieldFilter) match(field Field) bool {
+	value := toString(field.Value)
+	switch {
+	case f.q != nil && !f.q.MatchString(field.Path) && !f.q.MatchString(value):
+		return false
+	case f.path != nil && !f.path.MatchString(field.Path), f.pathRe != nil && !f.pathRe.MatchString(field.Path):
+		return false
+	case f.value != nil && !f.value.MatchString(value), f.valueRe != nil && !f.valueRe.MatchString(value):
+		return false
+	case f.types != nil && !f.types[field.Type]:
+		return false
+	case f.changed != nil && !f.changed[field.Path]:
+		return false
+	}
+	return true
+}
+
 // findWritableYAMLFile 返回可写的yaml文件路径（存在的第一个）
 func findWritableYAMLFile() (string, error) {
 	candidates := []string{"config.yaml", "sample_config.yaml", "test.yaml"}
@@ -218,6 +529,11 @@ func setScalar(n *yaml.Node, v interface{}) {
 		n.Kind = yaml.ScalarNode
 		n.Tag = "!!float"
 		n.Value = strconv.FormatFloat(toFloat64(x), 'f', -1, 64)
+	case nil:
+		n.Kind = yaml.ScalarNode
+		n.Tag = "!!null"
+		n.Style = 0
+		n.Value = "null"
 	default:
 		n.Kind = yaml.ScalarNode
 		n.Tag = "!!str"
@@ -231,6 +547,684 @@ func toFloat64(v interface{}) float64 { switch t := v.(type) { case float32: ret
 func toString(v interface{}) string { if v == nil { return "" }; s, ok := v.(string); if ok { return s }; return stringify(v) }
 func stringify(v interface{}) string { b, _ := yaml.Marshal(v); return strings.TrimSpace(string(b)) }
 
+// yamlChange 在内存中应用修改的结果：原内容、修改后的内容、实际生效的修改（机密字段回传的屏蔽值已剔除）、
+// 修改前的机密字段，以及各项修改的检查结果
+type yamlChange struct {
+	Before, After []byte
+	Updates       map[string]interface{}
+	Secrets       map[string]bool
+	Problems      []updateProblem
+}
+
+// updateProblem 修改的检查结果：error 的修改不会生效，warning 提示可能不符合预期的修改
+type updateProblem struct {
+	Path    string `json:"path"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// errUpdateRejected 修改中有 error 级的检查结果（即试运行中 valid 为 false），文件不会写入
+type errUpdateRejected struct{ Problems []updateProblem }
+
+func (e *errUpdateRejected) Error() string {
+	var msgs []string
+	for _, p := range e.Problems {
+		if p.Level != "error" { continue }
+		if p.Path != "" { msgs = append(msgs, p.Path+": "+p.Message) } else { msgs = append(msgs, p.Message) }
+	}
+	return "存在无法生效的修改，未保存: " + strings.Join(msgs, "; ")
+}
+
+// planYAMLUpdates 基于 yaml.Node 在内存中应用修改（保留原始顺序），不写文件
+func planYAMLUpdates(filePath string, updates map[string]interface{}) (*yamlChange, error) {
+	// 在缓存的 yaml.Node 副本上修改
+	doc, err := parsed.load(filePath)
+	if err != nil { return nil, errors.New("读取文件失败") }
+	if doc.err != nil { return nil, errors.New("解析YAML失败") }
+	root := cloneNode(doc.root)
+
+	// 按路径顺序应用更新到节点
+	change := &yamlChange{Before: doc.content, Updates: make(map[string]interface{}, len(updates)), Secrets: doc.data.secretPaths()}
+	content := doc.data.Content
+	paths := make([]string, 0, len(updates))
+	for p := range updates { paths = append(paths, p) }
+	sort.Strings(paths)
+	for _, p := range paths {
+		v := updates[p]
+		if change.Secrets[p] && v == maskedValue { continue }
+		if err := setNodeValueByPath(root, p, v); err != nil {
+			change.Problems = append(change.Problems, updateProblem{Path: p, Level: "error", Message: err.Error()})
+			continue
+		}
+		if msg := checkUpdate(content, p, v); msg != "" {
+			change.Problems = append(change.Problems, updateProblem{Path: p, Level: "warning", Message: msg})
+		}
+		// 路径按顺序应用，上级字段的修改先于下级，会被替换为对象
+		for parent := p; strings.Contains(parent, "."); {
+			parent = parent[:strings.LastIndex(parent, ".")]
+			if _, ok := change.Updates[parent]; ok {
+				change.Problems = append(change.Problems, updateProblem{Path: parent, Level: "warning", Message: "将被 " + p + " 的修改替换为对象"})
+			}
+		}
+		change.Updates[p] = v
+	}
+
+	out, err := yaml.Marshal(root)
+	if err != nil { return nil, errors.New("生成YAML失败") }
+	if _, err := parseYAMLBytes(out); err != nil {
+		change.Problems = append(change.Problems, updateProblem{Level: "error", Message: "修改后的内容无法解析: " + err.Error()})
+	}
+	change.After = out
+	return change, nil
+}
+
+// checkUpdate 对照当前内容检查一项修改：新增字段、替换对象或非对象上级、改变取值类型时返回提示
+func checkUpdate(content interface{}, path string, v interface{}) string {
+	cur, found, blocked := lookupContent(content, path)
+	switch {
+	case blocked != "":
+		return "上级字段 " + blocked + " 不是对象，将被替换为对象"
+	case !found:
+		return "新增字段"
+	case getType(cur) == "object":
+		return "当前为对象，将被替换为标量"
+	case cur != nil && v != nil && getType(cur) != getType(v):
+		return "类型由 " + getType(cur) + " 变为 " + getType(v)
+	}
+	return ""
+}
+
+// saveYAMLUpdates 按 planYAMLUpdates 应用修改并写回文件，机密字段回传的屏蔽值表示未修改、忽略；
+// 有 error 级的检查结果时不写入，返回 *errUpdateRejected。写入前备份原文件，写入后异步保存更新快照并提交到GitOps仓库
+func saveYAMLUpdates(filePath string, updates map[string]interface{}, actor string) error {
+	change, err := planYAMLUpdates(filePath, updates)
+	if err != nil { return err }
+	for _, p := range change.Problems {
+		if p.Level == "error" { return &errUpdateRejected{Problems: change.Problems} }
+	}
+	updates, secretPaths := change.Updates, change.Secrets
+
+	// 备份原文件；先登记写入的内容，文件监视据此将本次修改归于操作人
+	_ = os.WriteFile(filePath+".bak", change.Before, 0644)
+	watched.wrote(filePath, change.After, actor)
+
+	// 写回，保留原始键顺序
+	if err := os.WriteFile(filePath, change.After, 0644); err != nil { return errors.New("写入文件失败") }
+
+	log.Printf("%s 更新了 %s: %d 个字段", actor, filepath.Base(filePath), len(updates))
+
+	// 重解析用于保存更新快照（修改记录每次保存，最新快照在内容改变时保存）
+	if doc, err := parsed.load(filePath); err == nil && doc.err == nil {
+		sealed, updatedSecrets := make(map[string]interface{}, len(updates)), doc.data.secretPaths()
+		for p, v := range updates {
+			if secretPaths[p] || updatedSecrets[p] { v = sealSecret(filePath, p, v) }
+			sealed[p] = v
+		}
+		latest := parsed.snapshotDue(filePath, doc.revision)
+		go func(copyData *YAMLData) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			docs := snapshotFields(copyData)
+			snapshotWrite("yaml_updates", mongo.SaveYAMLUpdate(ctx, filepath.Base(filePath), actor, sealed, copyData.Content, docs))
+			if !latest { return }
+			err := mongo.UpsertLatest(ctx, filepath.Base(filePath), copyData.Content, docs)
+			snapshotWrite("yaml_latest", err)
+			if err != nil { parsed.snapshotFailed(filePath, doc.revision) }
+		}(doc.data.mapSecrets(sealFields(filePath)))
+	}
+	publishYAML(filePath, actor, updates)
+	return nil
+}
+
+// writeSaveError 输出 saveYAMLUpdates 的错误：修改被拒绝时返回400及全部检查结果，其余为500
+func writeSaveError(c *gin.Context, err error) {
+	var rejected *errUpdateRejected
+	if errors.As(err, &rejected) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "problems": rejected.Problems})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// dryRunYAML 保存的试运行结果：修改后的文件内容与相对当前内容的统一diff（机密字段均已屏蔽，修改了的机密字段见 changed），
+// valid 表示没有不会生效的修改
+func dryRunYAML(filePath string, updates map[string]interface{}) (gin.H, error) {
+	change, err := planYAMLUpdates(filePath, updates)
+	if err != nil { return nil, err }
+	before, err := concealYAML(change.Before, maskField)
+	if err != nil { return nil, errors.New("解析YAML失败") }
+	after, err := concealYAML(change.After, maskField)
+	if err != nil { return nil, errors.New("解析YAML失败") }
+	changed := make([]string, 0, len(change.Updates))
+	for p := range change.Updates { changed = append(changed, p) }
+	sort.Strings(changed)
+	valid, problems := true, change.Problems
+	if problems == nil { problems = []updateProblem{} }
+	for _, p := range problems { valid = valid && p.Level != "error" }
+	name := filepath.Base(filePath)
+	return gin.H{
+		"dryRun":   true,
+		"file":     name,
+		"document": string(after),
+		"diff":     textdiff.Unified("a/"+name, "b/"+name, before, after),
+		"changed":  changed,
+		"valid":    valid,
+		"problems": problems,
+	}, nil
+}
+
+// fileRevision 文件内容的摘要（sha256），用于导入预览与文件变更事件
+func fileRevision(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// watchDebounce 文件监视的静默时间，编辑器保存时的多次写入合并为一次变更
+const watchDebounce = 200 * time.Millisecond
+
+// fileWatch 工作目录中YAML文件的监视状态：各文件上次解析的摘要与字段取值，用于计算变更的字段路径；
+// writes 记录经接口写入的内容摘要与操作人，用于区分外部修改
+type fileWatch struct {
+	mu        sync.Mutex
+	events    *watch.Hub
+	revisions map[string]string
+	values    map[string]map[string]string
+	writes    map[string]string
+}
+
+var watched = &fileWatch{events: watch.NewHub(), revisions: map[string]string{}, values: map[string]map[string]string{}, writes: map[string]string{}}
+
+// fieldValues 字段路径到取值文本的映射，数组元素的同名路径合并为一项
+func fieldValues(data *YAMLData) map[string]string {
+	values := make(map[string]string, len(data.Fields))
+	for _, f := range data.Fields {
+		text := f.Type + ":" + toString(f.Value)
+		if prev, ok := values[f.Path]; ok { text = prev + "\n" + text }
+		values[f.Path] = text
+	}
+	return values
+}
+
+// wrote 登记经接口写入的内容（写文件之前调用）
+func (w *fileWatch) wrote(filePath string, content []byte, actor string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes[fileRevision(content)] = actor
+}
+
+// load 记录文件的当前状态，不发送事件（启动时调用）
+func (w *fileWatch) load(name string) {
+	doc, err := parsed.load(name)
+	if err != nil || doc.err != nil { return }
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.revisions[name], w.values[name] = doc.revision, fieldValues(doc.data)
+}
+
+// reload 文件改变后重新解析，与上次状态比较得出变更的字段路径并广播；内容未变时不广播，
+// 无法解析时广播 invalid 并保留上次的字段取值，修复后的 changed 相对最后一次可解析的内容
+func (w *fileWatch) reload(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	doc, err := parsed.load(name)
+	if os.IsNotExist(err) {
+		if _, ok := w.revisions[name]; !ok { return }
+		removed := make([]string, 0, len(w.values[name]))
+		for p := range w.values[name] { removed = append(removed, p) }
+		sort.Strings(removed)
+		delete(w.revisions, name)
+		delete(w.values, name)
+		w.events.Publish(watch.Event{Type: watch.TypeRemoved, File: name, Removed: removed, Source: watch.SourceExternal})
+		return
+	}
+	if err != nil { log.Printf("读取 %s 失败: %v", name, err); return }
+	revision := doc.revision
+	if revision == w.revisions[name] { return }
+	event := watch.Event{Type: watch.TypeChanged, File: name, Revision: revision, Source: watch.SourceExternal}
+	if actor, ok := w.writes[revision]; ok {
+		event.Source, event.Actor = watch.SourceAPI, actor
+		delete(w.writes, revision)
+	}
+	if doc.err != nil {
+		event.Type, event.Error = watch.TypeInvalid, "解析YAML失败: "+doc.err.Error()
+		w.revisions[name] = revision
+		w.events.Publish(event)
+		return
+	}
+	before, after := w.values[name], fieldValues(doc.data)
+	for p, v := range after {
+		old, ok := before[p]
+		switch {
+		case !ok: event.Added = append(event.Added, p)
+		case old != v: event.Changed = append(event.Changed, p)
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok { event.Removed = append(event.Removed, p) }
+	}
+	sort.Strings(event.Changed)
+	sort.Strings(event.Added)
+	sort.Strings(event.Removed)
+	w.revisions[name], w.values[name] = revision, after
+	w.events.Publish(event)
+}
+
+// streamEvents 以 SSE 推送文件变更事件，断线重连时按 Last-Event-ID 补发；定时发送注释行保持连接
+func streamEvents(c *gin.Context) {
+	lastID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	events, cancel := watched.events.Subscribe(lastID)
+	defer cancel()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	_, _ = io.WriteString(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			return ok && watch.WriteSSE(w, e) == nil
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// gitopsYAML 提交到GitOps仓库的文件内容：机密字段按快照规则加密（未配置密钥时为屏蔽值），不提交明文
+func gitopsYAML(filePath string) ([]byte, error) {
+	root, err := concealedRoot(filePath, sealFields(filePath))
+	if err != nil { return nil, err }
+	return yaml.Marshal(root)
+}
+
+// concealedRoot 缓存中文件节点树的副本，机密取值已替换为 conceal 的结果
+func concealedRoot(filePath string, conceal func(string, interface{}) interface{}) (*yaml.Node, error) {
+	doc, err := parsed.load(filePath)
+	if err != nil { return nil, err }
+	if doc.err != nil { return nil, doc.err }
+	root := cloneNode(doc.root)
+	if len(root.Content) > 0 { concealNodes(root.Content[0], "", false, conceal) }
+	return root, nil
+}
+
+// snapshotRead 文件内容自上次快照后改变时，后台保存读取快照（yaml_reads）与最新快照（yaml_latest）；
+// 内容未变时不写入，写入失败时撤销登记、下次读取时重试
+func snapshotRead(filePath string) {
+	doc, err := parsed.load(filePath)
+	if err != nil || doc.err != nil || !parsed.snapshotDue(filePath, doc.revision) { return }
+	go func(copyData *YAMLData) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		docs := snapshotFields(copyData)
+		errRead := mongo.SaveYAMLRead(ctx, filepath.Base(filePath), copyData.Content, docs)
+		snapshotWrite("yaml_reads", errRead)
+		errLatest := mongo.UpsertLatest(ctx, filepath.Base(filePath), copyData.Content, docs)
+		snapshotWrite("yaml_latest", errLatest)
+		if errRead != nil || errLatest != nil { parsed.snapshotFailed(filePath, doc.revision) }
+	}(doc.data.mapSecrets(sealFields(filePath)))
+}
+
+// concealYAML 将YAML内容中的机密取值替换为 conceal 的结果，保留键顺序与注释
+func concealYAML(b []byte, conceal func(string, interface{}) interface{}) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil { return nil, err }
+	if len(root.Content) > 0 { concealNodes(root.Content[0], "", false, conceal) }
+	return yaml.Marshal(&root)
+}
+
+// concealNodes 按 extractFieldsNode 的规则找出机密标量（path 为其字段路径）并替换为 conceal 的结果（保留键顺序与注释）
+func concealNodes(node *yaml.Node, path string, secret bool, conceal func(string, interface{}) interface{}) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		described := secret || mappingType(node) == "secret"
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			concealNodes(node.Content[i+1], buildPath(path, key), isSecretKey(key) || (described && (key == "default" || key == "value")), conceal)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content { concealNodes(item, buildPath(path, "[]"), secret, conceal) }
+	case yaml.ScalarNode:
+		if secret && node.Value != "" && node.Tag != "!!null" {
+			node.Tag, node.Style, node.Value = "!!str", 0, toString(conceal(path, node.Value))
+		}
+	}
+}
+
+// rawDocument 按格式输出YAML文件（机密字段已屏蔽）：yaml 保留原文件的键顺序与注释，其余格式由解析后的内容转换
+func rawDocument(filePath, format string, data *YAMLData) ([]byte, error) {
+	if format != formats.YAML { return formats.Marshal(format, data.mapSecrets(maskField).Content) }
+	root, err := concealedRoot(filePath, maskField)
+	if err != nil { return nil, err }
+	return formats.Marshal(format, root)
+}
+
+// publishYAML 后台将保存后的文件提交到GitOps仓库（GITOPS_REPO_PATH），作者为操作人，提交说明列出修改的字段
+func publishYAML(filePath, actor string, updates map[string]interface{}) {
+	repo := gitops.Default()
+	if repo == nil { return }
+	paths := make([]string, 0, len(updates))
+	for p := range updates { paths = append(paths, p) }
+	sort.Strings(paths)
+	name, author := filepath.Base(filePath), repo.AuthorFor(actor)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_, err := repo.Commit(ctx, name, author, func(root string) (string, error) {
+			out, err := gitopsYAML(filePath)
+			if err != nil { return "", err }
+			return gitopsMessage(name, paths), os.WriteFile(filepath.Join(root, name), out, 0644)
+		})
+		if err != nil { log.Printf("GitOps提交 %s 失败: %v", name, err) }
+	}()
+}
+
+// gitopsMessage 提交说明：标题列出修改的字段（超过3个时只给数量），正文逐行列出
+func gitopsMessage(name string, paths []string) string {
+	subject := name + ": 更新"
+	switch {
+	case len(paths) == 0:
+		subject += "配置"
+	case len(paths) <= 3:
+		subject += " " + strings.Join(paths, ", ")
+	default:
+		subject += " " + strconv.Itoa(len(paths)) + " 个字段"
+	}
+	var b strings.Builder
+	b.WriteString(subject + "\n")
+	if len(paths) > 0 { b.WriteString("\n") }
+	for _, p := range paths { b.WriteString("- " + p + "\n") }
+	return b.String()
+}
+
+// pulledCommit 一个外部提交的导入结果
+type pulledCommit struct {
+	Hash    string        `json:"hash"`
+	Author  gitops.Author `json:"author"`
+	Subject string        `json:"subject"`
+	Applied []string      `json:"applied"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// pullYAML 将GitOps仓库中上次拉取之后的外部提交（提交者不是本服务）导回：逐个提交读取该版本的文件，
+// 与当前取值不同的字段以 git:<作者邮箱>（未经验证）的名义保存。机密字段的屏蔽值视为未修改，密文解密后比较；数组字段不导回。
+// 首次拉取只处理最新一个修改了该文件的提交
+func pullYAML(ctx context.Context, filePath string) (gin.H, error) {
+	repo := gitops.Default()
+	if repo == nil { return nil, gitops.ErrDisabled }
+	if err := repo.Update(ctx); err != nil { return nil, err }
+	head, err := repo.Head(ctx)
+	if err != nil { return nil, err }
+	name, from := filepath.Base(filePath), repo.Pulled(ctx)
+	result := gin.H{"from": from, "to": head, "commits": []pulledCommit{}}
+	if head == "" || head == from { return result, nil }
+
+	var commits []gitops.Commit
+	if from != "" {
+		// 上次的位置已不在历史中（如远端被改写）时返回错误，不跳过其间的提交
+		if commits, err = repo.Commits(ctx, from, name); err != nil { return nil, errors.New("读取上次拉取位置 " + from + " 之后的提交失败: " + err.Error()) }
+	} else {
+		// 首次拉取只处理最新一个修改了该文件的提交
+		if commits, err = repo.Commits(ctx, "", name); err != nil { return nil, err }
+		if len(commits) > 1 { commits = commits[len(commits)-1:] }
+	}
+	var pulled []pulledCommit
+	for _, c := range commits {
+		if c.Committer.Email == repo.Committer().Email { continue }
+		item := pulledCommit{Hash: c.Hash, Author: c.Author, Subject: c.Subject, Applied: []string{}}
+		updates, err := pulledUpdates(ctx, repo, c.Hash, filePath)
+		if err == nil && len(updates) > 0 {
+			for p := range updates { item.Applied = append(item.Applied, p) }
+			sort.Strings(item.Applied)
+			err = saveYAMLUpdates(filePath, updates, "git:"+c.Author.Email)
+		}
+		if err != nil { item.Error, item.Applied = err.Error(), []string{} }
+		pulled = append(pulled, item)
+	}
+	if err := repo.SetPulled(ctx, head); err != nil { return nil, err }
+	if pulled != nil { result["commits"] = pulled }
+	return result, nil
+}
+
+// pulledUpdates 对比提交中的文件与当前文件，返回取值不同的字段
+func pulledUpdates(ctx context.Context, repo *gitops.Repo, rev, filePath string) (map[string]interface{}, error) {
+	data, ok, err := repo.Show(ctx, rev, filepath.Base(filePath))
+	if err != nil || !ok { return nil, err }
+	incoming, err := parseYAMLBytes(data)
+	if err != nil { return nil, errors.New("解析YAML失败: " + err.Error()) }
+	current, err := parseYAMLFile(filePath)
+	if err != nil { return nil, err }
+	values := make(map[string]interface{}, len(current.Fields))
+	for _, f := range current.Fields { values[f.Path] = f.Value }
+	updates := make(map[string]interface{})
+	for _, f := range incoming.Fields {
+		if strings.Contains(f.Path, "[]") { continue }
+		v := f.Value
+		if f.Secret {
+			if toString(v) == maskedValue { continue }
+			plain, err := secrets.Default().Decrypt(secretOwner(filePath, f.Path), toString(v))
+			if err != nil { return nil, errors.New("字段 " + f.Path + " 无法解密: " + err.Error()) }
+			if secrets.IsEncrypted(toString(v)) { v = plain }
+		}
+		if cur, exists := values[f.Path]; exists && cur == v { continue }
+		updates[f.Path] = v
+	}
+	return updates, nil
+}
+
+// maxImportSize 导入文件的最大字节数
+const maxImportSize = 4 << 20
+
+// importChange 导入预览中的一个字段，机密字段的取值已屏蔽
+type importChange struct {
+	Path   string      `json:"path"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// importPreview 导入预览：Changed 将被修改，Added 将新增，Unchanged 与当前取值相同，Untouched 为导入文件中没有的现有字段，
+// Skipped 为无法合并的字段。Revision 为预览时文件内容的摘要，确认导入时回传可防止覆盖预览之后的修改
+type importPreview struct {
+	File      string         `json:"file"`
+	Format    string         `json:"format"`
+	Revision  string         `json:"revision"`
+	Changed   []importChange `json:"changed"`
+	Added     []importChange `json:"added"`
+	Unchanged []string       `json:"unchanged"`
+	Untouched []string       `json:"untouched"`
+	Skipped   []importChange `json:"skipped"`
+	Message   string         `json:"message,omitempty"`
+}
+
+// importFormat 导入格式：format 参数优先，否则按扩展名判断（.json、.toml、.env、.properties、.yaml/.yml）
+func importFormat(format, filename string) string {
+	if format != "" { return format }
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if ext == "yml" { return formats.YAML }
+	return ext
+}
+
+// readImport 读取上传的导入文件（multipart 字段 file）并解析为文档
+func readImport(c *gin.Context) (map[string]interface{}, string, error) {
+	fh, err := c.FormFile("file")
+	if err != nil { return nil, "", errors.New("file is required") }
+	if fh.Size > maxImportSize { return nil, "", errors.New("导入文件不能超过4MB") }
+	format := importFormat(c.PostForm("format"), fh.Filename)
+	if !formats.Valid(format) { return nil, "", formats.ErrFormat }
+	f, err := fh.Open()
+	if err != nil { return nil, "", err }
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxImportSize))
+	if err != nil { return nil, "", err }
+	doc, err := formats.Parse(format, data)
+	return doc, format, err
+}
+
+// previewImport 将导入的文档按字段路径与当前文件比较，返回预览与待保存的修改（即 POST /yaml 的 updates）。
+// 只合并标量：数组只比较不修改，当前为对象或上级不是对象的路径跳过；机密字段回传屏蔽值表示不修改，密文解密后比较
+func previewImport(filePath string, doc map[string]interface{}) (*importPreview, map[string]interface{}, error) {
+	cached, err := parsed.load(filePath)
+	if err != nil { return nil, nil, errors.New("读取文件失败") }
+	if cached.err != nil { return nil, nil, errors.New("解析YAML失败") }
+	current := cached.data
+	preview := &importPreview{
+		File: filepath.Base(filePath), Revision: cached.revision,
+		Changed: []importChange{}, Added: []importChange{}, Unchanged: []string{}, Untouched: []string{}, Skipped: []importChange{},
+	}
+	leaves := make(map[string]interface{})
+	importLeaves("", doc, leaves, preview)
+	paths := make([]string, 0, len(leaves))
+	for p := range leaves { paths = append(paths, p) }
+	sort.Strings(paths)
+
+	secretPaths, updates := current.secretPaths(), make(map[string]interface{})
+	for _, p := range paths {
+		v := leaves[p]
+		cur, found, blocked := lookupContent(current.Content, p)
+		_, curList := cur.([]interface{})
+		_, newList := v.([]interface{})
+		switch {
+		case blocked != "":
+			preview.Skipped = append(preview.Skipped, importChange{Path: p, Reason: "上级字段 " + blocked + " 不是对象"})
+		case !found && newList:
+			preview.Skipped = append(preview.Skipped, importChange{Path: p, Reason: "不支持新增数组"})
+		case !found:
+			change := importChange{Path: p, To: v}
+			if importSecret(p) { change.To = maskSecret(v) }
+			preview.Added, updates[p] = append(preview.Added, change), v
+		case curList || newList:
+			if sameValue(cur, v) {
+				preview.Unchanged = append(preview.Unchanged, p)
+			} else {
+				preview.Skipped = append(preview.Skipped, importChange{Path: p, Reason: "不支持修改数组"})
+			}
+		default:
+			if _, isMap := cur.(map[string]interface{}); isMap {
+				preview.Skipped = append(preview.Skipped, importChange{Path: p, Reason: "当前为对象，只能导入标量"})
+				continue
+			}
+			secret := secretPaths[p]
+			if secret && v == maskedValue { preview.Unchanged = append(preview.Unchanged, p); continue }
+			if s, ok := v.(string); ok && secret && secrets.IsEncrypted(s) {
+				plain, err := secrets.Default().Decrypt(secretOwner(filePath, p), s)
+				if err != nil { preview.Skipped = append(preview.Skipped, importChange{Path: p, Reason: "无法解密: " + err.Error()}); continue }
+				v = plain
+			}
+			if sameValue(cur, v) { preview.Unchanged = append(preview.Unchanged, p); continue }
+			change := importChange{Path: p, From: cur, To: v}
+			if secret { change.From, change.To = maskSecret(cur), maskSecret(v) }
+			preview.Changed, updates[p] = append(preview.Changed, change), v
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range current.Fields {
+		p := f.Path
+		if i := strings.Index(p, "[]"); i >= 0 { p = strings.TrimSuffix(p[:i], ".") }
+		if _, ok := leaves[p]; ok || seen[p] || p == "" { continue }
+		seen[p] = true
+		preview.Untouched = append(preview.Untouched, p)
+	}
+	sort.Strings(preview.Untouched)
+	return preview, updates, nil
+}
+
+// importLeaves 收集导入文档中的标量与数组（以字段路径为键），空对象与含 . 的键名记为跳过
+func importLeaves(path string, v interface{}, leaves map[string]interface{}, preview *importPreview) {
+	m, ok := v.(map[string]interface{})
+	if !ok { leaves[path] = v; return }
+	if len(m) == 0 && path != "" {
+		preview.Skipped = append(preview.Skipped, importChange{Path: path, Reason: "空对象"})
+	}
+	for k, item := range m {
+		if k == "" || strings.Contains(k, ".") {
+			preview.Skipped = append(preview.Skipped, importChange{Path: buildPath(path, k), Reason: "键名为空或含 ."})
+			continue
+		}
+		importLeaves(buildPath(path, k), item, leaves, preview)
+	}
+}
+
+// lookupContent 按字段路径查找当前内容；路径中途遇到非对象时 blocked 为该上级路径
+func lookupContent(content interface{}, path string) (value interface{}, found bool, blocked string) {
+	parts := strings.Split(path, ".")
+	cur := content
+	for i, part := range parts {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			if i == 0 { return nil, false, "" }
+			return nil, false, strings.Join(parts[:i], ".")
+		}
+		if cur, ok = m[part]; !ok { return nil, false, "" }
+	}
+	return cur, true, ""
+}
+
+// importSecret 新增字段是否按机密字段屏蔽（任一级键名为机密词）
+func importSecret(path string) bool {
+	for _, part := range strings.Split(path, ".") {
+		if isSecretKey(part) { return true }
+	}
+	return false
+}
+
+// sameValue 按键值格式的取值文本比较，数字与字符串 "8080" 视为不同
+func sameValue(a, b interface{}) bool {
+	x, errA := formats.Value(formats.Properties, "", a)
+	y, errB := formats.Value(formats.Properties, "", b)
+	if errA != nil || errB != nil { return reflect.DeepEqual(a, b) }
+	return x == y
+}
+
+// importYAML 导入 JSON、TOML、.env 等文件：commit 为 false 时只返回预览；为 true 时保存预览中的修改与新增，
+// 表单回传的 revision 与当前文件不一致时返回409
+func importYAML(commit bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filePath, err := findWritableYAMLFile()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		doc, format, err := readImport(c)
+		if errors.Is(err, formats.ErrUnrepresentable) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		preview, updates, err := previewImport(filePath, doc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		preview.Format = format
+		if !commit {
+			c.JSON(http.StatusOK, preview)
+			return
+		}
+		if rev := c.PostForm("revision"); rev != "" && rev != preview.Revision {
+			c.JSON(http.StatusConflict, gin.H{"error": "文件在预览之后已被修改，请重新预览", "revision": preview.Revision})
+			return
+		}
+		preview.Message = "无修改"
+		if len(updates) > 0 {
+			if err := saveYAMLUpdates(filePath, updates, auth.ActorFrom(c.Request.Context())); err != nil {
+				writeSaveError(c, err)
+				return
+			}
+			preview.Message = "saved"
+		}
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
 func main() {
 	// 加载 .env 文件（如果存在）
 	_ = godotenv.Load()
@@ -240,13 +1234,59 @@ func main() {
 	// 创建Gin引擎
 	r := gin.New()
 	r.Use(gin.Recovery())
-	r.Use(cors.Default())
+	r.Use(metrics.Middleware())
+	if cfg, ok := corsConfig(); ok { r.Use(cors.New(cfg)) }
+
+	// 认证（AUTH_PROVIDERS=token,jwt,oidc），未设置时拒绝启动，本地开发可设置 AUTH_DISABLED=true 显式关闭
+	authenticator, err := auth.New(auth.LoadConfig())
+	if err != nil { log.Fatalf("认证初始化失败: %v", err) }
+	viewer, operator, admin := auth.Require(auth.RoleViewer), auth.Require(auth.RoleOperator), auth.Require(auth.RoleAdmin)
+
+	// 机密字段快照加密密钥（SECRET_KEYS_FILE 或 SECRET_KEY），未配置时快照中只保存屏蔽值
+	if err := secrets.Init(); err != nil { log.Fatalf("加密密钥加载失败: %v", err) }
+
+	// GitOps同步（GITOPS_REPO_PATH 为空时不启用）：保存后提交到本地仓库，拉取时导回外部提交
+	if err := gitops.Init(); err != nil { log.Fatalf("GitOps仓库初始化失败: %v", err) }
+
+	// 监视工作目录中的YAML文件，变更经 GET /api/v1/yaml/events（SSE）推送给打开的页面
+	yamlFiles := []string{"config.yaml", "sample_config.yaml", "test.yaml"}
+	for _, name := range yamlFiles { watched.load(name) }
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if err := watch.Watch(watchCtx, ".", yamlFiles, watchDebounce, watched.reload); err != nil { log.Printf("文件监视未启用: %v", err) }
+
+	// 健康检查：存活只看进程；就绪要求YAML文件可解析，MongoDB仅用于快照，不可用时降级
+	r.GET("/metrics", metrics.Handler())
+	r.GET("/healthz", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	r.GET("/readyz", func(c *gin.Context) {
+		status, code := "ok", http.StatusOK
+		yamlCheck := gin.H{"required": true, "connected": true}
+		name, err := findWritableYAMLFile()
+		if err == nil { _, err = parseYAMLFile(name) }
+		if err != nil {
+			yamlCheck["connected"], yamlCheck["error"] = false, err.Error()
+			status, code = "unavailable", http.StatusServiceUnavailable
+		} else {
+			yamlCheck["file"] = name
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		mongoCheck := gin.H{"required": false, "connected": true}
+		if err := mongo.Ping(ctx); err != nil {
+			mongoCheck["connected"], mongoCheck["error"] = false, err.Error()
+			if status == "ok" { status = "degraded" }
+		}
+		c.JSON(code, gin.H{"status": status, "stores": gin.H{"yaml": yamlCheck, "mongodb": mongoCheck}})
+	})
 
 	// API路由（先注册API，避免与静态资源通配符冲突）
-	api := r.Group("/api/v1")
+	api := r.Group("/api/v1", auth.Middleware(authenticator, "yaml-config"))
 	{
+		api.GET("/auth/me", viewer, auth.Current)
+
 		// 获取YAML数据（支持分页）
-		api.GET("/yaml", func(c *gin.Context) {
+		api.GET("/yaml", viewer, func(c *gin.Context) {
 			// 尝试读取当前目录下的yaml文件
 			yamlFiles := []string{"config.yaml", "sample_config.yaml", "test.yaml"}
 			var yamlData *YAMLData
@@ -267,18 +1307,32 @@ func main() {
 				return
 			}
 
-			// 首次/常规读取快照保存到Mongo（不阻塞主流程）
-			go func(copyData *YAMLData, fname string) {
-				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-				defer cancel()
-				// 将字段转换为简易文档
-				var docs []map[string]interface{}
-				for _, f := range copyData.Fields {
-					docs = append(docs, map[string]interface{}{"path": f.Path, "value": f.Value, "type": f.Type})
+			// 内容改变后的首次读取保存快照到Mongo（不阻塞主流程）
+			snapshotRead(chosen)
+			yamlData = yamlData.mapSecrets(maskField)
+
+			// 筛选在分页之前进行，total 为筛选后的数量
+			filter, err := parseFieldFilter(c)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if !filter.since.IsZero() {
+				ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+				paths, err := mongo.ChangedPaths(ctx, filepath.Base(chosen), filter.since)
+				cancel()
+				if err != nil {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": "changedSince 需要读取MongoDB中的修改记录: " + err.Error()})
+					return
 				}
-				_ = mongo.SaveYAMLRead(ctx, filepath.Base(fname), copyData.Content, docs)
-				_ = mongo.UpsertLatest(ctx, filepath.Base(fname), copyData.Content, docs)
-			}(yamlData, chosen)
+				filter.changed = make(map[string]bool, len(paths))
+				for _, p := range paths { filter.changed[p] = true }
+			}
+			matched := make([]Field, 0, len(yamlData.Fields))
+			for _, f := range yamlData.Fields {
+				if filter.match(f) { matched = append(matched, f) }
+			}
+			yamlData.Fields = matched
 
 			// 分页参数
 			page := 1
@@ -316,8 +1370,9 @@ func main() {
 			})
 		})
 
-		// 保存YAML修改（基于 yaml.Node 原位更新，保留原始顺序）
-		api.POST("/yaml", func(c *gin.Context) {
+		// 保存YAML修改（基于 yaml.Node 原位更新，保留原始顺序）；dryRun=true 时只返回修改后的内容、diff 与检查结果，
+		// 不写文件、备份与快照
+		api.POST("/yaml", operator, func(c *gin.Context) {
 			var req struct { Updates map[string]interface{} `json:"updates"` }
 			if err := c.ShouldBindJSON(&req); err != nil || req.Updates == nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
@@ -329,53 +1384,32 @@ func main() {
 				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 				return
 			}
-
-			// 读取为 yaml.Node
-			b, err := os.ReadFile(filePath)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "读取文件失败"})
-				return
-			}
-			var root yaml.Node
-			if err := yaml.Unmarshal(b, &root); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "解析YAML失败"})
-				return
-			}
-
-			// 应用更新到节点
-			for p, v := range req.Updates { _ = setNodeValueByPath(&root, p, v) }
-
-			// 备份原文件
-			_ = os.WriteFile(filePath+".bak", b, 0644)
-
-			// 写回，保留原始键顺序
-			out, err := yaml.Marshal(&root)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "生成YAML失败"})
+			if c.Query("dryRun") == "true" {
+				result, err := dryRunYAML(filePath, req.Updates)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				c.JSON(http.StatusOK, result)
 				return
 			}
-			if err := os.WriteFile(filePath, out, 0644); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "写入文件失败"})
+			if err := saveYAMLUpdates(filePath, req.Updates, auth.ActorFrom(c.Request.Context())); err != nil {
+				writeSaveError(c, err)
 				return
 			}
 
-			// 重解析用于保存更新快照
-			if data, err := parseYAMLFile(filePath); err == nil {
-				go func(copyData *YAMLData) {
-					ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-					defer cancel()
-					var docs []map[string]interface{}
-					for _, f := range copyData.Fields { docs = append(docs, map[string]interface{}{"path": f.Path, "value": f.Value, "type": f.Type}) }
-					_ = mongo.SaveYAMLUpdate(ctx, filepath.Base(filePath), req.Updates, copyData.Content, docs)
-					_ = mongo.UpsertLatest(ctx, filepath.Base(filePath), copyData.Content, docs)
-				}(data)
-			}
-
 			c.JSON(http.StatusOK, gin.H{"message": "saved", "file": filepath.Base(filePath)})
 		})
 
+		// 文件变更事件（SSE）：接口保存、外部编辑或恢复备份后推送变更的字段路径
+		api.GET("/yaml/events", viewer, streamEvents)
+
+		// 从 JSON、TOML、.env 等文件导入：先预览将修改、新增与不变的字段，确认后按同样的 yaml.Node 方式保存
+		api.POST("/yaml/import/preview", operator, importYAML(false))
+		api.POST("/yaml/import", operator, importYAML(true))
+
 		// 获取YAML原始内容
-		api.GET("/yaml/raw", func(c *gin.Context) {
+		api.GET("/yaml/raw", viewer, func(c *gin.Context) {
 			yamlFiles := []string{"config.yaml", "sample_config.yaml", "test.yaml"}
 			var yamlData *YAMLData
 			var chosen string
@@ -395,20 +1429,107 @@ func main() {
 				return
 			}
 
-			// 读取原始也保存一次最新快照（异步）
-			go func(copyData *YAMLData, fname string) {
-				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-				defer cancel()
-				var docs []map[string]interface{}
-				for _, f := range copyData.Fields { docs = append(docs, map[string]interface{}{"path": f.Path, "value": f.Value, "type": f.Type}) }
-				_ = mongo.UpsertLatest(ctx, filepath.Base(fname), copyData.Content, docs)
-			}(yamlData, chosen)
+			// 读取原始内容同样在内容改变时保存快照（异步）
+			snapshotRead(chosen)
+
+			// format=json|toml|env|properties|yaml 时直接输出该格式的文档，键值格式的扁平化规则见 formats 包
+			if format := c.Query("format"); format != "" {
+				if !formats.Valid(format) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": formats.ErrFormat.Error()})
+					return
+				}
+				out, err := rawDocument(chosen, format, yamlData)
+				if errors.Is(err, formats.ErrUnrepresentable) {
+					c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+					return
+				}
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				name := strings.TrimSuffix(filepath.Base(chosen), filepath.Ext(chosen)) + "." + format
+				c.Header("Content-Disposition", `inline; filename="`+name+`"`)
+				c.Data(http.StatusOK, formats.ContentType(format), out)
+				return
+			}
 
 			c.JSON(http.StatusOK, gin.H{
-				"data":    yamlData.Content,
+				"data":    yamlData.mapSecrets(maskField).Content,
 				"message": "success",
 			})
 		})
+
+		// 查看机密字段明文（仅管理员），须填写原因，查看人与原因记入MongoDB yaml_reveals；无法记录时不返回明文
+		api.POST("/yaml/reveal", admin, func(c *gin.Context) {
+			var req struct {
+				Path   string `json:"path" binding:"required"`
+				Reason string `json:"reason"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+				return
+			}
+			if strings.TrimSpace(req.Reason) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "查看明文需要填写原因"})
+				return
+			}
+			filePath, err := findWritableYAMLFile()
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			data, err := parseYAMLFile(filePath)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "解析YAML失败"})
+				return
+			}
+			for _, f := range data.Fields {
+				if f.Path != req.Path { continue }
+				actor := auth.ActorFrom(c.Request.Context())
+				ctx, cancel := context.WithTimeout(c.Request.Context(), 3*time.Second)
+				err := mongo.SaveYAMLReveal(ctx, filepath.Base(filePath), actor, f.Path, req.Reason)
+				cancel()
+				if err != nil {
+					c.JSON(http.StatusServiceUnavailable, gin.H{"error": "无法记录查看操作，未返回明文: " + err.Error()})
+					return
+				}
+				log.Printf("%s 查看了 %s 中 %s 的明文，原因: %s", actor, filepath.Base(filePath), f.Path, req.Reason)
+				c.JSON(http.StatusOK, gin.H{"path": f.Path, "value": f.Value, "secret": f.Secret})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "字段不存在: " + req.Path})
+		})
+
+		// GitOps同步状态
+		api.GET("/gitops", viewer, func(c *gin.Context) {
+			repo := gitops.Default()
+			if repo == nil {
+				c.JSON(http.StatusOK, gin.H{"enabled": false})
+				return
+			}
+			ctx := c.Request.Context()
+			head, _ := repo.Head(ctx)
+			c.JSON(http.StatusOK, gin.H{"enabled": true, "path": repo.Path(), "head": head, "pulled": repo.Pulled(ctx)})
+		})
+
+		// 从GitOps仓库导回外部提交（仅管理员）
+		api.POST("/gitops/pull", admin, func(c *gin.Context) {
+			filePath, err := findWritableYAMLFile()
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			result, err := pullYAML(c.Request.Context(), filePath)
+			if errors.Is(err, gitops.ErrDisabled) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+		})
 	}
 
 	// 静态资源（放在最后，避免与 /api 路由冲突）
@@ -424,6 +1545,7 @@ func main() {
 
 	// 创建HTTP服务器
 	srv := &http.Server{ Addr: ":" + port, Handler: r }
+	srv.RegisterOnShutdown(watched.events.Close)
 
 	// 启动服务器
 	go func() {
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "yaml_config"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "按路由统计的HTTP请求数",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "按路由统计的HTTP请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	parseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "yaml_parse_duration_seconds",
+		Help:      "YAML文件解析耗时",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"result"})
+
+	parseCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "yaml_parse_cache_total",
+		Help:      "YAML解析缓存查询次数：hit 修改时间与大小未变，unchanged 重新读取但内容未变，miss 重新解析",
+	}, []string{"result"})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "MongoDB操作耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	mongoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_errors_total",
+		Help:      "MongoDB操作失败次数（含连接不可用）",
+	}, []string{"operation"})
+
+	snapshotsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_writes_dropped_total",
+		Help:      "异步写入失败而被丢弃的快照数",
+	}, []string{"collection"})
+)
+
+// Middleware 记录每个请求的次数与耗时，路由取注册时的模板
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler 暴露 Prometheus 抓取端点
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// ObserveParse 记录一次YAML解析的耗时与结果
+func ObserveParse(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	parseDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// ObserveParseCache 记录一次解析缓存查询的结果（hit、unchanged 或 miss）
+func ObserveParseCache(result string) {
+	parseCache.WithLabelValues(result).Inc()
+}
+
+// ObserveMongo 记录一次MongoDB操作的耗时，失败时计入错误数
+func ObserveMongo(operation string, start time.Time, err error) {
+	mongoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		mongoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// SnapshotDropped 记录一次被丢弃的异步快照写入
+func SnapshotDropped(collection string) {
+	snapshotsDropped.WithLabelValues(collection).Inc()
+}
@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"os"
+	"sort"
 	"sync"
 	"time"
 	"log"
@@ -10,25 +11,42 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"simple-version/metrics"
 )
 
 var (
 	client     *mongo.Client
-	initOnce   sync.Once
+	clientMu   sync.Mutex
 	clientErr  error
 )
 
+// ensureClient 懒加载客户端；连接失败不缓存，MongoDB恢复后下次调用自动重连
 func ensureClient(ctx context.Context) (*mongo.Client, error) {
-	initOnce.Do(func() {
-		uri := os.Getenv("MONGO_URI")
-		if uri == "" { uri = "mongodb://localhost:27017" }
-		c, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
-		if err != nil { log.Printf("MongoDB connect error: %v", err); clientErr = err; return }
-		if err := c.Ping(ctx, nil); err != nil { log.Printf("MongoDB ping error: %v", err); clientErr = err; return }
-		client = c
-		log.Printf("MongoDB connected")
-	})
-	return client, clientErr
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	if client != nil { return client, nil }
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" { uri = "mongodb://localhost:27017" }
+	opts := options.Client().ApplyURI(uri).SetServerSelectionTimeout(3 * time.Second)
+	c, err := mongo.Connect(ctx, opts)
+	if err != nil { log.Printf("MongoDB connect error: %v", err); clientErr = err; return nil, err }
+	if err := c.Ping(ctx, nil); err != nil {
+		log.Printf("MongoDB ping error: %v", err)
+		_ = c.Disconnect(context.Background())
+		clientErr = err
+		return nil, err
+	}
+	client, clientErr = c, nil
+	log.Printf("MongoDB connected")
+	return client, nil
+}
+
+// Ping 检查MongoDB是否可用，供就绪检查使用
+func Ping(ctx context.Context) error {
+	c, err := ensureClient(ctx)
+	if err != nil { return err }
+	return c.Ping(ctx, nil)
 }
 
 func getColl(ctx context.Context, name string) (*mongo.Collection, error) {
@@ -39,6 +57,11 @@ func getColl(ctx context.Context, name string) (*mongo.Collection, error) {
 	return c.Database(db).Collection(name), nil
 }
 
+// observe 记录操作耗时与失败次数，需以 defer 调用以读取最终错误
+func observe(operation string, start time.Time, err *error) {
+	metrics.ObserveMongo(operation, start, *err)
+}
+
 // YAMLReadDoc 首次/常规读取的快照文档
 type YAMLReadDoc struct {
 	ID        interface{}            `bson:"_id,omitempty"`
@@ -52,14 +75,26 @@ type YAMLReadDoc struct {
 type YAMLUpdateDoc struct {
 	ID        interface{}            `bson:"_id,omitempty"`
 	Filename  string                 `bson:"filename"`
+	Actor     string                 `bson:"actor"`
 	UpdatedAt time.Time              `bson:"updated_at"`
 	Updates   map[string]interface{} `bson:"updates"`
 	Content   interface{}            `bson:"content"`
 	Fields    []map[string]interface{} `bson:"fields"`
 }
 
+// YAMLRevealDoc 查看机密字段明文的审计记录
+type YAMLRevealDoc struct {
+	ID         interface{} `bson:"_id,omitempty"`
+	Filename   string      `bson:"filename"`
+	Actor      string      `bson:"actor"`
+	Path       string      `bson:"path"`
+	Reason     string      `bson:"reason"`
+	RevealedAt time.Time   `bson:"revealed_at"`
+}
+
 // SaveYAMLRead 保存读取快照
-func SaveYAMLRead(ctx context.Context, filename string, content interface{}, fields []map[string]interface{}) error {
+func SaveYAMLRead(ctx context.Context, filename string, content interface{}, fields []map[string]interface{}) (err error) {
+	defer observe("insert_yaml_reads", time.Now(), &err)
 	coll, err := getColl(ctx, "yaml_reads")
 	if err != nil { return err }
 	doc := YAMLReadDoc{Filename: filename, ReadAt: time.Now(), Content: content, Fields: fields}
@@ -67,17 +102,29 @@ func SaveYAMLRead(ctx context.Context, filename string, content interface{}, fie
 	return err
 }
 
-// SaveYAMLUpdate 保存更新快照
-func SaveYAMLUpdate(ctx context.Context, filename string, updates map[string]interface{}, content interface{}, fields []map[string]interface{}) error {
+// SaveYAMLUpdate 保存更新快照，actor 为发起修改的调用方
+func SaveYAMLUpdate(ctx context.Context, filename string, actor string, updates map[string]interface{}, content interface{}, fields []map[string]interface{}) (err error) {
+	defer observe("insert_yaml_updates", time.Now(), &err)
 	coll, err := getColl(ctx, "yaml_updates")
 	if err != nil { return err }
-	doc := YAMLUpdateDoc{Filename: filename, UpdatedAt: time.Now(), Updates: updates, Content: content, Fields: fields}
+	doc := YAMLUpdateDoc{Filename: filename, Actor: actor, UpdatedAt: time.Now(), Updates: updates, Content: content, Fields: fields}
+	_, err = coll.InsertOne(ctx, doc)
+	return err
+}
+
+// SaveYAMLReveal 记录一次查看机密字段明文，actor 为查看人
+func SaveYAMLReveal(ctx context.Context, filename, actor, path, reason string) (err error) {
+	defer observe("insert_yaml_reveals", time.Now(), &err)
+	coll, err := getColl(ctx, "yaml_reveals")
+	if err != nil { return err }
+	doc := YAMLRevealDoc{Filename: filename, Actor: actor, Path: path, Reason: reason, RevealedAt: time.Now()}
 	_, err = coll.InsertOne(ctx, doc)
 	return err
 }
 
 // UpsertLatest 按文件维护一份最新快照（可选）
-func UpsertLatest(ctx context.Context, filename string, content interface{}, fields []map[string]interface{}) error {
+func UpsertLatest(ctx context.Context, filename string, content interface{}, fields []map[string]interface{}) (err error) {
+	defer observe("upsert_yaml_latest", time.Now(), &err)
 	coll, err := getColl(ctx, "yaml_latest")
 	if err != nil { return err }
 	_, err = coll.UpdateOne(ctx,
@@ -87,3 +134,25 @@ func UpsertLatest(ctx context.Context, filename string, content interface{}, fie
 	)
 	return err
 }
+
+// ChangedPaths 返回 since 之后经接口修改过的字段路径（yaml_updates 中 updates 的键），按路径排序
+func ChangedPaths(ctx context.Context, filename string, since time.Time) (paths []string, err error) {
+	defer observe("find_yaml_updates", time.Now(), &err)
+	coll, err := getColl(ctx, "yaml_updates")
+	if err != nil { return nil, err }
+	filter := bson.M{"filename": filename, "updated_at": bson.M{"$gte": since}}
+	cur, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"updates": 1}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	seen := make(map[string]bool)
+	for cur.Next(ctx) {
+		var doc YAMLUpdateDoc
+		if err := cur.Decode(&doc); err != nil { return nil, err }
+		for p := range doc.Updates {
+			if !seen[p] { seen[p] = true; paths = append(paths, p) }
+		}
+	}
+	if err := cur.Err(); err != nil { return nil, err }
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,206 @@
+// Package watch 监视工作目录中的配置文件（fsnotify），并将文件变更事件广播给订阅者（SSE）
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 事件类型
+const (
+	TypeChanged = "changed" // 文件内容改变（含新建），Changed/Added/Removed 为受影响的字段路径
+	TypeRemoved = "removed" // 文件被删除或移走
+	TypeInvalid = "invalid" // 文件改变后无法解析，Error 为原因
+	TypeReset   = "reset"   // 订阅者错过了部分事件，应重新加载
+)
+
+// 事件来源
+const (
+	SourceAPI      = "api"      // 经本服务接口写入
+	SourceExternal = "external" // 其他进程修改（编辑器、恢复备份等）
+)
+
+// Event 一次文件变更，字段路径与 GET /yaml 的 path 一致，不含取值
+type Event struct {
+	ID       uint64    `json:"id"`
+	Type     string    `json:"type"`
+	File     string    `json:"file,omitempty"`
+	Revision string    `json:"revision,omitempty"`
+	Changed  []string  `json:"changed,omitempty"`
+	Added    []string  `json:"added,omitempty"`
+	Removed  []string  `json:"removed,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Actor    string    `json:"actor,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// WriteSSE 按 text/event-stream 格式写出事件：id 为事件序号，event 为事件类型，data 为JSON
+func WriteSSE(w io.Writer, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// historySize 保留的最近事件数，订阅者断线重连时按 Last-Event-ID 补发
+const historySize = 64
+
+// subscriberBuffer 每个订阅者的事件缓冲，写满时断开该订阅者，由其重连后补发
+const subscriberBuffer = 16
+
+// Hub 事件广播：为事件分配递增序号并发给全部订阅者
+type Hub struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	subs    map[chan Event]struct{}
+	closed  bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Publish 分配序号与时间后广播事件，返回广播的事件
+func (h *Hub) Publish(e Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e.ID, e.Time = h.seq, time.Now()
+	h.history = append(h.history, e)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// Subscribe 订阅之后的事件；lastID 为客户端收到的最后一个事件序号（Last-Event-ID），
+// 补发其后仍保留的事件，已无法补全（或服务重启后序号不连续）时先发送 reset。
+// 通道关闭表示订阅已断开，应调用 cancel 释放
+func (h *Hub) Subscribe(lastID uint64) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan Event, subscriberBuffer+historySize+1)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if lastID > 0 {
+		if lastID > h.seq || (len(h.history) > 0 && h.history[0].ID > lastID+1) {
+			ch <- Event{ID: h.seq, Type: TypeReset, Time: time.Now()}
+		} else {
+			for _, e := range h.history {
+				if e.ID > lastID {
+					ch <- e
+				}
+			}
+		}
+	}
+	h.subs[ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close 断开全部订阅者（服务关闭时调用，避免长连接阻塞优雅关闭）
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Watch 在后台监视目录 dir 中名为 names 的文件。写入、新建、重命名与删除在静默 debounce 后
+// 按文件合并为一次 onChange(name) 回调（编辑器保存时的多次写入只回调一次）；ctx 结束时停止。
+// 监视的是目录而不是文件本身，先写临时文件再重命名的保存方式与删除后重建同样能被发现
+func Watch(ctx context.Context, dir string, names []string, debounce time.Duration, onChange func(name string)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return err
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	go func() {
+		defer w.Close()
+		var mu sync.Mutex
+		timers := make(map[string]*time.Timer)
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, t := range timers {
+				t.Stop()
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("监视 %s 出错: %v", dir, err)
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Base(ev.Name)
+				if !wanted[name] || ev.Op == fsnotify.Chmod {
+					continue
+				}
+				mu.Lock()
+				// 已触发的定时器不再重置，另起一个，避免同一批修改回调两次
+				if t, ok := timers[name]; ok && t.Stop() {
+					t.Reset(debounce)
+				} else {
+					var t *time.Timer
+					t = time.AfterFunc(debounce, func() {
+						mu.Lock()
+						if timers[name] == t {
+							delete(timers, name)
+						}
+						mu.Unlock()
+						onChange(name)
+					})
+					timers[name] = t
+				}
+				mu.Unlock()
+			}
+		}
+	}()
+	return nil
+}